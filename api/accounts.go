@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newAccountMutationFields returns the mutations for users to manage their own account.
+func newAccountMutationFields(dataModel data.IWritableData, authenticationProvider IAuthenticationProvider, policy authorizationPolicy, passwordCost int, authenticationType *graphql.Object) graphql.Fields {
+	return graphql.Fields{
+		"register": &graphql.Field{
+			Type:        authenticationType,
+			Description: "Create an account and log in with it",
+			Args: graphql.FieldConfigArgument{
+				"email": &graphql.ArgumentConfig{
+					Description: "email of the user",
+					Type:        graphql.NewNonNull(graphql.String),
+				},
+				"username": &graphql.ArgumentConfig{
+					Description: "username of the user",
+					Type:        graphql.NewNonNull(graphql.String),
+				},
+				"name": &graphql.ArgumentConfig{
+					Description: "name of the user",
+					Type:        graphql.NewNonNull(graphql.String),
+				},
+				"password": &graphql.ArgumentConfig{
+					Description: "password of the user, at least 8 characters with a letter and a digit",
+					Type:        graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user := data.User{
+					Email:    p.Args["email"].(string),
+					Username: p.Args["username"].(string),
+					Name:     p.Args["name"].(string),
+					Role:     data.RoleUser,
+				}
+				password := p.Args["password"].(string)
+
+				var errs validationError
+				validateEmail(&errs, user.Email, "email")
+				if _, err := dataModel.GetUserWithEmail(user.Email); err == nil {
+					errs.add(data.ErrEmailTaken.Error(), "email")
+				}
+				validateRequired(&errs, user.Username, "username")
+				validateRequired(&errs, user.Name, "name")
+				validatePassword(&errs, password, "password")
+
+				if err := errs.err(); err != nil {
+					return nil, err
+				}
+
+				if err := setPassword(&user, password, passwordCost); err != nil {
+					return nil, err
+				}
+
+				user, err := dataModel.CreateUser(user)
+
+				if errors.Is(err, data.ErrEmailTaken) {
+					// The email was taken after it was checked.
+					errs.add(data.ErrEmailTaken.Error(), "email")
+					return nil, errs
+				}
+
+				if err != nil {
+					return nil, err
+				}
+
+				tokens, err := authenticationProvider.IssueTokens(user.ID)
+
+				if err != nil {
+					return nil, fmt.Errorf("login failed: %w", err)
+				}
+
+				return newAuthentication(tokens, user), nil
+			},
+		},
+		"changePassword": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "Change the password of the authenticated user",
+			Args: graphql.FieldConfigArgument{
+				"oldPassword": &graphql.ArgumentConfig{
+					Description: "current password of the user",
+					Type:        graphql.NewNonNull(graphql.String),
+				},
+				"newPassword": &graphql.ArgumentConfig{
+					Description: "new password of the user, at least 8 characters with a letter and a digit",
+					Type:        graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				user, err := policy.of(p).viewer(p.Context)
+
+				if err != nil {
+					return nil, err
+				}
+
+				oldPassword := p.Args["oldPassword"].(string)
+				newPassword := p.Args["newPassword"].(string)
+
+				var errs validationError
+				if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)); err != nil {
+					errs.add("is incorrect", "oldPassword")
+				}
+				validatePassword(&errs, newPassword, "newPassword")
+				if oldPassword == newPassword {
+					errs.add("must be different from the old password", "newPassword")
+				}
+
+				if err := errs.err(); err != nil {
+					return nil, err
+				}
+
+				if err := setPassword(&user, newPassword, passwordCost); err != nil {
+					return nil, err
+				}
+
+				if _, err := dataModel.UpdateUser(user); err != nil {
+					return nil, err
+				}
+
+				return true, nil
+			}),
+		},
+		"resetPassword": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "Set the password of a user, without their current one. Only administrators may reset passwords",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Description: "id of the user",
+					Type:        graphql.NewNonNull(graphql.Int),
+				},
+				"newPassword": &graphql.ArgumentConfig{
+					Description: "new password of the user, at least 8 characters with a letter and a digit",
+					Type:        graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizeAdmin(p.Context); err != nil {
+					return nil, err
+				}
+
+				user, err := dataModel.GetUser(p.Args["id"].(int))
+				if err != nil {
+					return nil, dataError(err)
+				}
+
+				newPassword := p.Args["newPassword"].(string)
+
+				var errs validationError
+				validatePassword(&errs, newPassword, "newPassword")
+
+				if err := errs.err(); err != nil {
+					return nil, err
+				}
+
+				if err := setPassword(&user, newPassword, passwordCost); err != nil {
+					return nil, err
+				}
+
+				if _, err := dataModel.UpdateUser(user); err != nil {
+					return nil, dataError(err)
+				}
+
+				return true, nil
+			}),
+		},
+	}
+}
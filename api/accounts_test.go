@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = Describe("Accounts", func() {
+	var testData data.IWritableData
+	var auth IAuthenticationProvider
+	var variables map[string]interface{}
+	var params graphql.Params
+
+	BeforeEach(func() {
+		config := data.DefaultGeneratorConfig()
+		config.Users = 3
+		testData, _ = NewTestDataWithConfig(config)
+
+		auth = NewAuthenticationProvider()
+		api, err := NewAPIWithConfig(testData, auth, Config{PasswordCost: bcrypt.MinCost})
+		Expect(err).To(BeNil())
+
+		variables = make(map[string]interface{})
+		params = graphql.Params{
+			Schema:         api.Schema,
+			VariableValues: variables,
+		}
+	})
+
+	fieldErrors := func(err gqlerrors.FormattedError) []fieldError {
+		Expect(err.Extensions).To(HaveKeyWithValue("code", "BAD_USER_INPUT"))
+		return err.Extensions["fields"].([]fieldError)
+	}
+
+	When("register", func() {
+		BeforeEach(func() {
+			params.RequestString = `
+				mutation ($email: String!, $username: String!, $name: String!, $password: String!) {
+					register(email:$email, username:$username, name:$name, password:$password){
+						token
+						refreshToken
+						user {
+							id
+							name
+							role
+						}
+					}
+				}`
+			variables["email"] = "new.user@email.co.uk"
+			variables["username"] = "new.user"
+			variables["name"] = "New User"
+			variables["password"] = "NewPassword1"
+		})
+
+		It("creates the user and logs them in", func() {
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+
+			authentication := getData[data.Authentication](r, "register")
+
+			Expect(authentication.User.ID).To(Equal(3))
+			Expect(authentication.User.Name).To(Equal("New User"))
+			Expect(authentication.User.Role).To(Equal(data.RoleUser))
+			Expect(authentication.RefreshToken).ToNot(BeEmpty())
+
+			identity, err := auth.ValidateToken(authentication.Token)
+			Expect(err).To(BeNil())
+			Expect(identity.UserID).To(Equal(3))
+		})
+
+		It("hashes the password with the configured cost", func() {
+			graphql.Do(params)
+
+			hash := []byte(must(testData.GetUser(3)).PasswordHash)
+			Expect(bcrypt.CompareHashAndPassword(hash, []byte("NewPassword1"))).To(Succeed())
+			Expect(bcrypt.Cost(hash)).To(Equal(bcrypt.MinCost))
+		})
+
+		It("can log in with the new account", func() {
+			graphql.Do(params)
+
+			params.RequestString = `
+				mutation ($email: String!, $password: String!) {
+					login(email:$email, password:$password){
+						user {
+							id
+						}
+					}
+				}`
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+			Expect(getData[data.Authentication](r, "login").User.ID).To(Equal(3))
+		})
+
+		It("rejects a duplicate email", func() {
+			variables["email"] = must(testData.GetUser(0)).Email
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(fieldErrors(r.Errors[0])).To(Equal([]fieldError{
+				{Path: []string{"email"}, Message: data.ErrEmailTaken.Error()},
+			}))
+			Expect(testData.GetUsers()).To(HaveLen(3))
+		})
+
+		It("reports every invalid argument", func() {
+			variables["email"] = "not an email"
+			variables["username"] = " "
+			variables["password"] = "short"
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(r.Errors[0].Message).To(Equal("invalid input: email is not a valid email address; username is required; password must be at least 8 characters"))
+			Expect(r.Errors[0].Path).To(Equal([]interface{}{"register"}))
+			Expect(fieldErrors(r.Errors[0])).To(Equal([]fieldError{
+				{Path: []string{"email"}, Message: "is not a valid email address"},
+				{Path: []string{"username"}, Message: "is required"},
+				{Path: []string{"password"}, Message: "must be at least 8 characters"},
+			}))
+			Expect(testData.GetUsers()).To(HaveLen(3))
+		})
+	})
+
+	When("changePassword", func() {
+		BeforeEach(func() {
+			params.RequestString = `
+				mutation ($oldPassword: String!, $newPassword: String!) {
+					changePassword(oldPassword:$oldPassword, newPassword:$newPassword)
+				}`
+			params.Context = WithIdentity(context.Background(), Identity{UserID: 1})
+			variables["oldPassword"] = "Password1"
+			variables["newPassword"] = "NewPassword1"
+		})
+
+		It("changes the password of the caller", func() {
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+			Expect(r.Data).To(Equal(map[string]interface{}{"changePassword": true}))
+
+			hash := []byte(must(testData.GetUser(1)).PasswordHash)
+			Expect(bcrypt.CompareHashAndPassword(hash, []byte("NewPassword1"))).To(Succeed())
+			Expect(bcrypt.Cost(hash)).To(Equal(bcrypt.MinCost))
+		})
+
+		It("rejects an incorrect old password", func() {
+			original := must(testData.GetUser(1)).PasswordHash
+			variables["oldPassword"] = "not their password"
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(fieldErrors(r.Errors[0])).To(Equal([]fieldError{
+				{Path: []string{"oldPassword"}, Message: "is incorrect"},
+			}))
+			Expect(must(testData.GetUser(1)).PasswordHash).To(Equal(original))
+		})
+
+		It("rejects a weak new password", func() {
+			variables["newPassword"] = "onlyletters"
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(fieldErrors(r.Errors[0])).To(Equal([]fieldError{
+				{Path: []string{"newPassword"}, Message: "must contain a letter and a digit"},
+			}))
+		})
+
+		It("rejects an unchanged password", func() {
+			variables["newPassword"] = "Password1"
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(fieldErrors(r.Errors[0])).To(Equal([]fieldError{
+				{Path: []string{"newPassword"}, Message: "must be different from the old password"},
+			}))
+		})
+
+		It("requires authentication", func() {
+			params.Context = context.Background()
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
+		})
+	})
+
+	When("resetPassword", func() {
+		BeforeEach(func() {
+			params.RequestString = `
+				mutation ($id: Int!, $newPassword: String!) {
+					resetPassword(id:$id, newPassword:$newPassword)
+				}`
+			params.Context = WithIdentity(context.Background(), Identity{UserID: 0})
+			variables["id"] = 1
+			variables["newPassword"] = "NewPassword1"
+		})
+
+		It("sets the password of the user", func() {
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+			Expect(r.Data).To(Equal(map[string]interface{}{"resetPassword": true}))
+
+			hash := []byte(must(testData.GetUser(1)).PasswordHash)
+			Expect(bcrypt.CompareHashAndPassword(hash, []byte("NewPassword1"))).To(Succeed())
+		})
+
+		It("rejects a weak new password", func() {
+			variables["newPassword"] = "onlyletters"
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(fieldErrors(r.Errors[0])).To(Equal([]fieldError{
+				{Path: []string{"newPassword"}, Message: "must contain a letter and a digit"},
+			}))
+		})
+
+		It("rejects an unknown user", func() {
+			variables["id"] = -1
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
+		})
+
+		It("is forbidden for other users", func() {
+			original := must(testData.GetUser(2)).PasswordHash
+			params.Context = WithIdentity(context.Background(), Identity{UserID: 1})
+			variables["id"] = 2
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Forbidden))
+			Expect(must(testData.GetUser(2)).PasswordHash).To(Equal(original))
+		})
+	})
+
+	It("rejects an invalid password cost", func() {
+		_, err := NewAPIWithConfig(testData, auth, Config{PasswordCost: bcrypt.MaxCost + 1})
+
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -1,15 +1,18 @@
 package api
 
 import (
-	"errors"
 	"fmt"
+	"log"
 
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
 	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
 	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
 	"github.com/graphql-go/graphql"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var errInvalidCredentials = apierrors.New(apierrors.Unauthenticated, "invalid email or password")
+
 type API struct {
 	Schema    graphql.Schema
 	UserType  *graphql.Object
@@ -17,7 +20,40 @@ type API struct {
 	PhotoType *graphql.Object
 }
 
+// Config configures the API.
+type Config struct {
+	// PasswordCost is the bcrypt cost of the passwords set through the API.
+	PasswordCost int
+	// Production hides the message of internal errors from clients.
+	Production bool
+	// ErrorLog logs internal errors in production. If nil, the standard logger is used.
+	ErrorLog *log.Logger
+	// Snapshots adds the mutations that save, restore and reset the data, if the data model supports them.
+	Snapshots bool
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PasswordCost: bcrypt.DefaultCost,
+		Snapshots:    true,
+	}
+}
+
 func NewAPI(dataModel data.IData, authenticationProvider IAuthenticationProvider) *API {
+	api, err := NewAPIWithConfig(dataModel, authenticationProvider, DefaultConfig())
+	if err != nil {
+		panic(err)
+	}
+	return api
+}
+
+func NewAPIWithConfig(dataModel data.IData, authenticationProvider IAuthenticationProvider, config Config) (*API, error) {
+	if config.PasswordCost < bcrypt.MinCost || config.PasswordCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("password cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	policy := authorizationPolicy{dataModel: dataModel}
+
 	photoType := graphql.NewObject(graphql.ObjectConfig{
 		Name:        "Photo",
 		Description: "A photo.",
@@ -52,6 +88,8 @@ func NewAPI(dataModel data.IData, authenticationProvider IAuthenticationProvider
 		},
 	})
 
+	photoConnectionType := newConnectionType[data.Photo]("Photo", photoType)
+
 	albumType := graphql.NewObject(graphql.ObjectConfig{
 		Name:        "Album",
 		Description: "A album.",
@@ -86,21 +124,43 @@ func NewAPI(dataModel data.IData, authenticationProvider IAuthenticationProvider
 			"photos": &graphql.Field{
 				Type:        graphql.NewList(photoType),
 				Description: "The albums photos.",
-				Args: graphql.FieldConfigArgument{
-					"limit": &graphql.ArgumentConfig{
-						Description: "limit the number of users",
-						Type:        graphql.Int,
-					},
+				Args:        pagingArgs("photos", nil),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveTypeWithError(p, func(album data.Album) (interface{}, error) {
+						return loadList(p, album.ID, photosByAlbumID, dataModel.GetPhotosByAlbumID)
+					})
 				},
+			},
+			"photosConnection": &graphql.Field{
+				Type:        photoConnectionType,
+				Description: "The albums photos, as a connection.",
+				Args:        connectionArgs(nil),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					return resolveType(p, func(album data.Album) interface{} {
-						return utils.TryLimitIfPresent(dataModel.GetPhotosByAlbumID(album.ID), p.Args)
+					return resolveTypeWithError(p, func(album data.Album) (interface{}, error) {
+						return loadConnection(p, album.ID, photosByAlbumID, dataModel.GetPhotosByAlbumID, photoCursors)
 					})
 				},
 			},
 		},
 	})
 
+	albumConnectionType := newConnectionType[data.Album]("Album", albumType)
+
+	roleType := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "Role",
+		Description: "The role of a user.",
+		Values: graphql.EnumValueConfigMap{
+			"USER": &graphql.EnumValueConfig{
+				Value:       data.RoleUser,
+				Description: "A user, who can only see and change their own data.",
+			},
+			"ADMIN": &graphql.EnumValueConfig{
+				Value:       data.RoleAdmin,
+				Description: "An administrator, who can see and change all data.",
+			},
+		},
+	})
+
 	userType := graphql.NewObject(graphql.ObjectConfig{
 		Name:        "User",
 		Description: "A user.",
@@ -134,46 +194,183 @@ func NewAPI(dataModel data.IData, authenticationProvider IAuthenticationProvider
 			},
 			"email": &graphql.Field{
 				Type:        graphql.String,
-				Description: "The email of the user.",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				Description: "The email of the user. Only visible to the user, or an administrator.",
+				Resolve: policy.private(func(p graphql.ResolveParams) (interface{}, error) {
 					return resolveType(p, func(user data.User) interface{} {
 						return user.Email
 					})
-				},
+				}),
 			},
 			"passwordHash": &graphql.Field{
 				Type:        graphql.String,
-				Description: "The password hash of the user.",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				Description: "The password hash of the user. Only visible to the user, or an administrator.",
+				Resolve: policy.private(func(p graphql.ResolveParams) (interface{}, error) {
 					return resolveType(p, func(user data.User) interface{} {
 						return string(user.PasswordHash[:])
 					})
+				}),
+			},
+			"role": &graphql.Field{
+				Type:        roleType,
+				Description: "The role of the user.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveType(p, func(user data.User) interface{} {
+						return user.Role
+					})
 				},
 			},
 			"albums": &graphql.Field{
 				Type:        graphql.NewList(albumType),
 				Description: "The users albums.",
-				Args: graphql.FieldConfigArgument{
-					"limit": &graphql.ArgumentConfig{
-						Description: "limit the number of users",
-						Type:        graphql.Int,
-					},
+				Args:        pagingArgs("albums", nil),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveTypeWithError(p, func(user data.User) (interface{}, error) {
+						return loadList(p, user.ID, albumsByUserID, dataModel.GetAlbumsByUserID)
+					})
 				},
+			},
+			"albumsConnection": &graphql.Field{
+				Type:        albumConnectionType,
+				Description: "The users albums, as a connection.",
+				Args:        connectionArgs(nil),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					return resolveType(p, func(user data.User) interface{} {
-						return utils.TryLimitIfPresent(dataModel.GetAlbumsByUserID(user.ID), p.Args)
+					return resolveTypeWithError(p, func(user data.User) (interface{}, error) {
+						return loadConnection(p, user.ID, albumsByUserID, dataModel.GetAlbumsByUserID, albumCursors)
 					})
 				},
 			},
 		},
 	})
 
+	userConnectionType := newConnectionType[data.User]("User", userType)
+
+	userWhereType := newWhereType("UserWhere", "Filters on users.", graphql.InputObjectConfigFieldMap{
+		"id": &graphql.InputObjectFieldConfig{
+			Type:        intFilterType,
+			Description: "The id of the user.",
+		},
+		"name": &graphql.InputObjectFieldConfig{
+			Type:        stringFilterType,
+			Description: "The name of the user.",
+		},
+		"username": &graphql.InputObjectFieldConfig{
+			Type:        stringFilterType,
+			Description: "The username of the user.",
+		},
+		"email": &graphql.InputObjectFieldConfig{
+			Type:        stringFilterType,
+			Description: "The email of the user. Only matches users whose email the caller can see.",
+		},
+		"role": &graphql.InputObjectFieldConfig{
+			Type:        newEnumFilterType(roleType),
+			Description: "The role of the user.",
+		},
+	})
+
+	albumWhereType := newWhereType("AlbumWhere", "Filters on albums.", graphql.InputObjectConfigFieldMap{
+		"id": &graphql.InputObjectFieldConfig{
+			Type:        intFilterType,
+			Description: "The id of the album.",
+		},
+		"userid": &graphql.InputObjectFieldConfig{
+			Type:        intFilterType,
+			Description: "The id of the user.",
+		},
+		"description": &graphql.InputObjectFieldConfig{
+			Type:        stringFilterType,
+			Description: "The description of the album.",
+		},
+	})
+
+	photoWhereType := newWhereType("PhotoWhere", "Filters on photos.", graphql.InputObjectConfigFieldMap{
+		"id": &graphql.InputObjectFieldConfig{
+			Type:        intFilterType,
+			Description: "The id of the photo.",
+		},
+		"albumid": &graphql.InputObjectFieldConfig{
+			Type:        intFilterType,
+			Description: "The id of the album.",
+		},
+		"description": &graphql.InputObjectFieldConfig{
+			Type:        stringFilterType,
+			Description: "The description of the photo.",
+		},
+	})
+
+	userOrderByType := newOrderByType("User", graphql.EnumValueConfigMap{
+		"ID":       &graphql.EnumValueConfig{Value: "id", Description: "The id of the user."},
+		"NAME":     &graphql.EnumValueConfig{Value: "name", Description: "The name of the user."},
+		"USERNAME": &graphql.EnumValueConfig{Value: "username", Description: "The username of the user."},
+		"ROLE":     &graphql.EnumValueConfig{Value: "role", Description: "The role of the user."},
+	})
+
+	albumOrderByType := newOrderByType("Album", graphql.EnumValueConfigMap{
+		"ID":          &graphql.EnumValueConfig{Value: "id", Description: "The id of the album."},
+		"USERID":      &graphql.EnumValueConfig{Value: "userid", Description: "The id of the user."},
+		"DESCRIPTION": &graphql.EnumValueConfig{Value: "description", Description: "The description of the album."},
+	})
+
+	photoOrderByType := newOrderByType("Photo", graphql.EnumValueConfigMap{
+		"ID":          &graphql.EnumValueConfig{Value: "id", Description: "The id of the photo."},
+		"ALBUMID":     &graphql.EnumValueConfig{Value: "albumid", Description: "The id of the album."},
+		"DESCRIPTION": &graphql.EnumValueConfig{Value: "description", Description: "The description of the photo."},
+	})
+
+	// Users cannot be sorted by email, as it would reveal the order of the emails they cannot see.
+	userOrderFields := map[string]orderField[data.User]{
+		"id":       newOrderField(func(user data.User) int { return user.ID }),
+		"name":     newOrderField(func(user data.User) string { return user.Name }),
+		"username": newOrderField(func(user data.User) string { return user.Username }),
+		"role":     newOrderField(func(user data.User) data.Role { return user.Role }),
+	}
+
+	albumOrderFields := map[string]orderField[data.Album]{
+		"id":          newOrderField(func(album data.Album) int { return album.ID }),
+		"userid":      newOrderField(func(album data.Album) int { return album.UserID }),
+		"description": newOrderField(func(album data.Album) string { return album.Description }),
+	}
+
+	photoOrderFields := map[string]orderField[data.Photo]{
+		"id":          newOrderField(func(photo data.Photo) int { return photo.ID }),
+		"albumid":     newOrderField(func(photo data.Photo) int { return photo.AlbumID }),
+		"description": newOrderField(func(photo data.Photo) string { return photo.Description }),
+	}
+
+	// userFilters are the filters on the fields of users, for the caller.
+	userFilters := func(p graphql.ResolveParams) map[string]filterField[data.User] {
+		canAccess := policy.of(p).canAccess(p.Context)
+		email := fieldFilter(func(user data.User) string { return user.Email })
+
+		return map[string]filterField[data.User]{
+			"id":       fieldFilter(func(user data.User) int { return user.ID }),
+			"name":     fieldFilter(func(user data.User) string { return user.Name }),
+			"username": fieldFilter(func(user data.User) string { return user.Username }),
+			"email": func(filter map[string]interface{}) func(user data.User) bool {
+				return utils.All(func(user data.User) bool { return canAccess(user.ID) }, email(filter))
+			},
+			"role": fieldFilter(func(user data.User) data.Role { return user.Role }),
+		}
+	}
+
+	albumFilters := map[string]filterField[data.Album]{
+		"id":          fieldFilter(func(album data.Album) int { return album.ID }),
+		"userid":      fieldFilter(func(album data.Album) int { return album.UserID }),
+		"description": fieldFilter(func(album data.Album) string { return album.Description }),
+	}
+
+	photoFilters := map[string]filterField[data.Photo]{
+		"id":          fieldFilter(func(photo data.Photo) int { return photo.ID }),
+		"albumid":     fieldFilter(func(photo data.Photo) int { return photo.AlbumID }),
+		"description": fieldFilter(func(photo data.Photo) string { return photo.Description }),
+	}
+
 	queryType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Query",
 		Fields: graphql.Fields{
+			"search": newSearchField(dataModel, userType, albumType, photoType),
 			"user": &graphql.Field{
 				Type:        userType,
-				Description: "User by ID",
+				Description: "User by ID, or null if there is no such user",
 				Args: graphql.FieldConfigArgument{
 					"id": &graphql.ArgumentConfig{
 						Description: "id of the user",
@@ -181,25 +378,48 @@ func NewAPI(dataModel data.IData, authenticationProvider IAuthenticationProvider
 					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					return dataModel.GetUser(p.Args["id"].(int)), nil
+					return resolveItem(dataFor(p, dataModel).GetUser(p.Args["id"].(int)))
 				},
 			},
 			"users": &graphql.Field{
 				Type:        graphql.NewList(userType),
 				Description: "All users",
-				Args: graphql.FieldConfigArgument{
-					"limit": &graphql.ArgumentConfig{
-						Description: "limit the number of users",
-						Type:        graphql.Int,
-					},
+				Args: pagingArgs("users", graphql.FieldConfigArgument{
+					"where":   whereArg("users", userWhereType),
+					"orderBy": orderByArg("users", userOrderByType),
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					users, err := dataFor(p, dataModel).GetUsers()
+					if err != nil {
+						return nil, dataError(err)
+					}
+
+					users = filterWhere(users, p.Args, userFilters(p))
+					users = orderItems(users, p.Args, userOrderFields)
+					return utils.TryPageIfPresent(users, p.Args)
 				},
+			},
+			"usersConnection": &graphql.Field{
+				Type:        userConnectionType,
+				Description: "All users, as a connection",
+				Args: connectionArgs(graphql.FieldConfigArgument{
+					"where":   whereArg("users", userWhereType),
+					"orderBy": orderByArg("users", userOrderByType),
+				}),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					return utils.TryLimitIfPresent(dataModel.GetUsers(), p.Args), nil
+					users, err := dataFor(p, dataModel).GetUsers()
+					if err != nil {
+						return nil, dataError(err)
+					}
+
+					users = filterWhere(users, p.Args, userFilters(p))
+					users = orderItems(users, p.Args, userOrderFields)
+					return newConnection(users, p.Args, userCursors)
 				},
 			},
 			"album": &graphql.Field{
 				Type:        albumType,
-				Description: "Album by ID",
+				Description: "Album by ID, or null if there is no such album",
 				Args: graphql.FieldConfigArgument{
 					"id": &graphql.ArgumentConfig{
 						Description: "id of the album",
@@ -207,38 +427,73 @@ func NewAPI(dataModel data.IData, authenticationProvider IAuthenticationProvider
 					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					return dataModel.GetAlbum(p.Args["id"].(int)), nil
+					return resolveItem(dataFor(p, dataModel).GetAlbum(p.Args["id"].(int)))
 				},
 			},
 			"albums": &graphql.Field{
 				Type:        graphql.NewList(albumType),
 				Description: "All albums",
-				Args: graphql.FieldConfigArgument{
+				Args: pagingArgs("albums", graphql.FieldConfigArgument{
 					"userid": &graphql.ArgumentConfig{
 						Description: "id of the user",
 						Type:        graphql.Int,
 					},
-					"limit": &graphql.ArgumentConfig{
-						Description: "limit the number of albums",
+					"where":   whereArg("albums", albumWhereType),
+					"orderBy": orderByArg("albums", albumOrderByType),
+				}),
+
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var albums []data.Album
+					var err error
+
+					if id, exists := p.Args["userid"].(int); exists {
+						albums, err = dataFor(p, dataModel).GetAlbumsByUserID(id)
+					} else {
+						albums, err = dataFor(p, dataModel).GetAlbums()
+					}
+					if err != nil {
+						return nil, dataError(err)
+					}
+
+					albums = filterWhere(albums, p.Args, albumFilters)
+					albums = orderItems(albums, p.Args, albumOrderFields)
+
+					return utils.TryPageIfPresent(albums, p.Args)
+				},
+			},
+			"albumsConnection": &graphql.Field{
+				Type:        albumConnectionType,
+				Description: "All albums, as a connection",
+				Args: connectionArgs(graphql.FieldConfigArgument{
+					"userid": &graphql.ArgumentConfig{
+						Description: "id of the user",
 						Type:        graphql.Int,
 					},
-				},
-
+					"where":   whereArg("albums", albumWhereType),
+					"orderBy": orderByArg("albums", albumOrderByType),
+				}),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					var albums []data.Album
+					var err error
 
 					if id, exists := p.Args["userid"].(int); exists {
-						albums = dataModel.GetAlbumsByUserID(id)
+						albums, err = dataFor(p, dataModel).GetAlbumsByUserID(id)
 					} else {
-						albums = dataModel.GetAlbums()
+						albums, err = dataFor(p, dataModel).GetAlbums()
+					}
+					if err != nil {
+						return nil, dataError(err)
 					}
 
-					return utils.TryLimitIfPresent(albums, p.Args), nil
+					albums = filterWhere(albums, p.Args, albumFilters)
+					albums = orderItems(albums, p.Args, albumOrderFields)
+
+					return newConnection(albums, p.Args, albumCursors)
 				},
 			},
 			"photo": &graphql.Field{
 				Type:        photoType,
-				Description: "Photo by ID",
+				Description: "Photo by ID, or null if there is no such photo",
 				Args: graphql.FieldConfigArgument{
 					"id": &graphql.ArgumentConfig{
 						Description: "id of the photo",
@@ -246,33 +501,68 @@ func NewAPI(dataModel data.IData, authenticationProvider IAuthenticationProvider
 					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					return dataModel.GetPhoto(p.Args["id"].(int)), nil
+					return resolveItem(dataFor(p, dataModel).GetPhoto(p.Args["id"].(int)))
 				},
 			},
 			"photos": &graphql.Field{
 				Type:        graphql.NewList(photoType),
 				Description: "All photos",
-				Args: graphql.FieldConfigArgument{
+				Args: pagingArgs("photos", graphql.FieldConfigArgument{
 					"albumid": &graphql.ArgumentConfig{
 						Description: "id of the album",
 						Type:        graphql.Int,
 					},
-					"limit": &graphql.ArgumentConfig{
-						Description: "limit the number of photos",
+					"where":   whereArg("photos", photoWhereType),
+					"orderBy": orderByArg("photos", photoOrderByType),
+				}),
+
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var photos []data.Photo
+					var err error
+
+					if id, exists := p.Args["albumid"].(int); exists {
+						photos, err = dataFor(p, dataModel).GetPhotosByAlbumID(id)
+					} else {
+						photos, err = dataFor(p, dataModel).GetPhotos()
+					}
+					if err != nil {
+						return nil, dataError(err)
+					}
+
+					photos = filterWhere(photos, p.Args, photoFilters)
+					photos = orderItems(photos, p.Args, photoOrderFields)
+
+					return utils.TryPageIfPresent(photos, p.Args)
+				},
+			},
+			"photosConnection": &graphql.Field{
+				Type:        photoConnectionType,
+				Description: "All photos, as a connection",
+				Args: connectionArgs(graphql.FieldConfigArgument{
+					"albumid": &graphql.ArgumentConfig{
+						Description: "id of the album",
 						Type:        graphql.Int,
 					},
-				},
-
+					"where":   whereArg("photos", photoWhereType),
+					"orderBy": orderByArg("photos", photoOrderByType),
+				}),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					var photos []data.Photo
+					var err error
 
 					if id, exists := p.Args["albumid"].(int); exists {
-						photos = dataModel.GetPhotosByAlbumID(id)
+						photos, err = dataFor(p, dataModel).GetPhotosByAlbumID(id)
 					} else {
-						photos = dataModel.GetPhotos()
+						photos, err = dataFor(p, dataModel).GetPhotos()
+					}
+					if err != nil {
+						return nil, dataError(err)
 					}
 
-					return utils.TryLimitIfPresent(photos, p.Args), nil
+					photos = filterWhere(photos, p.Args, photoFilters)
+					photos = orderItems(photos, p.Args, photoOrderFields)
+
+					return newConnection(photos, p.Args, photoCursors)
 				},
 			},
 		},
@@ -290,6 +580,24 @@ func NewAPI(dataModel data.IData, authenticationProvider IAuthenticationProvider
 					})
 				},
 			},
+			"refreshToken": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Single use token for refreshing the authentication token",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveType(p, func(auth data.Authentication) interface{} {
+						return auth.RefreshToken
+					})
+				},
+			},
+			"expiresAt": &graphql.Field{
+				Type:        graphql.DateTime,
+				Description: "Expiry of the authentication token",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveType(p, func(auth data.Authentication) interface{} {
+						return auth.ExpiresAt
+					})
+				},
+			},
 			"user": &graphql.Field{
 				Type:        userType,
 				Description: "User",
@@ -302,64 +610,175 @@ func NewAPI(dataModel data.IData, authenticationProvider IAuthenticationProvider
 		},
 	})
 
-	mutationType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Mutation",
-		Fields: graphql.Fields{
-			"login": &graphql.Field{
-				Type:        authenticationType,
-				Description: "User authentication",
-				Args: graphql.FieldConfigArgument{
-					"email": &graphql.ArgumentConfig{
-						Description: "email of the user",
-						Type:        graphql.NewNonNull(graphql.String),
-					},
-					"password": &graphql.ArgumentConfig{
-						Description: "password of the user",
-						Type:        graphql.NewNonNull(graphql.String),
-					},
+	mutationFields := graphql.Fields{
+		"login": &graphql.Field{
+			Type:        authenticationType,
+			Description: "User authentication",
+			Args: graphql.FieldConfigArgument{
+				"email": &graphql.ArgumentConfig{
+					Description: "email of the user",
+					Type:        graphql.NewNonNull(graphql.String),
 				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					user, err := dataModel.GetUserWithEmail(p.Args["email"].(string))
+				"password": &graphql.ArgumentConfig{
+					Description: "password of the user",
+					Type:        graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, err := dataModel.GetUserWithEmail(p.Args["email"].(string))
 
-					if err != nil {
-						return nil, errors.New("invalid email or password")
-					}
+				if err != nil {
+					return nil, errInvalidCredentials
+				}
 
-					if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(p.Args["password"].(string))); err != nil {
-						return nil, errors.New("invalid email or password")
-					}
+				if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(p.Args["password"].(string))); err != nil {
+					return nil, errInvalidCredentials
+				}
 
-					token, err := authenticationProvider.GetToken(user.ID)
+				tokens, err := authenticationProvider.IssueTokens(user.ID)
 
-					if err != nil {
-						return nil, errors.New("login failed")
-					}
+				if err != nil {
+					return nil, fmt.Errorf("login failed: %w", err)
+				}
 
-					return data.Authentication{
-						Token: token,
-						User:  *user,
-					}, nil
+				return newAuthentication(tokens, *user), nil
+			},
+		},
+		"refreshToken": &graphql.Field{
+			Type:        authenticationType,
+			Description: "Exchange a refresh token for new tokens",
+			Args: graphql.FieldConfigArgument{
+				"refreshToken": &graphql.ArgumentConfig{
+					Description: "refresh token from login or a previous refresh",
+					Type:        graphql.NewNonNull(graphql.String),
 				},
 			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				tokens, err := authenticationProvider.RefreshTokens(p.Args["refreshToken"].(string))
+
+				if err != nil {
+					return nil, err
+				}
+
+				user, err := dataModel.GetUser(tokens.UserID)
+
+				if err != nil {
+					// The user has been deleted since they logged in.
+					authenticationProvider.RevokeRefreshToken(tokens.RefreshToken)
+					return nil, errInvalidRefreshToken
+				}
+
+				return newAuthentication(tokens, user), nil
+			},
+		},
+		"logout": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "Revoke a refresh token, and the authentication token of the caller",
+			Args: graphql.FieldConfigArgument{
+				"refreshToken": &graphql.ArgumentConfig{
+					Description: "refresh token to revoke",
+					Type:        graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if err := authenticationProvider.RevokeRefreshToken(p.Args["refreshToken"].(string)); err != nil {
+					return nil, err
+				}
+
+				if identity, ok := IdentityFromContext(p.Context); ok {
+					authenticationProvider.RevokeToken(identity)
+				}
+
+				return true, nil
+			},
 		},
+	}
+
+	if writableData, ok := dataModel.(data.IWritableData); ok {
+		for name, field := range newCrudMutationFields(writableData, policy, config.PasswordCost, userType, albumType, photoType) {
+			mutationFields[name] = field
+		}
+		for name, field := range newAccountMutationFields(writableData, authenticationProvider, policy, config.PasswordCost, authenticationType) {
+			mutationFields[name] = field
+		}
+	}
+
+	if restorableData, ok := dataModel.(data.IRestorableData); ok && config.Snapshots {
+		queryType.AddFieldConfig("snapshots", newSnapshotQueryField(restorableData, policy))
+		for name, field := range newSnapshotMutationFields(restorableData, policy) {
+			mutationFields[name] = field
+		}
+	}
+
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Mutation",
+		Fields: mutationFields,
 	})
 
 	schema, _ := graphql.NewSchema(graphql.SchemaConfig{
-		Query:    queryType,
-		Mutation: mutationType,
+		Query:      queryType,
+		Mutation:   mutationType,
+		Extensions: []graphql.Extension{requestExtension{dataModel: dataModel}},
 	})
 
+	errorLog := config.ErrorLog
+	if errorLog == nil {
+		errorLog = log.Default()
+	}
+	codeErrors(schema, config.Production, errorLog)
+
 	return &API{
 		Schema:    schema,
 		UserType:  userType,
 		AlbumType: albumType,
 		PhotoType: photoType,
-	}
+	}, nil
 }
 
 func resolveType[T any](p graphql.ResolveParams, onOk func(model T) interface{}) (interface{}, error) {
-	if model, ok := p.Source.(T); ok {
+	return resolveTypeWithError(p, func(model T) (interface{}, error) {
 		return onOk(model), nil
+	})
+}
+
+func resolveTypeWithError[T any](p graphql.ResolveParams, onOk func(model T) (interface{}, error)) (interface{}, error) {
+	if model, ok := p.Source.(T); ok {
+		return onOk(model)
 	}
 	return nil, fmt.Errorf("source is not of type %T", *new(T))
 }
+
+func newAuthentication(tokens Tokens, user data.User) data.Authentication {
+	return data.Authentication{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         user,
+	}
+}
+
+// pagingArgs returns the limit and offset arguments for a list of items, along with any extra arguments.
+func pagingArgs(items string, extra graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{
+		"limit": &graphql.ArgumentConfig{
+			Description: "limit the number of " + items,
+			Type:        graphql.Int,
+		},
+		"offset": &graphql.ArgumentConfig{
+			Description: "skip the first n " + items,
+			Type:        graphql.Int,
+		},
+	}
+
+	for name, arg := range extra {
+		args[name] = arg
+	}
+
+	return args
+}
+
+var userCursors = newCursors("User", func(user data.User) int { return user.ID })
+
+var albumCursors = newCursors("Album", func(album data.Album) int { return album.ID })
+
+var photoCursors = newCursors("Photo", func(photo data.Photo) int { return photo.ID })
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"context"
+
 	"fmt"
+	"time"
 
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
 	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
 	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
 	"github.com/graphql-go/graphql"
@@ -16,15 +20,15 @@ var _ = Describe("Api", func() {
 	var api *API
 
 	Context("Valid Schema", func() {
-		photoTests := utils.Transform(testData.GetPhotos(), func(v data.Photo) TableEntry {
+		photoTests := utils.Transform(must(testData.GetPhotos()), func(v data.Photo) TableEntry {
 			return Entry(fmt.Sprint(v.ID), v.ID)
 		})
 
-		albumTests := utils.Transform(testData.GetAlbums(), func(v data.Album) TableEntry {
+		albumTests := utils.Transform(must(testData.GetAlbums()), func(v data.Album) TableEntry {
 			return Entry(fmt.Sprint(v.ID), v.ID)
 		})
 
-		userTests := utils.Transform(testData.GetUsers(), func(v data.User) TableEntry {
+		userTests := utils.Transform(must(testData.GetUsers()), func(v data.User) TableEntry {
 			return Entry(fmt.Sprint(v.ID), v.ID)
 		})
 
@@ -39,6 +43,7 @@ var _ = Describe("Api", func() {
 			params = graphql.Params{
 				Schema:         api.Schema,
 				VariableValues: variables,
+				Context:        WithIdentity(context.Background(), Identity{UserID: 0}),
 			}
 		})
 
@@ -68,16 +73,15 @@ var _ = Describe("Api", func() {
 					variables["id"] = -1
 
 					r := graphql.Do(params)
-					Expect(r.Errors).To(BeEmpty())
-
-					album := getData[data.Album](r, "album")
+					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
 
-					Expect(album).To(Equal(data.Album{}))
+					Expect(r.Data).To(HaveKeyWithValue("album", BeNil()))
 				})
 
 				DescribeTable("Get album by ID", func(id int) {
 					variables["id"] = id
-					expected := testData.GetAlbum(id)
+					expected := must(testData.GetAlbum(id))
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(BeEmpty())
@@ -90,7 +94,7 @@ var _ = Describe("Api", func() {
 				DescribeTable("Get all album photos", func(id int) {
 					variables["id"] = id
 					variables["withPhotos"] = true
-					expected := testData.GetPhotosByAlbumID(id)
+					expected := must(testData.GetPhotosByAlbumID(id))
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(BeEmpty())
@@ -127,7 +131,7 @@ var _ = Describe("Api", func() {
 
 					When("limited less than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetPhotosByAlbumID(albumId)) - 1
+							limit = len(must(testData.GetPhotosByAlbumID(albumId))) - 1
 						})
 
 						It("returns limit", func() {
@@ -142,11 +146,11 @@ var _ = Describe("Api", func() {
 
 					When("limited greter than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetPhotosByAlbumID(albumId)) + 1
+							limit = len(must(testData.GetPhotosByAlbumID(albumId))) + 1
 						})
 
 						It("returns size", func() {
-							expected := len(testData.GetPhotosByAlbumID(albumId))
+							expected := len(must(testData.GetPhotosByAlbumID(albumId)))
 
 							r := graphql.Do(params)
 							Expect(r.Errors).To(BeEmpty())
@@ -172,7 +176,7 @@ var _ = Describe("Api", func() {
 				})
 
 				It("Get all albums", func() {
-					expected := testData.GetAlbums()
+					expected := must(testData.GetAlbums())
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(BeEmpty())
@@ -196,7 +200,7 @@ var _ = Describe("Api", func() {
 
 					DescribeTable("Get albums by userID", func(id int) {
 						variables["id"] = id
-						expected := testData.GetAlbumsByUserID(id)
+						expected := must(testData.GetAlbumsByUserID(id))
 
 						r := graphql.Do(params)
 						Expect(r.Errors).To(BeEmpty())
@@ -227,7 +231,7 @@ var _ = Describe("Api", func() {
 
 					When("limited less than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetAlbums()) - 1
+							limit = len(must(testData.GetAlbums())) - 1
 						})
 
 						It("returns limit", func() {
@@ -242,11 +246,11 @@ var _ = Describe("Api", func() {
 
 					When("limited greter than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetAlbums()) + 1
+							limit = len(must(testData.GetAlbums())) + 1
 						})
 
 						It("returns size", func() {
-							expected := len(testData.GetAlbums())
+							expected := len(must(testData.GetAlbums()))
 
 							r := graphql.Do(params)
 							Expect(r.Errors).To(BeEmpty())
@@ -275,17 +279,17 @@ var _ = Describe("Api", func() {
 
 				It("Invalid ID", func() {
 					variables["id"] = -1
-					r := graphql.Do(params)
-					Expect(r.Errors).To(BeEmpty())
 
-					photo := getData[data.Photo](r, "photo")
+					r := graphql.Do(params)
+					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
 
-					Expect(photo).To(Equal(data.Photo{}))
+					Expect(r.Data).To(HaveKeyWithValue("photo", BeNil()))
 				})
 
 				DescribeTable("Get photo by ID", func(id int) {
 					variables["id"] = id
-					expected := testData.GetPhoto(id)
+					expected := must(testData.GetPhoto(id))
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(BeEmpty())
@@ -309,7 +313,7 @@ var _ = Describe("Api", func() {
 				})
 
 				It("Get all Photos", func() {
-					expected := testData.GetPhotos()
+					expected := must(testData.GetPhotos())
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(BeEmpty())
@@ -333,7 +337,7 @@ var _ = Describe("Api", func() {
 
 					DescribeTable("Get photos by albumID", func(id int) {
 						variables["id"] = id
-						expected := testData.GetPhotosByAlbumID(id)
+						expected := must(testData.GetPhotosByAlbumID(id))
 
 						r := graphql.Do(params)
 						Expect(r.Errors).To(BeEmpty())
@@ -364,7 +368,7 @@ var _ = Describe("Api", func() {
 
 					When("limited less than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetPhotos()) - 1
+							limit = len(must(testData.GetPhotos())) - 1
 						})
 
 						It("returns limit", func() {
@@ -379,11 +383,11 @@ var _ = Describe("Api", func() {
 
 					When("limited greter than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetPhotos()) + 1
+							limit = len(must(testData.GetPhotos())) + 1
 						})
 
 						It("returns size", func() {
-							expected := len(testData.GetPhotos())
+							expected := len(must(testData.GetPhotos()))
 
 							r := graphql.Do(params)
 							Expect(r.Errors).To(BeEmpty())
@@ -408,6 +412,7 @@ var _ = Describe("Api", func() {
 								username
 								email
 								passwordHash
+								role
 								albums @include(if: $withPhotos) {
 									id
 									userid
@@ -419,17 +424,17 @@ var _ = Describe("Api", func() {
 
 				It("Invalid ID", func() {
 					variables["id"] = -1
-					r := graphql.Do(params)
-					Expect(r.Errors).To(BeEmpty())
 
-					user := getData[data.User](r, "user")
+					r := graphql.Do(params)
+					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
 
-					Expect(user).To(Equal(data.User{}))
+					Expect(r.Data).To(HaveKeyWithValue("user", BeNil()))
 				})
 
 				DescribeTable("Get user by ID", func(id int) {
 					variables["id"] = id
-					expected := testData.GetUser(id)
+					expected := must(testData.GetUser(id))
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(BeEmpty())
@@ -442,7 +447,7 @@ var _ = Describe("Api", func() {
 				DescribeTable("Get user albums", func(id int) {
 					variables["withPhotos"] = true
 					variables["id"] = id
-					expected := testData.GetAlbumsByUserID(id)
+					expected := must(testData.GetAlbumsByUserID(id))
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(BeEmpty())
@@ -475,7 +480,7 @@ var _ = Describe("Api", func() {
 
 					When("limited less than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetAlbumsByUserID(0)) - 1
+							limit = len(must(testData.GetAlbumsByUserID(0))) - 1
 						})
 
 						It("returns limit", func() {
@@ -490,11 +495,11 @@ var _ = Describe("Api", func() {
 
 					When("limited greter than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetAlbumsByUserID(0)) + 1
+							limit = len(must(testData.GetAlbumsByUserID(0))) + 1
 						})
 
 						It("returns size", func() {
-							expected := len(testData.GetAlbumsByUserID(0))
+							expected := len(must(testData.GetAlbumsByUserID(0)))
 
 							r := graphql.Do(params)
 							Expect(r.Errors).To(BeEmpty())
@@ -517,12 +522,13 @@ var _ = Describe("Api", func() {
 								username
 								email
 								passwordHash
+								role
 							}
 						}`
 				})
 
 				It("Get all users", func() {
-					expected := testData.GetUsers()
+					expected := must(testData.GetUsers())
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(BeEmpty())
@@ -552,7 +558,7 @@ var _ = Describe("Api", func() {
 
 					When("limited less than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetUsers()) - 1
+							limit = len(must(testData.GetUsers())) - 1
 						})
 
 						It("returns limit", func() {
@@ -567,11 +573,11 @@ var _ = Describe("Api", func() {
 
 					When("limited greter than size", func() {
 						BeforeEach(func() {
-							limit = len(testData.GetUsers()) + 1
+							limit = len(must(testData.GetUsers())) + 1
 						})
 
 						It("returns size", func() {
-							expected := len(testData.GetUsers())
+							expected := len(must(testData.GetUsers()))
 
 							r := graphql.Do(params)
 							Expect(r.Errors).To(BeEmpty())
@@ -584,6 +590,138 @@ var _ = Describe("Api", func() {
 				})
 			})
 		})
+		Context("Paging", func() {
+			type pagedField struct {
+				name  string
+				query string
+				path  []string
+				all   func() []int
+				ids   func(result interface{}) []int
+			}
+
+			userID := func(user data.User) int { return user.ID }
+			albumID := func(album data.Album) int { return album.ID }
+			photoID := func(photo data.Photo) int { return photo.ID }
+
+			fields := []pagedField{
+				{
+					name:  "users",
+					query: `query ($offset: Int, $limit: Int) { users(offset:$offset, limit:$limit) { id } }`,
+					path:  []string{"users"},
+					all: func() []int {
+						return utils.Transform(must(testData.GetUsers()), userID)
+					},
+					ids: idsOf(userID),
+				},
+				{
+					name:  "albums",
+					query: `query ($offset: Int, $limit: Int) { albums(offset:$offset, limit:$limit) { id } }`,
+					path:  []string{"albums"},
+					all: func() []int {
+						return utils.Transform(must(testData.GetAlbums()), albumID)
+					},
+					ids: idsOf(albumID),
+				},
+				{
+					name:  "photos",
+					query: `query ($offset: Int, $limit: Int) { photos(offset:$offset, limit:$limit) { id } }`,
+					path:  []string{"photos"},
+					all: func() []int {
+						return utils.Transform(must(testData.GetPhotos()), photoID)
+					},
+					ids: idsOf(photoID),
+				},
+				{
+					name:  "User.albums",
+					query: `query ($offset: Int, $limit: Int) { user(id:1) { albums(offset:$offset, limit:$limit) { id } } }`,
+					path:  []string{"user", "albums"},
+					all: func() []int {
+						return utils.Transform(must(testData.GetAlbumsByUserID(1)), albumID)
+					},
+					ids: idsOf(albumID),
+				},
+				{
+					name:  "Album.photos",
+					query: `query ($offset: Int, $limit: Int) { album(id:1) { photos(offset:$offset, limit:$limit) { id } } }`,
+					path:  []string{"album", "photos"},
+					all: func() []int {
+						return utils.Transform(must(testData.GetPhotosByAlbumID(1)), photoID)
+					},
+					ids: idsOf(photoID),
+				},
+			}
+
+			// Offsets, limits and the expected range are given relative to the size of the list.
+			type pagingCase struct {
+				name   string
+				offset func(size int) int
+				limit  func(size int) int
+				from   func(size int) int
+				to     func(size int) int
+			}
+
+			constant := func(n int) func(int) int { return func(int) int { return n } }
+			relative := func(n int) func(int) int { return func(size int) int { return size + n } }
+
+			cases := []pagingCase{
+				{name: "offset zero", offset: constant(0), from: constant(0), to: relative(0)},
+				{name: "offset one", offset: constant(1), from: constant(1), to: relative(0)},
+				{name: "offset last item", offset: relative(-1), from: relative(-1), to: relative(0)},
+				{name: "offset equal to size", offset: relative(0), from: relative(0), to: relative(0)},
+				{name: "offset greater than size", offset: relative(1), from: relative(0), to: relative(0)},
+				{name: "limit zero", limit: constant(0), from: constant(0), to: constant(0)},
+				{name: "limit one", limit: constant(1), from: constant(0), to: constant(1)},
+				{name: "limit equal to size", limit: relative(0), from: constant(0), to: relative(0)},
+				{name: "offset and limit", offset: constant(1), limit: constant(2), from: constant(1), to: constant(3)},
+				{name: "offset and limit past the end", offset: relative(-1), limit: constant(2), from: relative(-1), to: relative(0)},
+			}
+
+			pagingEntries := make([]TableEntry, 0)
+			invalidEntries := make([]TableEntry, 0)
+			for _, field := range fields {
+				for _, c := range cases {
+					pagingEntries = append(pagingEntries, Entry(field.name+" "+c.name, field, c))
+				}
+				invalidEntries = append(invalidEntries,
+					Entry(field.name+" negative offset", field, "offset"),
+					Entry(field.name+" negative limit", field, "limit"),
+				)
+			}
+
+			DescribeTable("pages the list", func(field pagedField, c pagingCase) {
+				all := field.all()
+				size := len(all)
+				Expect(size).To(BeNumerically(">", 2))
+
+				if c.offset != nil {
+					variables["offset"] = c.offset(size)
+				}
+				if c.limit != nil {
+					variables["limit"] = c.limit(size)
+				}
+				params.RequestString = field.query
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				var result interface{} = r.Data
+				for _, key := range field.path {
+					result = result.(map[string]interface{})[key]
+				}
+
+				Expect(field.ids(result)).To(Equal(all[c.from(size):c.to(size)]))
+			}, pagingEntries)
+
+			DescribeTable("rejects negative values", func(field pagedField, arg string) {
+				variables[arg] = -1
+				params.RequestString = field.query
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+			}, invalidEntries)
+		})
+
 	})
 
 	Context("Bad Schema", func() {
@@ -601,9 +739,14 @@ var _ = Describe("Api", func() {
 				queries := utils.TransformValues(api.UserType.Fields(), convertFieldDefinitionToQueryString)
 				for _, query := range queries {
 					query := fmt.Sprintf(`{user(id:0){%s}}`, query)
-					params := graphql.Params{Schema: api.Schema, RequestString: query}
+					params := graphql.Params{
+						Schema:        api.Schema,
+						RequestString: query,
+						Context:       WithIdentity(context.Background(), Identity{UserID: 0}),
+					}
 					r := graphql.Do(params)
 					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Internal))
 					Expect(r.Errors[0].Message).To(Equal("source is not of type data.User"))
 				}
 			})
@@ -617,6 +760,7 @@ var _ = Describe("Api", func() {
 					params := graphql.Params{Schema: api.Schema, RequestString: query}
 					r := graphql.Do(params)
 					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Internal))
 					Expect(r.Errors[0].Message).To(Equal("source is not of type data.Photo"))
 				}
 			})
@@ -630,6 +774,7 @@ var _ = Describe("Api", func() {
 					params := graphql.Params{Schema: api.Schema, RequestString: query}
 					r := graphql.Do(params)
 					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Internal))
 					Expect(r.Errors[0].Message).To(Equal("source is not of type data.Album"))
 				}
 			})
@@ -662,6 +807,8 @@ var _ = Describe("Api", func() {
 						mutation ($email: String!, $password: String!) {
 							login(email:$email, password:$password){
 								token
+								refreshToken
+								expiresAt
 								user {
 									id
 								}
@@ -670,16 +817,18 @@ var _ = Describe("Api", func() {
 				})
 
 				It("Authenticates valid data", func() {
-					user := testData.GetUser(0)
+					user := must(testData.GetUser(0))
 					variables["email"] = user.Email
 					variables["password"] = "Password0"
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(BeEmpty())
 
-					authentication := getData[data.Authentication](r, "authentication")
+					authentication := getData[data.Authentication](r, "login")
 
-					Expect(authentication.Token).ToNot(BeNil())
+					Expect(authentication.Token).ToNot(BeEmpty())
+					Expect(authentication.RefreshToken).ToNot(BeEmpty())
+					Expect(authentication.ExpiresAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
 					Expect(authentication.User.ID).To(Equal(user.ID))
 				})
 
@@ -690,22 +839,128 @@ var _ = Describe("Api", func() {
 
 						r := graphql.Do(params)
 						Expect(r.Errors).To(HaveLen(1))
-						Expect(r.Errors[0].Message).To(Equal("invalid email or password"))
+						Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
 					})
 
 					It("Rejects invalid password", func() {
-						user := testData.GetUser(0)
+						user := must(testData.GetUser(0))
 						variables["email"] = user.Email
 						variables["password"] = "not their password"
 
 						r := graphql.Do(params)
 						Expect(r.Errors).To(HaveLen(1))
-						Expect(r.Errors[0].Message).To(Equal("invalid email or password"))
+						Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
 					})
 				})
 			})
 		})
 
+		Context("Sessions", func() {
+			var sessionData data.IWritableData
+			var auth IAuthenticationProvider
+			var tokens Tokens
+
+			BeforeEach(func() {
+				config := data.DefaultGeneratorConfig()
+				config.Users = 3
+				sessionData, _ = NewTestDataWithConfig(config)
+
+				auth = NewAuthenticationProvider()
+				api = NewAPI(sessionData, auth)
+				variables = make(map[string]interface{})
+				params = graphql.Params{
+					Schema:         api.Schema,
+					VariableValues: variables,
+				}
+
+				tokens, _ = auth.IssueTokens(1)
+				variables["refreshToken"] = tokens.RefreshToken
+			})
+
+			When("refreshToken", func() {
+				BeforeEach(func() {
+					mutation = `
+						mutation ($refreshToken: String!) {
+							refreshToken(refreshToken:$refreshToken){
+								token
+								refreshToken
+								expiresAt
+								user {
+									id
+								}
+							}
+						}`
+				})
+
+				It("issues new tokens", func() {
+					r := graphql.Do(params)
+					Expect(r.Errors).To(BeEmpty())
+
+					authentication := getData[data.Authentication](r, "refreshToken")
+
+					Expect(authentication.RefreshToken).ToNot(Equal(tokens.RefreshToken))
+					Expect(authentication.User.ID).To(Equal(1))
+
+					identity, err := auth.ValidateToken(authentication.Token)
+					Expect(err).To(BeNil())
+					Expect(identity.UserID).To(Equal(1))
+				})
+
+				It("rejects a refresh token that has been used", func() {
+					graphql.Do(params)
+
+					r := graphql.Do(params)
+					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
+				})
+
+				It("rejects the refresh token of a deleted user", func() {
+					sessionData.DeleteUser(1)
+
+					r := graphql.Do(params)
+					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
+				})
+			})
+
+			When("logout", func() {
+				BeforeEach(func() {
+					mutation = `
+						mutation ($refreshToken: String!) {
+							logout(refreshToken:$refreshToken)
+						}`
+				})
+
+				It("revokes the refresh token", func() {
+					r := graphql.Do(params)
+					Expect(r.Errors).To(BeEmpty())
+					Expect(r.Data).To(Equal(map[string]interface{}{"logout": true}))
+
+					_, err := auth.RefreshTokens(tokens.RefreshToken)
+					Expect(err).To(MatchError(errInvalidRefreshToken))
+				})
+
+				It("revokes the access token of the caller", func() {
+					identity, _ := auth.ValidateToken(tokens.AccessToken)
+					params.Context = WithIdentity(context.Background(), identity)
+
+					r := graphql.Do(params)
+					Expect(r.Errors).To(BeEmpty())
+
+					_, err := auth.ValidateToken(tokens.AccessToken)
+					Expect(err).To(MatchError("token has been revoked"))
+				})
+
+				It("rejects an unknown refresh token", func() {
+					variables["refreshToken"] = "not a refresh token"
+
+					r := graphql.Do(params)
+					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
+				})
+			})
+		})
+
 		Context("Invalid authentication provider", func() {
 			BeforeEach(func() {
 				auth := newInvalidAuthenticationProvider()
@@ -731,13 +986,13 @@ var _ = Describe("Api", func() {
 				})
 
 				It("fails to Authenticate valid data", func() {
-					user := testData.GetUser(0)
+					user := must(testData.GetUser(0))
 					variables["email"] = user.Email
 					variables["password"] = "Password0"
 
 					r := graphql.Do(params)
 					Expect(r.Errors).To(HaveLen(1))
-					Expect(r.Errors[0].Message).To(Equal("login failed"))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Internal))
 				})
 			})
 		})
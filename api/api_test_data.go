@@ -2,140 +2,671 @@ package api
 
 import (
 	"fmt"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
 
 	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
 	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
-const (
-	numberOfUsers       int = 10
-	numberOfUserAlbums  int = 10
-	numberOfAlbumPhotos int = 10
-)
-
+// testData is an in-memory store, which is safe for concurrent use.
+//
+// Its data is held by a testDataState, which writes change in place, unless the state has been
+// handed out as a snapshot, in which case they change a copy instead. So snapshots never change.
+// The copy shares what it has not changed with the snapshot, so it is cheap to make.
+//
+// Named snapshots and the initial data are saved the same way, so saving, restoring and resetting
+// only swap the state.
 type testData struct {
-	users  map[int]data.User
-	albums map[int]data.Album
-	photos map[int]data.Photo
+	mu     sync.RWMutex
+	state  *testDataState
+	shared atomic.Bool
+
+	nextUserID  int
+	nextAlbumID int
+	nextPhotoID int
+
+	initial   savedState
+	snapshots map[string]savedState
+}
+
+// savedState is a state that has been saved, along with the IDs that new items were to be given.
+type savedState struct {
+	state       *testDataState
+	nextUserID  int
+	nextAlbumID int
+	nextPhotoID int
+}
+
+// testDataState holds the items, along with the IDs of every item in order, the IDs of each user's
+// albums and each album's photos in order, and the user with each email, so that no lookup has to
+// scan or sort every item. Writes keep these indexes up to date.
+//
+// It is not safe for concurrent use while it is being changed.
+type testDataState struct {
+	users  *utils.CopyOnWriteMap[int, data.User]
+	albums *utils.CopyOnWriteMap[int, data.Album]
+	photos *utils.CopyOnWriteMap[int, data.Photo]
+
+	userIDs  sortedIDs
+	albumIDs sortedIDs
+	photoIDs sortedIDs
+
+	// The IDs of each key are copied before they are changed, as they may be shared.
+	albumIDsByUserID  *utils.CopyOnWriteMap[int, []int]
+	photoIDsByAlbumID *utils.CopyOnWriteMap[int, []int]
+	userIDByEmail     *utils.CopyOnWriteMap[string, int]
+
+	index *data.SearchIndex
 }
 
+// sortedIDs are IDs in order, which are copied before they are changed if they are shared.
+type sortedIDs struct {
+	ids    []int
+	shared bool
+}
+
+// emailSeed spreads emails between the shards of userIDByEmail.
+var emailSeed = maphash.MakeSeed()
+
 // Public so that main.go can access it.
 // This should be private once a 'real' data source has been added.
-func NewTestData() data.IData {
-	users := getUserData()
-	albums := getAlbums(maps.Keys(users))
-	photos := getPhotos(maps.Keys(albums))
-	return &testData{
-		users:  users,
-		albums: albums,
-		photos: photos,
+func NewTestData() data.IWritableData {
+	testData, err := NewTestDataWithConfig(data.DefaultGeneratorConfig())
+	if err != nil {
+		panic(err)
 	}
+	return testData
 }
 
-func (testData *testData) GetUsers() []data.User {
-	return utils.OrderedValues(testData.users)
+// NewTestDataWithConfig generates the test data as described by the config.
+// The generated passwords are hashed at the minimum cost, as they are no secret.
+func NewTestDataWithConfig(config data.GeneratorConfig) (data.IWritableData, error) {
+	dataset, err := data.Generate(config)
+	if err != nil {
+		return nil, err
+	}
+	return newTestData(dataset, bcrypt.MinCost)
 }
 
-func (testData *testData) GetAlbums() []data.Album {
-	return utils.OrderedValues(testData.albums)
+// NewTestDataWithDataset holds the dataset, such as one loaded from fixtures, hashing the passwords of its users
+// at the bcrypt cost. The test data can save snapshots of its data, and be reset to the dataset.
+func NewTestDataWithDataset(dataset data.Dataset, passwordCost int) (data.IWritableData, error) {
+	testData, err := newTestData(dataset, passwordCost)
+	if err != nil {
+		return nil, err
+	}
+	return testData, nil
 }
 
-func (testData *testData) GetPhotos() []data.Photo {
-	return utils.OrderedValues(testData.photos)
+func newTestData(dataset data.Dataset, passwordCost int) (*testData, error) {
+	state := &testDataState{
+		users:             utils.NewCopyOnWriteMap[int, data.User](shardOfID),
+		albums:            utils.NewCopyOnWriteMap[int, data.Album](shardOfID),
+		photos:            utils.NewCopyOnWriteMap[int, data.Photo](shardOfID),
+		albumIDsByUserID:  utils.NewCopyOnWriteMap[int, []int](shardOfID),
+		photoIDsByAlbumID: utils.NewCopyOnWriteMap[int, []int](shardOfID),
+		userIDByEmail: utils.NewCopyOnWriteMap[string, int](func(email string) uint {
+			return uint(maphash.String(emailSeed, email))
+		}),
+		index: data.NewSearchIndex(),
+	}
+	testData := &testData{state: state, snapshots: map[string]savedState{}}
+
+	for _, user := range dataset.Users {
+		if password, ok := dataset.Passwords[user.ID]; ok {
+			hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+			if err != nil {
+				return nil, err
+			}
+			user.PasswordHash = string(hashedPass[:])
+		}
+		state.putUser(user)
+		if user.ID >= testData.nextUserID {
+			testData.nextUserID = user.ID + 1
+		}
+	}
+
+	for _, album := range dataset.Albums {
+		state.putAlbum(album)
+		if album.ID >= testData.nextAlbumID {
+			testData.nextAlbumID = album.ID + 1
+		}
+	}
+
+	for _, photo := range dataset.Photos {
+		state.putPhoto(photo)
+		if photo.ID >= testData.nextPhotoID {
+			testData.nextPhotoID = photo.ID + 1
+		}
+	}
+
+	testData.initial = testData.save()
+
+	return testData, nil
 }
 
-func (testData *testData) GetUser(id int) data.User {
-	if user, ok := testData.users[id]; ok {
-		return user
+// Snapshot returns the current state, which is copied before the next write instead of being changed.
+func (testData *testData) Snapshot() data.IData {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	testData.shared.Store(true)
+	return testData.state
+}
+
+// writableState returns a state that writes may change. The caller must hold the write lock.
+func (testData *testData) writableState() *testDataState {
+	if testData.shared.Load() {
+		testData.state = testData.state.clone()
+		testData.shared.Store(false)
 	}
-	return data.User{}
+	return testData.state
 }
 
-func (testData *testData) GetAlbum(id int) data.Album {
-	if album, ok := testData.albums[id]; ok {
-		return album
+// SaveSnapshot saves the current state, which is copied before the next write instead of being changed.
+func (testData *testData) SaveSnapshot(name string) error {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	testData.snapshots[name] = testData.save()
+	return nil
+}
+
+func (testData *testData) RestoreSnapshot(name string) error {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	saved, ok := testData.snapshots[name]
+	if !ok {
+		return fmt.Errorf("snapshot %s %w", name, data.ErrNotFound)
 	}
-	return data.Album{}
+
+	testData.restore(saved)
+	return nil
 }
 
-func (testData *testData) GetPhoto(id int) data.Photo {
-	if photo, ok := testData.photos[id]; ok {
-		return photo
+func (testData *testData) DeleteSnapshot(name string) error {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	if _, ok := testData.snapshots[name]; !ok {
+		return fmt.Errorf("snapshot %s %w", name, data.ErrNotFound)
 	}
-	return data.Photo{}
+
+	delete(testData.snapshots, name)
+	return nil
+}
+
+func (testData *testData) Snapshots() []string {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	names := maps.Keys(testData.snapshots)
+	slices.Sort(names)
+	return names
+}
+
+func (testData *testData) Reset() error {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	testData.restore(testData.initial)
+	return nil
+}
+
+// save returns the current state, marking it as shared so that writes do not change it.
+// The caller must hold the write lock.
+func (testData *testData) save() savedState {
+	testData.shared.Store(true)
+	return savedState{testData.state, testData.nextUserID, testData.nextAlbumID, testData.nextPhotoID}
+}
+
+// restore makes the saved state the current state. It stays shared, so writes do not change the saved state.
+// The caller must hold the write lock.
+func (testData *testData) restore(saved savedState) {
+	testData.state = saved.state
+	testData.shared.Store(true)
+	testData.nextUserID = saved.nextUserID
+	testData.nextAlbumID = saved.nextAlbumID
+	testData.nextPhotoID = saved.nextPhotoID
+}
+
+func (testData *testData) GetUsers() ([]data.User, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetUsers()
+}
+
+func (testData *testData) GetAlbums() ([]data.Album, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetAlbums()
+}
+
+func (testData *testData) GetPhotos() ([]data.Photo, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetPhotos()
+}
+
+func (testData *testData) GetUser(id int) (data.User, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetUser(id)
+}
+
+func (testData *testData) GetAlbum(id int) (data.Album, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetAlbum(id)
+}
+
+func (testData *testData) GetPhoto(id int) (data.Photo, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetPhoto(id)
 }
 
 func (testData *testData) GetUserWithEmail(email string) (*data.User, error) {
-	return utils.Single(testData.GetUsers(), func(user data.User) bool {
-		return user.Email == email
-	})
-}
-
-func (testData *testData) GetAlbumsByUserID(userID int) []data.Album {
-	return utils.ValuesWhere(testData.albums, func(album data.Album) bool {
-		return album.UserID == userID
-	})
-}
-
-func (testData *testData) GetPhotosByAlbumID(albumID int) []data.Photo {
-	return utils.ValuesWhere(testData.photos, func(photo data.Photo) bool {
-		return photo.AlbumID == albumID
-	})
-}
-
-func getUserData() map[int]data.User {
-	users := make(map[int]data.User, 0)
-	for i := 0; i < numberOfUsers; i++ {
-		iString := fmt.Sprint(i)
-		hashedPass, _ := bcrypt.GenerateFromPassword([]byte("Password"+iString), 10)
-		users[i] = data.User{
-			ID:           i,
-			Name:         "User " + iString,
-			Username:     "User" + iString,
-			Email:        "User" + iString + "@email.co.uk",
-			PasswordHash: string(hashedPass[:]),
-		}
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetUserWithEmail(email)
+}
+
+func (testData *testData) GetAlbumsByUserID(userID int) ([]data.Album, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetAlbumsByUserID(userID)
+}
+
+func (testData *testData) GetPhotosByAlbumID(albumID int) ([]data.Photo, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetPhotosByAlbumID(albumID)
+}
+
+func (testData *testData) GetAlbumsByUserIDs(userIDs []int) (map[int][]data.Album, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetAlbumsByUserIDs(userIDs)
+}
+
+func (testData *testData) GetPhotosByAlbumIDs(albumIDs []int) (map[int][]data.Photo, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.GetPhotosByAlbumIDs(albumIDs)
+}
+
+func (testData *testData) Search(text string, types []data.SearchType) ([]data.SearchResult, error) {
+	testData.mu.RLock()
+	defer testData.mu.RUnlock()
+
+	return testData.state.Search(text, types)
+}
+
+func (testData *testData) CreateUser(user data.User) (data.User, error) {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	if _, ok := testData.state.userIDByEmail.Get(user.Email); ok {
+		return data.User{}, data.ErrEmailTaken
+	}
+
+	user.ID = testData.nextUserID
+	testData.nextUserID++
+	testData.writableState().putUser(user)
+
+	return user, nil
+}
+
+func (testData *testData) UpdateUser(user data.User) (data.User, error) {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	if _, ok := testData.state.users.Get(user.ID); !ok {
+		return data.User{}, fmt.Errorf("user %d %w", user.ID, data.ErrNotFound)
 	}
+	if id, ok := testData.state.userIDByEmail.Get(user.Email); ok && id != user.ID {
+		return data.User{}, data.ErrEmailTaken
+	}
+
+	testData.writableState().putUser(user)
 
-	return users
+	return user, nil
 }
 
-func getAlbums(userIDs []int) map[int]data.Album {
-	albums := make(map[int]data.Album, len(userIDs)*numberOfUserAlbums)
+// DeleteUser deletes the user along with their albums and photos.
+func (testData *testData) DeleteUser(id int) (data.User, error) {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	user, ok := testData.state.users.Get(id)
+	if !ok {
+		return data.User{}, fmt.Errorf("user %d %w", id, data.ErrNotFound)
+	}
+
+	testData.writableState().removeUser(id)
+
+	return user, nil
+}
+
+func (testData *testData) CreateAlbum(album data.Album) (data.Album, error) {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	if _, ok := testData.state.users.Get(album.UserID); !ok {
+		return data.Album{}, fmt.Errorf("user %d %w", album.UserID, data.ErrNotFound)
+	}
+
+	album.ID = testData.nextAlbumID
+	testData.nextAlbumID++
+	testData.writableState().putAlbum(album)
 
+	return album, nil
+}
+
+func (testData *testData) UpdateAlbum(album data.Album) (data.Album, error) {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	if _, ok := testData.state.albums.Get(album.ID); !ok {
+		return data.Album{}, fmt.Errorf("album %d %w", album.ID, data.ErrNotFound)
+	}
+	if _, ok := testData.state.users.Get(album.UserID); !ok {
+		return data.Album{}, fmt.Errorf("user %d %w", album.UserID, data.ErrNotFound)
+	}
+
+	testData.writableState().putAlbum(album)
+
+	return album, nil
+}
+
+// DeleteAlbum deletes the album along with its photos.
+func (testData *testData) DeleteAlbum(id int) (data.Album, error) {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	album, ok := testData.state.albums.Get(id)
+	if !ok {
+		return data.Album{}, fmt.Errorf("album %d %w", id, data.ErrNotFound)
+	}
+
+	testData.writableState().removeAlbum(id)
+
+	return album, nil
+}
+
+func (testData *testData) CreatePhoto(photo data.Photo) (data.Photo, error) {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	if _, ok := testData.state.albums.Get(photo.AlbumID); !ok {
+		return data.Photo{}, fmt.Errorf("album %d %w", photo.AlbumID, data.ErrNotFound)
+	}
+
+	photo.ID = testData.nextPhotoID
+	testData.nextPhotoID++
+	testData.writableState().putPhoto(photo)
+
+	return photo, nil
+}
+
+func (testData *testData) UpdatePhoto(photo data.Photo) (data.Photo, error) {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	if _, ok := testData.state.photos.Get(photo.ID); !ok {
+		return data.Photo{}, fmt.Errorf("photo %d %w", photo.ID, data.ErrNotFound)
+	}
+	if _, ok := testData.state.albums.Get(photo.AlbumID); !ok {
+		return data.Photo{}, fmt.Errorf("album %d %w", photo.AlbumID, data.ErrNotFound)
+	}
+
+	testData.writableState().putPhoto(photo)
+
+	return photo, nil
+}
+
+func (testData *testData) DeletePhoto(id int) (data.Photo, error) {
+	testData.mu.Lock()
+	defer testData.mu.Unlock()
+
+	photo, ok := testData.state.photos.Get(id)
+	if !ok {
+		return data.Photo{}, fmt.Errorf("photo %d %w", id, data.ErrNotFound)
+	}
+
+	testData.writableState().removePhoto(id)
+
+	return photo, nil
+}
+
+func (state *testDataState) GetUsers() ([]data.User, error) {
+	return valuesOf(state.users, state.userIDs.ids), nil
+}
+
+func (state *testDataState) GetAlbums() ([]data.Album, error) {
+	return valuesOf(state.albums, state.albumIDs.ids), nil
+}
+
+func (state *testDataState) GetPhotos() ([]data.Photo, error) {
+	return valuesOf(state.photos, state.photoIDs.ids), nil
+}
+
+func (state *testDataState) GetUser(id int) (data.User, error) {
+	if user, ok := state.users.Get(id); ok {
+		return user, nil
+	}
+	return data.User{}, fmt.Errorf("user %d %w", id, data.ErrNotFound)
+}
+
+func (state *testDataState) GetAlbum(id int) (data.Album, error) {
+	if album, ok := state.albums.Get(id); ok {
+		return album, nil
+	}
+	return data.Album{}, fmt.Errorf("album %d %w", id, data.ErrNotFound)
+}
+
+func (state *testDataState) GetPhoto(id int) (data.Photo, error) {
+	if photo, ok := state.photos.Get(id); ok {
+		return photo, nil
+	}
+	return data.Photo{}, fmt.Errorf("photo %d %w", id, data.ErrNotFound)
+}
+
+func (state *testDataState) GetUserWithEmail(email string) (*data.User, error) {
+	if id, ok := state.userIDByEmail.Get(email); ok {
+		user, _ := state.users.Get(id)
+		return &user, nil
+	}
+	return nil, fmt.Errorf("user with email %s %w", email, data.ErrNotFound)
+}
+
+func (state *testDataState) GetAlbumsByUserID(userID int) ([]data.Album, error) {
+	ids, _ := state.albumIDsByUserID.Get(userID)
+	return valuesOf(state.albums, ids), nil
+}
+
+func (state *testDataState) GetPhotosByAlbumID(albumID int) ([]data.Photo, error) {
+	ids, _ := state.photoIDsByAlbumID.Get(albumID)
+	return valuesOf(state.photos, ids), nil
+}
+
+func (state *testDataState) GetAlbumsByUserIDs(userIDs []int) (map[int][]data.Album, error) {
+	albums := make(map[int][]data.Album, len(userIDs))
 	for _, userID := range userIDs {
-		startIndex := userID * numberOfUserAlbums
-		endIndex := (userID + 1) * numberOfUserAlbums
-		for i := startIndex; i < endIndex; i++ {
-			iString := fmt.Sprint(i)
-			albums[i] = data.Album{
-				ID:          i,
-				UserID:      userID,
-				Description: "Album " + iString,
-			}
-		}
+		ids, _ := state.albumIDsByUserID.Get(userID)
+		albums[userID] = valuesOf(state.albums, ids)
+	}
+	return albums, nil
+}
+
+func (state *testDataState) GetPhotosByAlbumIDs(albumIDs []int) (map[int][]data.Photo, error) {
+	photos := make(map[int][]data.Photo, len(albumIDs))
+	for _, albumID := range albumIDs {
+		ids, _ := state.photoIDsByAlbumID.Get(albumID)
+		photos[albumID] = valuesOf(state.photos, ids)
+	}
+	return photos, nil
+}
+
+func (state *testDataState) Search(text string, types []data.SearchType) ([]data.SearchResult, error) {
+	return state.index.Search(text, types), nil
+}
+
+// clone returns a copy of the state, which can be changed without changing the original.
+func (state *testDataState) clone() *testDataState {
+	return &testDataState{
+		users:             state.users.Clone(),
+		albums:            state.albums.Clone(),
+		photos:            state.photos.Clone(),
+		userIDs:           sortedIDs{ids: state.userIDs.ids, shared: true},
+		albumIDs:          sortedIDs{ids: state.albumIDs.ids, shared: true},
+		photoIDs:          sortedIDs{ids: state.photoIDs.ids, shared: true},
+		albumIDsByUserID:  state.albumIDsByUserID.Clone(),
+		photoIDsByAlbumID: state.photoIDsByAlbumID.Clone(),
+		userIDByEmail:     state.userIDByEmail.Clone(),
+		index:             state.index.Clone(),
+	}
+}
+
+// putUser adds or replaces the user and updates the indexes.
+func (state *testDataState) putUser(user data.User) {
+	if existing, ok := state.users.Get(user.ID); ok {
+		state.userIDByEmail.Delete(existing.Email)
+	} else {
+		state.userIDs.insert(user.ID)
+	}
+
+	state.users.Set(user.ID, user)
+	state.userIDByEmail.Set(user.Email, user.ID)
+	state.index.IndexUser(user)
+}
+
+// putAlbum adds or replaces the album and updates the indexes.
+func (state *testDataState) putAlbum(album data.Album) {
+	if existing, ok := state.albums.Get(album.ID); ok {
+		removeFromIndex(state.albumIDsByUserID, existing.UserID, album.ID)
+	} else {
+		state.albumIDs.insert(album.ID)
+	}
+
+	state.albums.Set(album.ID, album)
+	addToIndex(state.albumIDsByUserID, album.UserID, album.ID)
+	state.index.IndexAlbum(album)
+}
+
+// putPhoto adds or replaces the photo and updates the indexes.
+func (state *testDataState) putPhoto(photo data.Photo) {
+	if existing, ok := state.photos.Get(photo.ID); ok {
+		removeFromIndex(state.photoIDsByAlbumID, existing.AlbumID, photo.ID)
+	} else {
+		state.photoIDs.insert(photo.ID)
 	}
 
-	return albums
+	state.photos.Set(photo.ID, photo)
+	addToIndex(state.photoIDsByAlbumID, photo.AlbumID, photo.ID)
+	state.index.IndexPhoto(photo)
 }
 
-func getPhotos(albumIDs []int) map[int]data.Photo {
-	photos := make(map[int]data.Photo, len(albumIDs)*numberOfAlbumPhotos)
+// removeUser removes the user along with their albums and photos, and updates the indexes.
+func (state *testDataState) removeUser(id int) {
+	user, _ := state.users.Get(id)
 
+	albumIDs, _ := state.albumIDsByUserID.Get(id)
 	for _, albumID := range albumIDs {
-		startIndex := albumID * numberOfAlbumPhotos
-		endIndex := (albumID + 1) * numberOfAlbumPhotos
-		for i := startIndex; i < endIndex; i++ {
-			iString := fmt.Sprint(i)
-			photos[i] = data.Photo{
-				ID:          i,
-				AlbumID:     albumID,
-				Description: "Photo " + iString,
-			}
-		}
+		state.removeAlbum(albumID)
+	}
+
+	state.users.Delete(id)
+	state.userIDs.remove(id)
+	state.userIDByEmail.Delete(user.Email)
+	state.index.RemoveUser(id)
+}
+
+// removeAlbum removes the album along with its photos, and updates the indexes.
+func (state *testDataState) removeAlbum(id int) {
+	album, _ := state.albums.Get(id)
+
+	photoIDs, _ := state.photoIDsByAlbumID.Get(id)
+	for _, photoID := range photoIDs {
+		state.removePhoto(photoID)
+	}
+
+	state.albums.Delete(id)
+	state.albumIDs.remove(id)
+	removeFromIndex(state.albumIDsByUserID, album.UserID, id)
+	state.index.RemoveAlbum(id)
+}
+
+// removePhoto removes the photo and updates the indexes.
+func (state *testDataState) removePhoto(id int) {
+	photo, _ := state.photos.Get(id)
+
+	state.photos.Delete(id)
+	state.photoIDs.remove(id)
+	removeFromIndex(state.photoIDsByAlbumID, photo.AlbumID, id)
+	state.index.RemovePhoto(id)
+}
+
+func (sorted *sortedIDs) insert(id int) {
+	if sorted.shared {
+		sorted.ids = slices.Clone(sorted.ids)
+		sorted.shared = false
+	}
+	sorted.ids = utils.InsertSorted(sorted.ids, id)
+}
+
+func (sorted *sortedIDs) remove(id int) {
+	if sorted.shared {
+		sorted.ids = slices.Clone(sorted.ids)
+		sorted.shared = false
+	}
+	sorted.ids = utils.RemoveSorted(sorted.ids, id)
+}
+
+// valuesOf returns the items with the IDs, in the order of the IDs.
+func valuesOf[T any](items *utils.CopyOnWriteMap[int, T], ids []int) []T {
+	values := make([]T, 0, len(ids))
+	for _, id := range ids {
+		value, _ := items.Get(id)
+		values = append(values, value)
 	}
+	return values
+}
+
+// addToIndex adds the ID to a copy of the sorted IDs of the key.
+func addToIndex(index *utils.CopyOnWriteMap[int, []int], key int, id int) {
+	ids, _ := index.Get(key)
+	index.Set(key, utils.InsertSorted(slices.Clone(ids), id))
+}
+
+// removeFromIndex removes the ID from a copy of the sorted IDs of the key, and the key once it has no IDs.
+func removeFromIndex(index *utils.CopyOnWriteMap[int, []int], key int, id int) {
+	ids, _ := index.Get(key)
+	if ids = utils.RemoveSorted(slices.Clone(ids), id); len(ids) > 0 {
+		index.Set(key, ids)
+	} else {
+		index.Delete(key)
+	}
+}
 
-	return photos
+func shardOfID(id int) uint {
+	return uint(id)
 }
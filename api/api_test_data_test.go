@@ -0,0 +1,114 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data/conformance"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = conformance.DescribeData("Test data", func(dataset data.Dataset) (data.IData, error) {
+	return newTestData(dataset, bcrypt.MinCost)
+})
+
+var _ = Describe("Test data", func() {
+	var testData *testData
+
+	BeforeEach(func() {
+		testData = must(newTestData(conformance.Dataset(), bcrypt.MinCost))
+	})
+
+	It("gives new items the next ID", func() {
+		Expect(must(testData.CreateUser(data.User{Email: "dave@example.com"})).ID).To(Equal(4))
+		Expect(must(testData.CreateAlbum(data.Album{UserID: 0})).ID).To(Equal(5))
+		Expect(must(testData.CreatePhoto(data.Photo{AlbumID: 0})).ID).To(Equal(6))
+	})
+
+	It("hashes the passwords of the dataset at the cost", func() {
+		testData, err := NewTestDataWithDataset(data.Dataset{
+			Users:     []data.User{{ID: 0, Email: "alice@example.com"}},
+			Passwords: map[int]string{0: "Password0"},
+		}, bcrypt.MinCost+1)
+		Expect(err).ToNot(HaveOccurred())
+
+		hash := []byte(must(testData.GetUser(0)).PasswordHash)
+		Expect(bcrypt.CompareHashAndPassword(hash, []byte("Password0"))).To(Succeed())
+		Expect(bcrypt.Cost(hash)).To(Equal(bcrypt.MinCost + 1))
+	})
+})
+
+// Benchmarks each method of the test data on a dataset of 1,000 users with 10 albums of 10 photos each.
+// newBenchmarkData generates the users, each with 10 albums of 10 photos, and returns them with their test data.
+// Passwords are not needed, and hashing them would dominate the setup.
+func newBenchmarkData(b *testing.B, users int) (data.Dataset, *testData) {
+	dataset, err := data.Generate(data.GeneratorConfig{
+		Seed:           1,
+		Users:          users,
+		AlbumsPerUser:  data.Range{Min: 10, Max: 10},
+		PhotosPerAlbum: data.Range{Min: 10, Max: 10},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	dataset.Passwords = nil
+
+	testData, err := newTestData(dataset, bcrypt.MinCost)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return dataset, testData
+}
+
+func BenchmarkTestData(b *testing.B) {
+	dataset, testData := newBenchmarkData(b, 1000)
+
+	email := dataset.Users[len(dataset.Users)/2].Email
+	ids := make([]int, 0, 100)
+	for i := 0; i < 100; i++ {
+		ids = append(ids, i*10)
+	}
+
+	benchmarks := []struct {
+		name string
+		run  func()
+	}{
+		{"GetUsers", func() { testData.GetUsers() }},
+		{"GetAlbums", func() { testData.GetAlbums() }},
+		{"GetPhotos", func() { testData.GetPhotos() }},
+		{"GetUser", func() { testData.GetUser(500) }},
+		{"GetAlbum", func() { testData.GetAlbum(5000) }},
+		{"GetPhoto", func() { testData.GetPhoto(50000) }},
+		{"GetUserWithEmail", func() { testData.GetUserWithEmail(email) }},
+		{"GetAlbumsByUserID", func() { testData.GetAlbumsByUserID(500) }},
+		{"GetPhotosByAlbumID", func() { testData.GetPhotosByAlbumID(5000) }},
+		{"GetAlbumsByUserIDs", func() { testData.GetAlbumsByUserIDs(ids) }},
+		{"GetPhotosByAlbumIDs", func() { testData.GetPhotosByAlbumIDs(ids) }},
+		{"Search", func() { testData.Search("harbour", nil) }},
+		{"Snapshot", func() { testData.Snapshot() }},
+		{"UpdatePhoto", func() { testData.UpdatePhoto(data.Photo{ID: 50000, AlbumID: 5000}) }},
+		{"UpdatePhoto after Snapshot", func() {
+			testData.Snapshot()
+			testData.UpdatePhoto(data.Photo{ID: 50000, AlbumID: 5000})
+		}},
+		{"SaveSnapshot", func() { testData.SaveSnapshot("benchmark") }},
+		{"RestoreSnapshot after UpdatePhoto", func() {
+			testData.UpdatePhoto(data.Photo{ID: 50000, AlbumID: 5000})
+			testData.RestoreSnapshot("benchmark")
+		}},
+		{"Reset after UpdatePhoto", func() {
+			testData.UpdatePhoto(data.Photo{ID: 50000, AlbumID: 5000})
+			testData.Reset()
+		}},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchmark.run()
+			}
+		})
+	}
+}
@@ -3,28 +3,42 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
 	"golang.org/x/exp/maps"
 )
 
 func convertFieldDefinitionToQueryString(item *graphql.FieldDefinition) string {
 	value := item.Name
 
-	if list, ok := item.Type.(*graphql.List); ok {
-		if obj, ok := list.OfType.(*graphql.Object); ok {
-			subFieldsMap := obj.Fields()
-			if len(subFieldsMap) > 0 {
-				field := maps.Values(subFieldsMap)[0]
-				value += fmt.Sprintf("{%s}", convertFieldDefinitionToQueryString(field))
-			}
+	if obj, ok := unwrapType(item.Type).(*graphql.Object); ok {
+		subFieldsMap := obj.Fields()
+		if len(subFieldsMap) > 0 {
+			field := maps.Values(subFieldsMap)[0]
+			value += fmt.Sprintf("{%s}", convertFieldDefinitionToQueryString(field))
 		}
 	}
 
 	return value
 }
 
+// Removes any List and NonNull wrappers from the type.
+func unwrapType(t graphql.Type) graphql.Type {
+	switch t := t.(type) {
+	case *graphql.List:
+		return unwrapType(t.OfType)
+	case *graphql.NonNull:
+		return unwrapType(t.OfType)
+	default:
+		return t
+	}
+}
+
 func getData[T any](r *graphql.Result, key string) T {
 	result := r.Data.(map[string]interface{})
 	return convertTo[T](result[key])
@@ -38,9 +52,41 @@ func convertTo[T any](in interface{}) T {
 	return out
 }
 
+// idsOf returns a function that converts a list into a []T via Json, and returns the IDs of its items.
+func idsOf[T any](id func(T) int) func(in interface{}) []int {
+	return func(in interface{}) []int {
+		return utils.Transform(convertTo[[]T](in), id)
+	}
+}
+
 func newInvalidAuthenticationProvider() IAuthenticationProvider {
 	return &authenticationProvider{
-		signingMethod: jwt.SigningMethodNone, //invalid
-		secretKey:     "A secret key",
+		signingMethod:   jwt.SigningMethodNone, //invalid
+		keys:            []signingKey{{id: "invalid", private: "A secret key", public: "A secret key"}},
+		issuer:          issuer,
+		lifetime:        time.Hour,
+		refreshLifetime: time.Hour,
+		revocations:     newRevocationStore(),
+		now:             time.Now,
+	}
+}
+
+// must returns the value, or panics if there is an error.
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
 	}
+	return value
+}
+
+// errorCode returns the code in the extensions of the error.
+func errorCode(err gqlerrors.FormattedError) apierrors.Code {
+	code, _ := err.Extensions["code"].(string)
+	return apierrors.Code(code)
+}
+
+// invalidFields returns the invalid arguments in the extensions of a validation error.
+func invalidFields(err gqlerrors.FormattedError) []fieldError {
+	fields, _ := err.Extensions["fields"].([]fieldError)
+	return fields
 }
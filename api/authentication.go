@@ -1,39 +1,527 @@
 package api
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
+const issuer = "GraphQLFakeDataAPI"
+
+// AuthenticationConfig describes the tokens issued by the authentication provider.
+type AuthenticationConfig struct {
+	Issuer   string
+	Audience string
+	Lifetime time.Duration
+	// RefreshLifetime is how long a refresh token can be exchanged for new tokens.
+	RefreshLifetime time.Duration
+
+	// SigningMethod is one of HS256, RS256 or ES256.
+	SigningMethod string
+	// HMACKey is the secret used by HS256. A random secret is used if it is empty.
+	HMACKey string
+	// PrivateKeyPath is the PEM encoded private key used by RS256 and ES256.
+	PrivateKeyPath string
+	// PublicKeyPath is the PEM encoded public key used by RS256 and ES256.
+	// The public key is derived from the private key if it is empty.
+	PublicKeyPath string
+	// VerificationKeyPaths are PEM encoded public keys of previous signing keys,
+	// which tokens are still accepted from while they are rotated out.
+	VerificationKeyPaths []string
+}
+
+func DefaultAuthenticationConfig() AuthenticationConfig {
+	return AuthenticationConfig{
+		Issuer:          issuer,
+		Lifetime:        time.Hour,
+		RefreshLifetime: 30 * 24 * time.Hour,
+		SigningMethod:   jwt.SigningMethodHS256.Alg(),
+	}
+}
+
+// signingKey is a key that tokens are signed or verified with, identified by the "kid" header.
+type signingKey struct {
+	id      string
+	private interface{}
+	public  interface{}
+}
+
+func newSigningKey(private interface{}, public interface{}) (signingKey, error) {
+	id, err := keyID(public)
+	return signingKey{id: id, private: private, public: public}, err
+}
+
 type authenticationProvider struct {
 	signingMethod jwt.SigningMethod
-	secretKey     string
+	keysMu        sync.RWMutex
+	// keys are the keys that tokens are accepted from. The last key signs new tokens.
+	keys            []signingKey
+	issuer          string
+	audience        string
+	lifetime        time.Duration
+	refreshLifetime time.Duration
+	revocations     *revocationStore
+	now             func() time.Time
 }
 
 func NewAuthenticationProvider() IAuthenticationProvider {
-	return &authenticationProvider{
-		signingMethod: jwt.SigningMethodHS256,
-		secretKey:     "This is very secret!",
+	auth, err := NewAuthenticationProviderWithConfig(DefaultAuthenticationConfig())
+	if err != nil {
+		panic(err)
+	}
+	return auth
+}
+
+func NewAuthenticationProviderWithConfig(config AuthenticationConfig) (IAuthenticationProvider, error) {
+	if config.Lifetime <= 0 {
+		return nil, errors.New("token lifetime must be positive")
+	}
+
+	if config.RefreshLifetime <= 0 {
+		return nil, errors.New("refresh token lifetime must be positive")
+	}
+
+	auth := &authenticationProvider{
+		issuer:          config.Issuer,
+		audience:        config.Audience,
+		lifetime:        config.Lifetime,
+		refreshLifetime: config.RefreshLifetime,
+		revocations:     newRevocationStore(),
+		now:             time.Now,
+	}
+
+	var private, public interface{}
+	var verificationKeys []interface{}
+	var err error
+	switch config.SigningMethod {
+	case jwt.SigningMethodHS256.Alg():
+		auth.signingMethod = jwt.SigningMethodHS256
+		if len(config.VerificationKeyPaths) > 0 {
+			return nil, errors.New("verification keys require RS256 or ES256")
+		}
+		private, err = hmacKey(config.HMACKey)
+		public = private
+	case jwt.SigningMethodRS256.Alg():
+		auth.signingMethod = jwt.SigningMethodRS256
+		private, public, err = loadKeyPair(config, jwt.ParseRSAPrivateKeyFromPEM, jwt.ParseRSAPublicKeyFromPEM)
+		if err == nil {
+			verificationKeys, err = loadPublicKeys(config.VerificationKeyPaths, jwt.ParseRSAPublicKeyFromPEM)
+		}
+	case jwt.SigningMethodES256.Alg():
+		auth.signingMethod = jwt.SigningMethodES256
+		private, public, err = loadKeyPair(config, parseP256PrivateKeyFromPEM, jwt.ParseECPublicKeyFromPEM)
+		if err == nil {
+			verificationKeys, err = loadPublicKeys(config.VerificationKeyPaths, jwt.ParseECPublicKeyFromPEM)
+		}
+	default:
+		err = fmt.Errorf("unsupported signing method %q", config.SigningMethod)
 	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, verificationKey := range verificationKeys {
+		key, err := newSigningKey(nil, verificationKey)
+		if err != nil {
+			return nil, err
+		}
+		auth.keys = append(auth.keys, key)
+	}
+
+	key, err := newSigningKey(private, public)
+	if err != nil {
+		return nil, err
+	}
+	auth.keys = append(auth.keys, key)
+
+	return auth, nil
+}
+
+// Identity is the authenticated caller.
+type Identity struct {
+	UserID int
+	// TokenID and ExpiresAt identify the access token the caller authenticated with.
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+// Tokens are the access and refresh tokens issued to a user.
+type Tokens struct {
+	UserID       int
+	AccessToken  string
+	RefreshToken string
+	// ExpiresAt is when the access token expires.
+	ExpiresAt time.Time
 }
 
 type IAuthenticationProvider interface {
 	GetToken(id int) (string, error)
+	// ValidateToken checks the signature, expiry, issuer, audience and revocation of the token
+	// and returns the identity it was issued to.
+	ValidateToken(token string) (Identity, error)
+	// IssueTokens returns a new access token and refresh token for the user.
+	IssueTokens(id int) (Tokens, error)
+	// RefreshTokens exchanges a refresh token for new tokens.
+	// Each refresh token can only be used once.
+	RefreshTokens(refreshToken string) (Tokens, error)
+	// RevokeRefreshToken stops the refresh token from being exchanged.
+	RevokeRefreshToken(refreshToken string) error
+	// RevokeToken rejects the access token the identity authenticated with until it expires.
+	RevokeToken(identity Identity)
+	// Issuer is the "iss" claim of the issued tokens.
+	Issuer() string
+	// KeySet returns the public keys that tokens are accepted from.
+	// It is empty for HS256, as the secret cannot be published.
+	KeySet() JSONWebKeySet
+	// RotateKey generates a new key to sign tokens with and returns its key ID.
+	// Tokens signed with the previous keys are accepted until the keys are retired.
+	RotateKey() (string, error)
+	// RetireKey stops accepting tokens signed with the key.
+	RetireKey(id string) error
 }
 
 func (auth *authenticationProvider) GetToken(id int) (string, error) {
-	claims := jwt.NewWithClaims(auth.signingMethod, jwt.RegisteredClaims{
-		Issuer:    strconv.Itoa(id),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
-	})
+	token, _, err := auth.signToken(id)
+	return token, err
+}
+
+func (auth *authenticationProvider) IssueTokens(id int) (Tokens, error) {
+	token, expiresAt, err := auth.signToken(id)
+
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	refreshToken, err := randomHex(32)
 
-	token, err := claims.SignedString([]byte(auth.secretKey))
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	now := auth.now()
+	auth.revocations.addRefreshToken(refreshToken, id, now.Add(auth.refreshLifetime), now)
+
+	return Tokens{
+		UserID:       id,
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+func (auth *authenticationProvider) RefreshTokens(refreshToken string) (Tokens, error) {
+	id, err := auth.revocations.useRefreshToken(refreshToken, auth.now())
+
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return auth.IssueTokens(id)
+}
+
+func (auth *authenticationProvider) RevokeRefreshToken(refreshToken string) error {
+	_, err := auth.revocations.useRefreshToken(refreshToken, auth.now())
+	return err
+}
+
+func (auth *authenticationProvider) RevokeToken(identity Identity) {
+	if identity.TokenID == "" {
+		return
+	}
+	auth.revocations.revoke(identity.TokenID, identity.ExpiresAt, auth.now())
+}
+
+func (auth *authenticationProvider) Issuer() string {
+	return auth.issuer
+}
+
+func (auth *authenticationProvider) KeySet() JSONWebKeySet {
+	auth.keysMu.RLock()
+	defer auth.keysMu.RUnlock()
+
+	keySet := JSONWebKeySet{Keys: []JSONWebKey{}}
+
+	for _, key := range auth.keys {
+		jwk, err := newJSONWebKey(key.public, auth.signingMethod.Alg())
+		if err != nil {
+			// HMAC secrets are not published.
+			continue
+		}
+		jwk.KeyID = key.id
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+
+	return keySet
+}
 
+func (auth *authenticationProvider) RotateKey() (string, error) {
+	private, public, err := auth.generateKey()
 	if err != nil {
 		return "", err
 	}
 
-	return token, err
+	key, err := newSigningKey(private, public)
+	if err != nil {
+		return "", err
+	}
+
+	auth.keysMu.Lock()
+	defer auth.keysMu.Unlock()
+
+	auth.keys = append(auth.keys, key)
+	return key.id, nil
+}
+
+func (auth *authenticationProvider) RetireKey(id string) error {
+	auth.keysMu.Lock()
+	defer auth.keysMu.Unlock()
+
+	for i, key := range auth.keys {
+		if key.id != id {
+			continue
+		}
+		if i == len(auth.keys)-1 {
+			return errors.New("cannot retire the current signing key")
+		}
+		auth.keys = append(auth.keys[:i], auth.keys[i+1:]...)
+		return nil
+	}
+
+	return fmt.Errorf("key %q not found", id)
+}
+
+func (auth *authenticationProvider) currentKey() signingKey {
+	auth.keysMu.RLock()
+	defer auth.keysMu.RUnlock()
+
+	return auth.keys[len(auth.keys)-1]
+}
+
+func (auth *authenticationProvider) findKey(id string) (signingKey, bool) {
+	auth.keysMu.RLock()
+	defer auth.keysMu.RUnlock()
+
+	for _, key := range auth.keys {
+		if key.id == id {
+			return key, true
+		}
+	}
+	return signingKey{}, false
+}
+
+// generateKey returns a new key pair for the signing method.
+func (auth *authenticationProvider) generateKey() (interface{}, interface{}, error) {
+	switch auth.signingMethod {
+	case jwt.SigningMethodHS256:
+		key, err := hmacKey("")
+		return key, key, err
+	case jwt.SigningMethodRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, &key.PublicKey, nil
+	case jwt.SigningMethodES256:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, &key.PublicKey, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported signing method %q", auth.signingMethod.Alg())
+	}
+}
+
+// signToken returns a new access token for the user and when it expires.
+func (auth *authenticationProvider) signToken(id int) (string, time.Time, error) {
+	now := auth.now()
+	expiresAt := now.Add(auth.lifetime)
+
+	tokenID, err := randomHex(16)
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    auth.issuer,
+		Subject:   strconv.Itoa(id),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		NotBefore: jwt.NewNumericDate(now),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ID:        tokenID,
+	}
+
+	if auth.audience != "" {
+		claims.Audience = jwt.ClaimStrings{auth.audience}
+	}
+
+	key := auth.currentKey()
+	unsigned := jwt.NewWithClaims(auth.signingMethod, claims)
+	unsigned.Header["kid"] = key.id
+
+	token, err := unsigned.SignedString(key.private)
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// Tokens are only precise to the second.
+	return token, claims.ExpiresAt.Time, nil
+}
+
+func (auth *authenticationProvider) ValidateToken(token string) (Identity, error) {
+	claims := &jwt.RegisteredClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{auth.signingMethod.Alg()}), jwt.WithoutClaimsValidation())
+
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		id, _ := t.Header["kid"].(string)
+		key, ok := auth.findKey(id)
+		if !ok {
+			return nil, errors.New("unknown key ID")
+		}
+		return key.public, nil
+	})
+
+	if err != nil {
+		return Identity{}, err
+	}
+
+	now := auth.now()
+
+	if !claims.VerifyExpiresAt(now, true) {
+		return Identity{}, errors.New("token is expired")
+	}
+
+	if !claims.VerifyNotBefore(now, false) {
+		return Identity{}, errors.New("token is not valid yet")
+	}
+
+	if !claims.VerifyIssuer(auth.issuer, true) {
+		return Identity{}, errors.New("invalid issuer")
+	}
+
+	if auth.audience != "" && !claims.VerifyAudience(auth.audience, true) {
+		return Identity{}, errors.New("invalid audience")
+	}
+
+	if claims.ID != "" && auth.revocations.isRevoked(claims.ID) {
+		return Identity{}, errors.New("token has been revoked")
+	}
+
+	id, err := strconv.Atoi(claims.Subject)
+
+	if err != nil {
+		return Identity{}, errors.New("invalid subject")
+	}
+
+	return Identity{UserID: id, TokenID: claims.ID, ExpiresAt: claims.ExpiresAt.Time}, nil
+}
+
+func hmacKey(key string) ([]byte, error) {
+	if key != "" {
+		return []byte(key), nil
+	}
+
+	random := make([]byte, 32)
+	_, err := rand.Read(random)
+	return random, err
+}
+
+type publicKey interface {
+	Equal(x crypto.PublicKey) bool
+}
+
+// loadKeyPair reads the private key, and the public key if present, from the PEM files in the config.
+func loadKeyPair[Private interface{ Public() crypto.PublicKey }, Public publicKey](
+	config AuthenticationConfig,
+	parsePrivate func([]byte) (Private, error),
+	parsePublic func([]byte) (Public, error),
+) (interface{}, interface{}, error) {
+	if config.PrivateKeyPath == "" {
+		return nil, nil, fmt.Errorf("%s requires a private key", config.SigningMethod)
+	}
+
+	bytes, err := os.ReadFile(config.PrivateKeyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	private, err := parsePrivate(bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", config.PrivateKeyPath, err)
+	}
+
+	if config.PublicKeyPath == "" {
+		return private, private.Public(), nil
+	}
+
+	bytes, err = os.ReadFile(config.PublicKeyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	public, err := parsePublic(bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", config.PublicKeyPath, err)
+	}
+
+	if !public.Equal(private.Public()) {
+		return nil, nil, errors.New("public key does not match private key")
+	}
+
+	return private, public, nil
+}
+
+// loadPublicKeys reads the PEM encoded public keys.
+func loadPublicKeys[Public any](paths []string, parse func([]byte) (Public, error)) ([]interface{}, error) {
+	keys := make([]interface{}, 0, len(paths))
+
+	for _, path := range paths {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parse(bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// parseP256PrivateKeyFromPEM parses an EC private key, which ES256 requires to use the P-256 curve.
+func parseP256PrivateKeyFromPEM(bytes []byte) (*ecdsa.PrivateKey, error) {
+	key, err := jwt.ParseECPrivateKeyFromPEM(bytes)
+	if err != nil {
+		return nil, err
+	}
+	if key.Curve != elliptic.P256() {
+		return nil, errors.New("ES256 requires a P-256 key")
+	}
+	return key, nil
+}
+
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
 }
@@ -0,0 +1,430 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// writePEM writes the block to a new file in the directory and returns its path.
+func writePEM(dir string, name string, blockType string, bytes []byte) string {
+	path := filepath.Join(dir, name)
+	Expect(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: bytes}), 0600)).To(Succeed())
+	return path
+}
+
+func writeRSAKeyPair(dir string) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).To(BeNil())
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	Expect(err).To(BeNil())
+	return writePEM(dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		writePEM(dir, "rsa.pub.pem", "PUBLIC KEY", public)
+}
+
+func writeECKeyPair(dir string, curve elliptic.Curve) (string, string) {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	Expect(err).To(BeNil())
+	private, err := x509.MarshalECPrivateKey(key)
+	Expect(err).To(BeNil())
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	Expect(err).To(BeNil())
+	return writePEM(dir, "ec.pem", "EC PRIVATE KEY", private),
+		writePEM(dir, "ec.pub.pem", "PUBLIC KEY", public)
+}
+
+var _ = Describe("Authentication provider", func() {
+	var auth *authenticationProvider
+
+	BeforeEach(func() {
+		auth = NewAuthenticationProvider().(*authenticationProvider)
+	})
+
+	signWithKeyID := func(method jwt.SigningMethod, claims jwt.RegisteredClaims, key interface{}, keyID string) string {
+		unsigned := jwt.NewWithClaims(method, claims)
+		if keyID != "" {
+			unsigned.Header["kid"] = keyID
+		}
+		token, err := unsigned.SignedString(key)
+		Expect(err).To(BeNil())
+		return token
+	}
+
+	sign := func(method jwt.SigningMethod, claims jwt.RegisteredClaims, key interface{}) string {
+		return signWithKeyID(method, claims, key, auth.currentKey().id)
+	}
+
+	validClaims := func() jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   "3",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}
+	}
+
+	parseClaims := func(token string) *jwt.RegisteredClaims {
+		claims := &jwt.RegisteredClaims{}
+		_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+		Expect(err).To(BeNil())
+		return claims
+	}
+
+	It("validates the tokens it issues", func() {
+		token, err := auth.GetToken(7)
+		Expect(err).To(BeNil())
+
+		identity, err := auth.ValidateToken(token)
+
+		Expect(err).To(BeNil())
+		Expect(identity.UserID).To(Equal(7))
+		Expect(identity.TokenID).To(Equal(parseClaims(token).ID))
+		Expect(identity.ExpiresAt).To(Equal(parseClaims(token).ExpiresAt.Time))
+	})
+
+	It("issues tokens with the registered claims", func() {
+		now := time.Now().Truncate(time.Second)
+		auth.now = func() time.Time { return now }
+
+		token, _ := auth.GetToken(7)
+		claims := parseClaims(token)
+
+		Expect(claims.Subject).To(Equal("7"))
+		Expect(claims.Issuer).To(Equal(issuer))
+		Expect(claims.IssuedAt.Time).To(Equal(now))
+		Expect(claims.NotBefore.Time).To(Equal(now))
+		Expect(claims.ExpiresAt.Time).To(Equal(now.Add(time.Hour)))
+		Expect(claims.ID).ToNot(BeEmpty())
+		Expect(claims.Audience).To(BeEmpty())
+	})
+
+	It("issues tokens with unique IDs", func() {
+		first, _ := auth.GetToken(7)
+		second, _ := auth.GetToken(7)
+
+		Expect(parseClaims(first).ID).ToNot(Equal(parseClaims(second).ID))
+	})
+
+	DescribeTable("rejects invalid tokens", func(token func() string) {
+		_, err := auth.ValidateToken(token())
+
+		Expect(err).ToNot(BeNil())
+	},
+		Entry("malformed", func() string {
+			return "not a token"
+		}),
+		Entry("expired", func() string {
+			claims := validClaims()
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			return sign(jwt.SigningMethodHS256, claims, auth.currentKey().private)
+		}),
+		Entry("missing expiry", func() string {
+			claims := validClaims()
+			claims.ExpiresAt = nil
+			return sign(jwt.SigningMethodHS256, claims, auth.currentKey().private)
+		}),
+		Entry("not valid yet", func() string {
+			claims := validClaims()
+			claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute))
+			return sign(jwt.SigningMethodHS256, claims, auth.currentKey().private)
+		}),
+		Entry("wrong issuer", func() string {
+			claims := validClaims()
+			claims.Issuer = "someone else"
+			return sign(jwt.SigningMethodHS256, claims, auth.currentKey().private)
+		}),
+		Entry("wrong key", func() string {
+			return sign(jwt.SigningMethodHS256, validClaims(), []byte("not the secret"))
+		}),
+		Entry("wrong signing method", func() string {
+			return sign(jwt.SigningMethodHS512, validClaims(), auth.currentKey().private)
+		}),
+		Entry("unsigned", func() string {
+			return sign(jwt.SigningMethodNone, validClaims(), jwt.UnsafeAllowNoneSignatureType)
+		}),
+		Entry("missing key ID", func() string {
+			return signWithKeyID(jwt.SigningMethodHS256, validClaims(), auth.currentKey().private, "")
+		}),
+		Entry("unknown key ID", func() string {
+			return signWithKeyID(jwt.SigningMethodHS256, validClaims(), auth.currentKey().private, "unknown")
+		}),
+		Entry("non-numeric subject", func() string {
+			claims := validClaims()
+			claims.Subject = "admin"
+			return sign(jwt.SigningMethodHS256, claims, auth.currentKey().private)
+		}),
+	)
+
+	Context("with a configured lifetime", func() {
+		var now time.Time
+		var token string
+
+		BeforeEach(func() {
+			config := DefaultAuthenticationConfig()
+			config.Lifetime = 5 * time.Minute
+			provider, err := NewAuthenticationProviderWithConfig(config)
+			Expect(err).To(BeNil())
+
+			auth = provider.(*authenticationProvider)
+			now = time.Now()
+			auth.now = func() time.Time { return now }
+			token, _ = auth.GetToken(7)
+		})
+
+		It("validates the token before it expires", func() {
+			now = now.Add(4 * time.Minute)
+
+			_, err := auth.ValidateToken(token)
+
+			Expect(err).To(BeNil())
+		})
+
+		It("rejects the token after it expires", func() {
+			now = now.Add(6 * time.Minute)
+
+			_, err := auth.ValidateToken(token)
+
+			Expect(err).To(MatchError("token is expired"))
+		})
+	})
+
+	Context("refresh tokens", func() {
+		var now time.Time
+		var tokens Tokens
+
+		BeforeEach(func() {
+			now = time.Now()
+			auth.now = func() time.Time { return now }
+
+			var err error
+			tokens, err = auth.IssueTokens(7)
+			Expect(err).To(BeNil())
+		})
+
+		It("issues an access token and a refresh token", func() {
+			Expect(tokens.UserID).To(Equal(7))
+			Expect(tokens.RefreshToken).ToNot(BeEmpty())
+			Expect(tokens.ExpiresAt).To(Equal(parseClaims(tokens.AccessToken).ExpiresAt.Time))
+
+			identity, err := auth.ValidateToken(tokens.AccessToken)
+			Expect(err).To(BeNil())
+			Expect(identity.UserID).To(Equal(7))
+		})
+
+		It("exchanges a refresh token for new tokens", func() {
+			refreshed, err := auth.RefreshTokens(tokens.RefreshToken)
+
+			Expect(err).To(BeNil())
+			Expect(refreshed.UserID).To(Equal(7))
+			Expect(refreshed.AccessToken).ToNot(Equal(tokens.AccessToken))
+			Expect(refreshed.RefreshToken).ToNot(Equal(tokens.RefreshToken))
+
+			_, err = auth.ValidateToken(refreshed.AccessToken)
+			Expect(err).To(BeNil())
+		})
+
+		It("only exchanges a refresh token once", func() {
+			_, err := auth.RefreshTokens(tokens.RefreshToken)
+			Expect(err).To(BeNil())
+
+			_, err = auth.RefreshTokens(tokens.RefreshToken)
+			Expect(err).To(MatchError(errInvalidRefreshToken))
+		})
+
+		It("rejects an unknown refresh token", func() {
+			_, err := auth.RefreshTokens("not a refresh token")
+
+			Expect(err).To(MatchError(errInvalidRefreshToken))
+		})
+
+		It("rejects an expired refresh token", func() {
+			now = now.Add(31 * 24 * time.Hour)
+
+			_, err := auth.RefreshTokens(tokens.RefreshToken)
+
+			Expect(err).To(MatchError(errInvalidRefreshToken))
+		})
+
+		It("rejects a revoked refresh token", func() {
+			Expect(auth.RevokeRefreshToken(tokens.RefreshToken)).To(Succeed())
+
+			_, err := auth.RefreshTokens(tokens.RefreshToken)
+
+			Expect(err).To(MatchError(errInvalidRefreshToken))
+		})
+
+		It("rejects a revoked access token", func() {
+			other, _ := auth.IssueTokens(7)
+			identity, _ := auth.ValidateToken(tokens.AccessToken)
+
+			auth.RevokeToken(identity)
+
+			_, err := auth.ValidateToken(tokens.AccessToken)
+			Expect(err).To(MatchError("token has been revoked"))
+
+			_, err = auth.ValidateToken(other.AccessToken)
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("with a configured issuer and audience", func() {
+		var config AuthenticationConfig
+
+		BeforeEach(func() {
+			config = DefaultAuthenticationConfig()
+			config.Issuer = "https://auth.example.com"
+			config.Audience = "photos-api"
+			config.HMACKey = "shared secret"
+			provider, _ := NewAuthenticationProviderWithConfig(config)
+			auth = provider.(*authenticationProvider)
+		})
+
+		It("issues tokens for the issuer and audience", func() {
+			token, _ := auth.GetToken(7)
+			claims := parseClaims(token)
+
+			Expect(claims.Issuer).To(Equal("https://auth.example.com"))
+			Expect(claims.Audience).To(Equal(jwt.ClaimStrings{"photos-api"}))
+		})
+
+		It("rejects tokens for another audience", func() {
+			config.Audience = "another-api"
+			other, _ := NewAuthenticationProviderWithConfig(config)
+			token, _ := other.GetToken(7)
+
+			_, err := auth.ValidateToken(token)
+
+			Expect(err).To(MatchError("invalid audience"))
+		})
+
+		It("accepts tokens from a provider with the same key", func() {
+			other, _ := NewAuthenticationProviderWithConfig(config)
+			token, _ := other.GetToken(7)
+
+			_, err := auth.ValidateToken(token)
+
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("with a key pair", func() {
+		var dir string
+		var config AuthenticationConfig
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			config = DefaultAuthenticationConfig()
+		})
+
+		DescribeTable("issues tokens that validate", func(method string, writeKeyPair func() (string, string), withPublicKey bool) {
+			config.SigningMethod = method
+			config.PrivateKeyPath, config.PublicKeyPath = writeKeyPair()
+			if !withPublicKey {
+				config.PublicKeyPath = ""
+			}
+
+			provider, err := NewAuthenticationProviderWithConfig(config)
+			Expect(err).To(BeNil())
+
+			token, err := provider.GetToken(7)
+			Expect(err).To(BeNil())
+
+			identity, err := provider.ValidateToken(token)
+			Expect(err).To(BeNil())
+			Expect(identity.UserID).To(Equal(7))
+
+			header, _, _ := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+			Expect(header.Method.Alg()).To(Equal(method))
+		},
+			Entry("RS256", "RS256", func() (string, string) { return writeRSAKeyPair(dir) }, true),
+			Entry("RS256 without a public key", "RS256", func() (string, string) { return writeRSAKeyPair(dir) }, false),
+			Entry("ES256", "ES256", func() (string, string) { return writeECKeyPair(dir, elliptic.P256()) }, true),
+			Entry("ES256 without a public key", "ES256", func() (string, string) { return writeECKeyPair(dir, elliptic.P256()) }, false),
+		)
+
+		It("rejects a public key that does not match the private key", func() {
+			config.SigningMethod = "RS256"
+			config.PrivateKeyPath, _ = writeRSAKeyPair(dir)
+			_, config.PublicKeyPath = writeRSAKeyPair(GinkgoT().TempDir())
+
+			_, err := NewAuthenticationProviderWithConfig(config)
+
+			Expect(err).To(MatchError("public key does not match private key"))
+		})
+
+		It("rejects an ES256 key on another curve", func() {
+			config.SigningMethod = "ES256"
+			config.PrivateKeyPath, _ = writeECKeyPair(dir, elliptic.P384())
+
+			_, err := NewAuthenticationProviderWithConfig(config)
+
+			Expect(err).To(MatchError(ContainSubstring("ES256 requires a P-256 key")))
+		})
+
+		It("rejects a key of the wrong type", func() {
+			config.SigningMethod = "RS256"
+			config.PrivateKeyPath, _ = writeECKeyPair(dir, elliptic.P256())
+
+			_, err := NewAuthenticationProviderWithConfig(config)
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("requires a private key", func() {
+			config.SigningMethod = "ES256"
+
+			_, err := NewAuthenticationProviderWithConfig(config)
+
+			Expect(err).To(MatchError("ES256 requires a private key"))
+		})
+
+		It("accepts tokens from the verification keys", func() {
+			config.SigningMethod = "RS256"
+			oldPrivateKeyPath, oldPublicKeyPath := writeRSAKeyPair(GinkgoT().TempDir())
+			config.PrivateKeyPath = oldPrivateKeyPath
+			old, _ := NewAuthenticationProviderWithConfig(config)
+			token, _ := old.GetToken(7)
+
+			config.PrivateKeyPath, _ = writeRSAKeyPair(dir)
+			config.VerificationKeyPaths = []string{oldPublicKeyPath}
+			provider, err := NewAuthenticationProviderWithConfig(config)
+			Expect(err).To(BeNil())
+
+			_, err = provider.ValidateToken(token)
+			Expect(err).To(BeNil())
+			Expect(provider.KeySet().Keys).To(HaveLen(2))
+		})
+
+		It("reports a missing key file", func() {
+			config.SigningMethod = "RS256"
+			config.PrivateKeyPath = filepath.Join(dir, "missing.pem")
+
+			_, err := NewAuthenticationProviderWithConfig(config)
+
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+	})
+
+	DescribeTable("rejects invalid configs", func(modify func(config *AuthenticationConfig), expected string) {
+		config := DefaultAuthenticationConfig()
+		modify(&config)
+
+		_, err := NewAuthenticationProviderWithConfig(config)
+
+		Expect(err).To(MatchError(expected))
+	},
+		Entry("zero lifetime", func(config *AuthenticationConfig) { config.Lifetime = 0 }, "token lifetime must be positive"),
+		Entry("zero refresh lifetime", func(config *AuthenticationConfig) { config.RefreshLifetime = 0 }, "refresh token lifetime must be positive"),
+		Entry("HS256 verification keys", func(config *AuthenticationConfig) { config.VerificationKeyPaths = []string{"key.pem"} }, "verification keys require RS256 or ES256"),
+		Entry("unsupported signing method", func(config *AuthenticationConfig) { config.SigningMethod = "none" }, `unsupported signing method "none"`),
+	)
+})
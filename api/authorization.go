@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+)
+
+var errForbidden = apierrors.New(apierrors.Forbidden, "forbidden")
+
+// authorizationPolicy decides what the authenticated caller is allowed to see and change.
+// Administrators are allowed everything, other users only their own data.
+type authorizationPolicy struct {
+	dataModel data.IData
+}
+
+// of returns the policy for the field, which reads the data that the field reads: the snapshot of the request
+// for queries, so that a query is authorized against the data it sees, and the data model for mutations.
+func (policy authorizationPolicy) of(p graphql.ResolveParams) authorizationPolicy {
+	return authorizationPolicy{dataModel: dataFor(p, policy.dataModel)}
+}
+
+// viewer returns the authenticated caller, or errUnauthenticated.
+func (policy authorizationPolicy) viewer(ctx context.Context) (data.User, error) {
+	identity, ok := IdentityFromContext(ctx)
+	if !ok {
+		return data.User{}, errUnauthenticated
+	}
+
+	user, err := policy.dataModel.GetUser(identity.UserID)
+	if err != nil {
+		// The user has been deleted since the token was issued.
+		return data.User{}, errUnauthenticated
+	}
+
+	return user, nil
+}
+
+// authorizeAdmin checks that the caller is an administrator.
+func (policy authorizationPolicy) authorizeAdmin(ctx context.Context) error {
+	viewer, err := policy.viewer(ctx)
+	if err != nil {
+		return err
+	}
+	if viewer.Role != data.RoleAdmin {
+		return errForbidden
+	}
+	return nil
+}
+
+// authorizeUser checks that the caller is the user, or an administrator.
+func (policy authorizationPolicy) authorizeUser(ctx context.Context, userID int) error {
+	viewer, err := policy.viewer(ctx)
+	if err != nil {
+		return err
+	}
+	if viewer.ID != userID && viewer.Role != data.RoleAdmin {
+		return errForbidden
+	}
+	return nil
+}
+
+// canAccess returns whether the caller may see and change the data of a user,
+// looking the caller up only once.
+func (policy authorizationPolicy) canAccess(ctx context.Context) func(userID int) bool {
+	viewer, err := policy.viewer(ctx)
+	return func(userID int) bool {
+		return err == nil && (viewer.ID == userID || viewer.Role == data.RoleAdmin)
+	}
+}
+
+// authorizeAlbum checks that the album exists, and that the caller owns it or is an administrator.
+func (policy authorizationPolicy) authorizeAlbum(ctx context.Context, albumID int) error {
+	album, err := policy.dataModel.GetAlbum(albumID)
+	if err != nil {
+		return dataError(err)
+	}
+	return policy.authorizeUser(ctx, album.UserID)
+}
+
+// authorizePhoto checks that the photo exists, and that the caller owns its album or is an administrator.
+func (policy authorizationPolicy) authorizePhoto(ctx context.Context, photoID int) error {
+	photo, err := policy.dataModel.GetPhoto(photoID)
+	if err != nil {
+		return dataError(err)
+	}
+	return policy.authorizeAlbum(ctx, photo.AlbumID)
+}
+
+// private marks a User field as only visible to that user, or an administrator.
+func (policy authorizationPolicy) private(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return resolveTypeWithError(p, func(user data.User) (interface{}, error) {
+			if err := policy.of(p).authorizeUser(p.Context, user.ID); err != nil {
+				return nil, err
+			}
+			return resolve(p)
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/handler"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authorization", func() {
+	const (
+		admin     = 0
+		user      = 1
+		otherUser = 2
+	)
+
+	var testData data.IWritableData
+	var server http.Handler
+	var auth IAuthenticationProvider
+
+	BeforeEach(func() {
+		config := data.DefaultGeneratorConfig()
+		config.Users = 3
+		config.AlbumsPerUser = data.Range{Min: 2, Max: 2}
+		config.PhotosPerAlbum = data.Range{Min: 2, Max: 2}
+		testData, _ = NewTestDataWithConfig(config)
+
+		auth = NewAuthenticationProvider()
+		api := NewAPI(testData, auth)
+		server = AuthenticationMiddleware(auth, handler.New(&handler.Config{Schema: &api.Schema}))
+	})
+
+	// Sends the query over HTTP with a token for the user, or anonymously if the user is negative.
+	do := func(userID int, query string) *graphql.Result {
+		body, _ := json.Marshal(map[string]interface{}{"query": query})
+		r := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		if userID >= 0 {
+			token, err := auth.GetToken(userID)
+			Expect(err).To(BeNil())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+
+		result := &graphql.Result{}
+		Expect(json.Unmarshal(w.Body.Bytes(), result)).To(Succeed())
+		return result
+	}
+
+	Context("private user fields", func() {
+		It("a token for user 1 cannot read user 2's password hash", func() {
+			r := do(user, `{ user(id:2) { passwordHash } }`)
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Forbidden))
+			Expect(r.Errors[0].Path).To(Equal([]interface{}{"user", "passwordHash"}))
+		})
+
+		It("a token for user 1 cannot read user 2's email", func() {
+			r := do(user, `{ user(id:2) { email } }`)
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Forbidden))
+		})
+
+		It("a token for user 1 can read their own email and password hash", func() {
+			r := do(user, `{ user(id:1) { email passwordHash } }`)
+
+			Expect(r.Errors).To(BeEmpty())
+			Expect(getData[data.User](r, "user").PasswordHash).To(Equal(must(testData.GetUser(user)).PasswordHash))
+		})
+
+		It("an admin can read every user's email and password hash", func() {
+			r := do(admin, `{ users { email passwordHash } }`)
+
+			Expect(r.Errors).To(BeEmpty())
+			Expect(getData[[]data.User](r, "users")).To(HaveLen(3))
+		})
+
+		It("only hides the private fields of other users", func() {
+			r := do(user, `{ users { id name email } }`)
+
+			Expect(r.Errors).To(HaveLen(2))
+			users := getData[[]data.User](r, "users")
+			Expect(users[1].Email).To(Equal(must(testData.GetUser(user)).Email))
+			Expect(users[2].Name).To(Equal(must(testData.GetUser(otherUser)).Name))
+			Expect(users[2].Email).To(BeEmpty())
+		})
+
+		It("an anonymous caller is unauthenticated", func() {
+			r := do(-1, `{ user(id:2) { email } }`)
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
+		})
+
+		It("a token for a deleted user is unauthenticated", func() {
+			testData.DeleteUser(user)
+
+			r := do(user, `{ user(id:2) { email } }`)
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
+		})
+	})
+
+	Context("writes", func() {
+		DescribeTable("are forbidden for other users' data", func(mutation string) {
+			r := do(user, mutation)
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Forbidden))
+		},
+			Entry("createUser", `mutation { createUser(input:{name:"a", username:"a", email:"a", password:"a"}) { id } }`),
+			Entry("updateUser", `mutation { updateUser(id:2, input:{name:"a"}) { id } }`),
+			Entry("deleteUser", `mutation { deleteUser(id:2) { id } }`),
+			Entry("createAlbum", `mutation { createAlbum(input:{userid:2}) { id } }`),
+			Entry("updateAlbum", `mutation { updateAlbum(id:4, input:{description:"a"}) { id } }`),
+			Entry("updateAlbum to another user", `mutation { updateAlbum(id:2, input:{userid:2}) { id } }`),
+			Entry("deleteAlbum", `mutation { deleteAlbum(id:4) { id } }`),
+			Entry("createPhoto", `mutation { createPhoto(input:{albumid:4}) { id } }`),
+			Entry("updatePhoto", `mutation { updatePhoto(id:8, input:{description:"a"}) { id } }`),
+			Entry("updatePhoto to another album", `mutation { updatePhoto(id:4, input:{albumid:4}) { id } }`),
+			Entry("deletePhoto", `mutation { deletePhoto(id:8) { id } }`),
+			Entry("resetPassword", `mutation { resetPassword(id:1, newPassword:"Password2") }`),
+		)
+
+		DescribeTable("are allowed for the user's own data", func(mutation string) {
+			r := do(user, mutation)
+
+			Expect(r.Errors).To(BeEmpty())
+		},
+			Entry("updateUser", `mutation { updateUser(id:1, input:{name:"a"}) { id } }`),
+			Entry("createAlbum", `mutation { createAlbum(input:{userid:1}) { id } }`),
+			Entry("updateAlbum", `mutation { updateAlbum(id:2, input:{description:"a"}) { id } }`),
+			Entry("deleteAlbum", `mutation { deleteAlbum(id:2) { id } }`),
+			Entry("createPhoto", `mutation { createPhoto(input:{albumid:2}) { id } }`),
+			Entry("updatePhoto", `mutation { updatePhoto(id:4, input:{albumid:3}) { id } }`),
+			Entry("deletePhoto", `mutation { deletePhoto(id:4) { id } }`),
+			Entry("deleteUser", `mutation { deleteUser(id:1) { id } }`),
+		)
+
+		DescribeTable("are allowed for an admin", func(mutation string) {
+			r := do(admin, mutation)
+
+			Expect(r.Errors).To(BeEmpty())
+		},
+			Entry("createUser", `mutation { createUser(input:{name:"a", username:"a", email:"a@email.co.uk", password:"Password1"}) { id } }`),
+			Entry("updateUser", `mutation { updateUser(id:2, input:{name:"a"}) { id } }`),
+			Entry("updateAlbum to another user", `mutation { updateAlbum(id:2, input:{userid:2}) { id } }`),
+			Entry("deletePhoto", `mutation { deletePhoto(id:8) { id } }`),
+			Entry("deleteUser", `mutation { deleteUser(id:2) { id } }`),
+			Entry("resetPassword", `mutation { resetPassword(id:2, newPassword:"Password3") }`),
+		)
+
+		DescribeTable("report unknown albums and photos as not found", func(mutation string, message string) {
+			r := do(user, mutation)
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(r.Errors[0].Message).To(Equal(message))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
+		},
+			Entry("updateAlbum", `mutation { updateAlbum(id:99, input:{description:"a"}) { id } }`, "album 99 not found"),
+			Entry("deleteAlbum", `mutation { deleteAlbum(id:99) { id } }`, "album 99 not found"),
+			Entry("createPhoto", `mutation { createPhoto(input:{albumid:99}) { id } }`, "album 99 not found"),
+			Entry("updatePhoto", `mutation { updatePhoto(id:99, input:{description:"a"}) { id } }`, "photo 99 not found"),
+			Entry("deletePhoto", `mutation { deletePhoto(id:99) { id } }`, "photo 99 not found"),
+		)
+
+		It("creates users with the user role", func() {
+			r := do(admin, `mutation { createUser(input:{name:"a", username:"a", email:"a@email.co.uk", password:"Password1"}) { role } }`)
+
+			Expect(r.Errors).To(BeEmpty())
+			Expect(getData[data.User](r, "createUser").Role).To(Equal(data.RoleUser))
+		})
+	})
+})
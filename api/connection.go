@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+)
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "PageInfo",
+	Description: "Information about a page of a connection.",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Whether there are more items after this page.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolveType(p, func(pageInfo utils.PageInfo) interface{} {
+					return pageInfo.HasNextPage
+				})
+			},
+		},
+		"hasPreviousPage": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Whether there are more items before this page.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolveType(p, func(pageInfo utils.PageInfo) interface{} {
+					return pageInfo.HasPreviousPage
+				})
+			},
+		},
+		"startCursor": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The cursor of the first item in this page.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolveType(p, func(pageInfo utils.PageInfo) interface{} {
+					return nullIfEmpty(pageInfo.StartCursor)
+				})
+			},
+		},
+		"endCursor": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The cursor of the last item in this page.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolveType(p, func(pageInfo utils.PageInfo) interface{} {
+					return nullIfEmpty(pageInfo.EndCursor)
+				})
+			},
+		},
+	},
+})
+
+// newConnectionType returns the <name>Connection type for nodes of type T, along with its <name>Edge type.
+func newConnectionType[T any](name string, nodeType *graphql.Object) *graphql.Object {
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        name + "Edge",
+		Description: fmt.Sprintf("An edge in a %s connection.", nodeType.Name()),
+		Fields: graphql.Fields{
+			"node": &graphql.Field{
+				Type:        graphql.NewNonNull(nodeType),
+				Description: "The item at the end of the edge.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveType(p, func(edge utils.Edge[T]) interface{} {
+						return edge.Node
+					})
+				},
+			},
+			"cursor": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "A cursor for use in pagination.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveType(p, func(edge utils.Edge[T]) interface{} {
+						return edge.Cursor
+					})
+				},
+			},
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        name + "Connection",
+		Description: fmt.Sprintf("A page of %ss.", nodeType.Name()),
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type:        graphql.NewList(edgeType),
+				Description: "The edges in this page.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveType(p, func(connection utils.Connection[T]) interface{} {
+						return connection.Edges
+					})
+				},
+			},
+			"pageInfo": &graphql.Field{
+				Type:        graphql.NewNonNull(pageInfoType),
+				Description: "Information to aid in pagination.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveType(p, func(connection utils.Connection[T]) interface{} {
+						return connection.PageInfo
+					})
+				},
+			},
+			"totalCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "The total number of items in the connection.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveType(p, func(connection utils.Connection[T]) interface{} {
+						return connection.TotalCount
+					})
+				},
+			},
+		},
+	})
+}
+
+// connectionArgs returns the Relay pagination arguments, along with any extra arguments.
+func connectionArgs(extra graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{
+			Description: "return the first n items",
+			Type:        graphql.Int,
+		},
+		"after": &graphql.ArgumentConfig{
+			Description: "return items after the cursor",
+			Type:        graphql.String,
+		},
+		"last": &graphql.ArgumentConfig{
+			Description: "return the last n items",
+			Type:        graphql.Int,
+		},
+		"before": &graphql.ArgumentConfig{
+			Description: "return items before the cursor",
+			Type:        graphql.String,
+		},
+	}
+
+	for name, arg := range extra {
+		args[name] = arg
+	}
+
+	return args
+}
+
+// newConnection pages through the items, which are sorted by ID unless the orderBy argument sorted them otherwise.
+func newConnection[T any](items []T, args map[string]interface{}, cursors utils.Cursors[T]) (utils.Connection[T], error) {
+	if orderBy, ok := args["orderBy"].([]interface{}); ok && len(orderBy) > 0 {
+		return utils.NewOrderedConnection(items, args, cursors)
+	}
+	return utils.NewConnection(items, args, cursors)
+}
+
+// newCursors returns the cursors of items of the given type.
+func newCursors[T any](typeName string, id func(item T) int) utils.Cursors[T] {
+	return utils.Cursors[T]{
+		ID:     id,
+		Encode: func(id int) string { return cursorFor(typeName, id) },
+		Decode: func(cursor string) (int, bool) { return idOfCursor(typeName, cursor) },
+	}
+}
+
+// cursorFor returns an opaque cursor identifying the item of the given type.
+func cursorFor(typeName string, id int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", typeName, id)))
+}
+
+// idOfCursor returns the ID of the item that the cursor identifies, or false if it is not a cursor of the type.
+func idOfCursor(typeName string, cursor string) (int, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+
+	name, id, found := strings.Cut(string(decoded), ":")
+	if !found || name != typeName {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(id)
+	return n, err == nil
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type connectionResult[T any] struct {
+	Edges []struct {
+		Node   T
+		Cursor string
+	}
+	PageInfo   utils.PageInfo
+	TotalCount int
+}
+
+func (connection connectionResult[T]) nodes() []T {
+	nodes := make([]T, 0, len(connection.Edges))
+	for _, edge := range connection.Edges {
+		nodes = append(nodes, edge.Node)
+	}
+	return nodes
+}
+
+var _ = Describe("Connections", func() {
+	testData := NewTestData()
+
+	var variables map[string]interface{}
+	var params graphql.Params
+	var query string
+
+	BeforeEach(func() {
+		api := NewAPI(testData, NewAuthenticationProvider())
+		variables = make(map[string]interface{})
+		params = graphql.Params{
+			Schema:         api.Schema,
+			VariableValues: variables,
+		}
+	})
+
+	JustBeforeEach(func() {
+		params.RequestString = query
+	})
+
+	Context("usersConnection", func() {
+		BeforeEach(func() {
+			query = `
+				query ($first: Int, $after: String, $last: Int, $before: String) {
+					usersConnection(first:$first, after:$after, last:$last, before:$before) {
+						edges {
+							node {
+								id
+								name
+							}
+							cursor
+						}
+						pageInfo {
+							hasNextPage
+							hasPreviousPage
+							startCursor
+							endCursor
+						}
+						totalCount
+					}
+				}`
+		})
+
+		It("pages forwards through all users", func() {
+			users := make([]data.User, 0)
+			variables["first"] = 3
+
+			for {
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				connection := getData[connectionResult[data.User]](r, "usersConnection")
+				Expect(connection.TotalCount).To(Equal(len(must(testData.GetUsers()))))
+				users = append(users, connection.nodes()...)
+
+				if !connection.PageInfo.HasNextPage {
+					break
+				}
+				variables["after"] = connection.PageInfo.EndCursor
+			}
+
+			Expect(utils.Transform(users, func(user data.User) int { return user.ID })).
+				To(Equal(utils.Transform(must(testData.GetUsers()), func(user data.User) int { return user.ID })))
+		})
+
+		It("pages backwards from the end", func() {
+			variables["last"] = 2
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+
+			connection := getData[connectionResult[data.User]](r, "usersConnection")
+			users := must(testData.GetUsers())
+
+			Expect(connection.nodes()).To(HaveLen(2))
+			Expect(connection.nodes()[1].ID).To(Equal(users[len(users)-1].ID))
+			Expect(connection.PageInfo.HasPreviousPage).To(BeTrue())
+			Expect(connection.PageInfo.HasNextPage).To(BeFalse())
+
+			variables["before"] = connection.PageInfo.StartCursor
+
+			r = graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+
+			previous := getData[connectionResult[data.User]](r, "usersConnection")
+
+			Expect(previous.nodes()[1].ID).To(Equal(users[len(users)-3].ID))
+		})
+
+		It("returns null cursors for an empty page", func() {
+			variables["first"] = 0
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+
+			pageInfo := r.Data.(map[string]interface{})["usersConnection"].(map[string]interface{})["pageInfo"].(map[string]interface{})
+
+			Expect(pageInfo["startCursor"]).To(BeNil())
+			Expect(pageInfo["endCursor"]).To(BeNil())
+		})
+
+		It("continues after a cursor whose user has been deleted", func() {
+			testData := NewTestData()
+			params.Schema = NewAPI(testData, NewAuthenticationProvider()).Schema
+			variables["first"] = 2
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+			page := getData[connectionResult[data.User]](r, "usersConnection")
+
+			must(testData.DeleteUser(page.nodes()[1].ID))
+			variables["after"] = page.PageInfo.EndCursor
+
+			r = graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+			next := getData[connectionResult[data.User]](r, "usersConnection")
+
+			Expect(next.nodes()[0].ID).To(Equal(must(testData.GetUsers())[1].ID))
+			Expect(next.PageInfo.HasPreviousPage).To(BeTrue())
+		})
+
+		DescribeTable("rejects an invalid cursor",
+			func(cursor string) {
+				variables["after"] = cursor
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+			},
+			Entry("not a cursor", "not a cursor"),
+			Entry("a cursor of another type", cursorFor("Album", 0)),
+		)
+	})
+
+	Context("albumsConnection", func() {
+		BeforeEach(func() {
+			query = `
+				query ($userid: Int, $first: Int) {
+					albumsConnection(userid:$userid, first:$first) {
+						edges {
+							node {
+								id
+								userid
+								description
+							}
+						}
+						totalCount
+					}
+				}`
+		})
+
+		It("filters by userID", func() {
+			variables["userid"] = 1
+			variables["first"] = 2
+			expected := must(testData.GetAlbumsByUserID(1))
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+
+			connection := getData[connectionResult[data.Album]](r, "albumsConnection")
+
+			Expect(connection.TotalCount).To(Equal(len(expected)))
+			Expect(connection.nodes()).To(Equal(expected[:2]))
+		})
+	})
+
+	Context("photosConnection", func() {
+		BeforeEach(func() {
+			query = `
+				query ($albumid: Int, $first: Int) {
+					photosConnection(albumid:$albumid, first:$first) {
+						edges {
+							node {
+								id
+								albumid
+								description
+							}
+						}
+						totalCount
+					}
+				}`
+		})
+
+		It("filters by albumID", func() {
+			variables["albumid"] = 3
+			variables["first"] = 4
+			expected := must(testData.GetPhotosByAlbumID(3))
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+
+			connection := getData[connectionResult[data.Photo]](r, "photosConnection")
+
+			Expect(connection.TotalCount).To(Equal(len(expected)))
+			Expect(connection.nodes()).To(Equal(expected[:4]))
+		})
+	})
+
+	Context("nested connections", func() {
+		BeforeEach(func() {
+			query = `
+				query {
+					user(id:2) {
+						albumsConnection(first:1) {
+							edges {
+								node {
+									id
+									photosConnection(last:1) {
+										edges {
+											node {
+												id
+											}
+										}
+										pageInfo {
+											hasPreviousPage
+										}
+									}
+								}
+							}
+							pageInfo {
+								hasNextPage
+							}
+						}
+					}
+				}`
+		})
+
+		It("pages through the user's albums and the album's photos", func() {
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+
+			user := getData[struct {
+				AlbumsConnection connectionResult[struct {
+					ID               int
+					PhotosConnection connectionResult[data.Photo]
+				}]
+			}](r, "user")
+
+			albums := must(testData.GetAlbumsByUserID(2))
+			photos := must(testData.GetPhotosByAlbumID(albums[0].ID))
+
+			Expect(user.AlbumsConnection.PageInfo.HasNextPage).To(BeTrue())
+			Expect(user.AlbumsConnection.Edges).To(HaveLen(1))
+
+			album := user.AlbumsConnection.Edges[0].Node
+			Expect(album.ID).To(Equal(albums[0].ID))
+			Expect(album.PhotosConnection.PageInfo.HasPreviousPage).To(BeTrue())
+			Expect(album.PhotosConnection.nodes()[0].ID).To(Equal(photos[len(photos)-1].ID))
+		})
+	})
+})
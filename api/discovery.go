@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	JWKSPath                = "/.well-known/jwks.json"
+	OpenIDConfigurationPath = "/.well-known/openid-configuration"
+)
+
+// OpenIDConfiguration is a minimal OpenID Connect discovery document,
+// enough for other services to find the keys that tokens are signed with.
+type OpenIDConfiguration struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// JWKSHandler serves the public keys that tokens are accepted from.
+func JWKSHandler(auth IAuthenticationProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, auth.KeySet())
+	})
+}
+
+// OpenIDConfigurationHandler serves the discovery document of the issuer.
+// The JWKS is expected to be served at JWKSPath.
+func OpenIDConfigurationHandler(auth IAuthenticationProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		algorithms := []string{}
+		for _, key := range auth.KeySet().Keys {
+			if !slices.Contains(algorithms, key.Algorithm) {
+				algorithms = append(algorithms, key.Algorithm)
+			}
+		}
+
+		writeJSON(w, r, OpenIDConfiguration{
+			Issuer:                           auth.Issuer(),
+			JWKSURI:                          jwksURI(auth.Issuer(), r),
+			ResponseTypesSupported:           []string{"token"},
+			SubjectTypesSupported:            []string{"public"},
+			IDTokenSigningAlgValuesSupported: algorithms,
+		})
+	})
+}
+
+// jwksURI returns the URL of the JWKS, relative to the issuer if it is a URL,
+// otherwise relative to the host of the request.
+func jwksURI(issuer string, r *http.Request) string {
+	if u, err := url.Parse(issuer); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return strings.TrimSuffix(issuer, "/") + JWKSPath
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + JWKSPath
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, value interface{}) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"crypto/elliptic"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Discovery", func() {
+	var config AuthenticationConfig
+	var auth IAuthenticationProvider
+
+	BeforeEach(func() {
+		config = DefaultAuthenticationConfig()
+		config.SigningMethod = "ES256"
+		config.PrivateKeyPath, _ = writeECKeyPair(GinkgoT().TempDir(), elliptic.P256())
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		auth, err = NewAuthenticationProviderWithConfig(config)
+		Expect(err).To(BeNil())
+	})
+
+	get := func(handler http.Handler, path string, value interface{}) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://fake.example.com"+path, nil))
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(json.Unmarshal(w.Body.Bytes(), value)).To(Succeed())
+	}
+
+	fetchKeySet := func() JSONWebKeySet {
+		keySet := JSONWebKeySet{}
+		get(JWKSHandler(auth), JWKSPath, &keySet)
+		return keySet
+	}
+
+	// Verifies the token the way another service would, using only the published keys.
+	verify := func(keySet JSONWebKeySet, token string) error {
+		_, err := jwt.NewParser(jwt.WithValidMethods([]string{"ES256"})).Parse(token, func(t *jwt.Token) (interface{}, error) {
+			for _, jwk := range keySet.Keys {
+				if jwk.KeyID == t.Header["kid"] {
+					return publicKeyFromJWK(jwk), nil
+				}
+			}
+			return nil, errors.New("unknown key ID")
+		})
+		return err
+	}
+
+	It("publishes the signing key", func() {
+		keySet := fetchKeySet()
+
+		Expect(keySet.Keys).To(HaveLen(1))
+		Expect(keySet.Keys[0].KeyID).ToNot(BeEmpty())
+		Expect(keySet.Keys[0].Algorithm).To(Equal("ES256"))
+		Expect(keySet.Keys[0].Use).To(Equal("sig"))
+	})
+
+	It("publishes keys that verify the issued tokens", func() {
+		token, _ := auth.GetToken(7)
+
+		Expect(verify(fetchKeySet(), token)).To(Succeed())
+	})
+
+	It("does not publish HMAC secrets", func() {
+		config = DefaultAuthenticationConfig()
+		auth, _ = NewAuthenticationProviderWithConfig(config)
+
+		Expect(fetchKeySet().Keys).To(BeEmpty())
+	})
+
+	It("rejects other methods", func() {
+		w := httptest.NewRecorder()
+		JWKSHandler(auth).ServeHTTP(w, httptest.NewRequest(http.MethodPost, JWKSPath, nil))
+
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	Context("key rotation", func() {
+		var oldToken string
+		var oldKeyID string
+		var newKeyID string
+
+		JustBeforeEach(func() {
+			oldToken, _ = auth.GetToken(7)
+			oldKeyID = fetchKeySet().Keys[0].KeyID
+
+			var err error
+			newKeyID, err = auth.RotateKey()
+			Expect(err).To(BeNil())
+		})
+
+		It("publishes both keys", func() {
+			keySet := fetchKeySet()
+
+			Expect(keySet.Keys).To(HaveLen(2))
+			Expect(keySet.Keys[0].KeyID).To(Equal(oldKeyID))
+			Expect(keySet.Keys[1].KeyID).To(Equal(newKeyID))
+		})
+
+		It("signs new tokens with the new key", func() {
+			token, _ := auth.GetToken(7)
+			header, _, _ := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+
+			Expect(header.Header["kid"]).To(Equal(newKeyID))
+			Expect(verify(fetchKeySet(), token)).To(Succeed())
+		})
+
+		It("still accepts tokens signed with the old key", func() {
+			Expect(verify(fetchKeySet(), oldToken)).To(Succeed())
+
+			_, err := auth.ValidateToken(oldToken)
+			Expect(err).To(BeNil())
+		})
+
+		It("stops accepting tokens signed with a retired key", func() {
+			Expect(auth.RetireKey(oldKeyID)).To(Succeed())
+
+			Expect(fetchKeySet().Keys).To(HaveLen(1))
+			Expect(verify(fetchKeySet(), oldToken)).ToNot(Succeed())
+
+			_, err := auth.ValidateToken(oldToken)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("cannot retire the current signing key", func() {
+			Expect(auth.RetireKey(newKeyID)).To(MatchError("cannot retire the current signing key"))
+		})
+
+		It("cannot retire an unknown key", func() {
+			Expect(auth.RetireKey("unknown")).ToNot(Succeed())
+		})
+	})
+
+	Context("openid-configuration", func() {
+		fetchConfiguration := func() OpenIDConfiguration {
+			configuration := OpenIDConfiguration{}
+			get(OpenIDConfigurationHandler(auth), OpenIDConfigurationPath, &configuration)
+			return configuration
+		}
+
+		It("describes the issuer", func() {
+			configuration := fetchConfiguration()
+
+			Expect(configuration.Issuer).To(Equal(issuer))
+			Expect(configuration.JWKSURI).To(Equal("http://fake.example.com" + JWKSPath))
+			Expect(configuration.IDTokenSigningAlgValuesSupported).To(Equal([]string{"ES256"}))
+		})
+
+		When("the issuer is a URL", func() {
+			BeforeEach(func() {
+				config.Issuer = "https://auth.example.com/"
+			})
+
+			It("serves the JWKS relative to the issuer", func() {
+				configuration := fetchConfiguration()
+
+				Expect(configuration.Issuer).To(Equal("https://auth.example.com/"))
+				Expect(configuration.JWKSURI).To(Equal("https://auth.example.com" + JWKSPath))
+			})
+		})
+	})
+})
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// errInternal replaces the message of internal errors in production.
+var errInternal = apierrors.New(apierrors.Internal, "internal error")
+
+// dataError adds a code to the errors of the data model and of paging that the caller can act on,
+// and returns other errors unchanged.
+func dataError(err error) error {
+	switch {
+	case errors.Is(err, data.ErrNotFound):
+		return apierrors.Wrap(apierrors.NotFound, err)
+	case errors.Is(err, data.ErrEmailTaken),
+		errors.Is(err, utils.ErrNegative),
+		errors.Is(err, utils.ErrInvalidCursor),
+		errors.Is(err, utils.ErrCursorNotInList):
+		return apierrors.Wrap(apierrors.BadUserInput, err)
+	}
+	return err
+}
+
+// resolveItem resolves a field to the item, or to null if looking up or changing the item failed.
+func resolveItem[T any](item T, err error) (interface{}, error) {
+	if err != nil {
+		return nil, dataError(err)
+	}
+	return item, nil
+}
+
+// codeErrors makes every field of the schema return errors with a code, treating errors without one as internal.
+// In production, the message of internal errors is hidden from clients and logged instead.
+func codeErrors(schema graphql.Schema, production bool, logger *log.Logger) {
+	for name, t := range schema.TypeMap() {
+		object, ok := t.(*graphql.Object)
+		if !ok || strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		for _, field := range object.Fields() {
+			if field.Resolve != nil {
+				field.Resolve = withErrorCode(field.Resolve, production, logger)
+			}
+		}
+	}
+}
+
+func withErrorCode(resolve graphql.FieldResolveFn, production bool, logger *log.Logger) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		result, err := resolve(p)
+		if err != nil {
+			return nil, codeError(p, err, production, logger)
+		}
+
+		// Thunks are called once the fields at the same depth have been resolved, so their errors are coded
+		// then. graphql drops the code of their errors, but the message of internal errors is still hidden.
+		if thunk, ok := result.(func() (interface{}, error)); ok {
+			return func() (interface{}, error) {
+				result, err := thunk()
+				if err != nil {
+					return nil, codeError(p, err, production, logger)
+				}
+				return result, nil
+			}, nil
+		}
+
+		return result, nil
+	}
+}
+
+// codeError adds a code to the error of the field, or replaces it with errInternal if it is internal in production.
+func codeError(p graphql.ResolveParams, err error, production bool, logger *log.Logger) error {
+	code := apierrors.CodeOf(err)
+	if code == apierrors.Internal {
+		err = dataError(err)
+		code = apierrors.CodeOf(err)
+	}
+
+	if code == apierrors.Internal && production {
+		logger.Printf("%s.%s: %v", p.Info.ParentType.Name(), p.Info.FieldName, err)
+		return errInternal
+	}
+
+	if _, ok := err.(gqlerrors.ExtendedError); !ok {
+		err = apierrors.Wrap(code, err)
+	}
+
+	return err
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// failingData fails to read lists, as a database might.
+type failingData struct {
+	data.IWritableData
+}
+
+func (d failingData) GetUsers() ([]data.User, error) {
+	return nil, errors.New("database is locked")
+}
+
+func (d failingData) GetAlbumsByUserIDs(userIDs []int) (map[int][]data.Album, error) {
+	return nil, errors.New("database is locked")
+}
+
+var _ = Describe("Errors", func() {
+	Context("dataError", func() {
+		DescribeTable("adds a code to the errors of the data model", func(err error, code apierrors.Code) {
+			coded := dataError(err)
+
+			Expect(apierrors.CodeOf(coded)).To(Equal(code))
+			Expect(coded).To(MatchError(err))
+			Expect(coded).To(MatchError(err.Error()))
+		},
+			Entry("not found", fmt.Errorf("user 1 %w", data.ErrNotFound), apierrors.NotFound),
+			Entry("email taken", data.ErrEmailTaken, apierrors.BadUserInput),
+			Entry("negative paging argument", fmt.Errorf("limit %w", utils.ErrNegative), apierrors.BadUserInput),
+			Entry("invalid cursor", fmt.Errorf("%w %q", utils.ErrInvalidCursor, "nope"), apierrors.BadUserInput),
+			Entry("cursor not in the list", fmt.Errorf("cursor %q %w", "nope", utils.ErrCursorNotInList), apierrors.BadUserInput),
+		)
+
+		It("returns other errors unchanged", func() {
+			err := errors.New("failed")
+
+			Expect(dataError(err)).To(BeIdenticalTo(err))
+		})
+	})
+
+	Context("resolveItem", func() {
+		It("resolves to the item", func() {
+			item, err := resolveItem(data.Album{ID: 1}, nil)
+
+			Expect(err).To(BeNil())
+			Expect(item).To(Equal(data.Album{ID: 1}))
+		})
+
+		It("resolves to null if the item does not exist", func() {
+			item, err := resolveItem(data.Album{}, fmt.Errorf("album 1 %w", data.ErrNotFound))
+
+			Expect(item).To(BeNil())
+			Expect(apierrors.CodeOf(err)).To(Equal(apierrors.NotFound))
+		})
+	})
+
+	Context("resolvers", func() {
+		var output *bytes.Buffer
+		var config Config
+		var params graphql.Params
+
+		BeforeEach(func() {
+			output = &bytes.Buffer{}
+			config = DefaultConfig()
+			config.PasswordCost = bcrypt.MinCost
+			config.ErrorLog = log.New(output, "", 0)
+
+			params = graphql.Params{
+				RequestString: `mutation { login(email:"admin@example.com", password:"Password0") { token } }`,
+				Context:       context.Background(),
+			}
+		})
+
+		// Returns a store whose only user is an administrator with the email and password of the query.
+		newData := func() data.IWritableData {
+			testData, err := newTestData(data.Dataset{
+				Users:     []data.User{{ID: 0, Email: "admin@example.com", Role: data.RoleAdmin}},
+				Passwords: map[int]string{0: "Password0"},
+			}, bcrypt.MinCost)
+			Expect(err).To(BeNil())
+			return testData
+		}
+
+		doWith := func(dataModel data.IWritableData) *graphql.Result {
+			api, err := NewAPIWithConfig(dataModel, newInvalidAuthenticationProvider(), config)
+			Expect(err).To(BeNil())
+
+			params.Schema = api.Schema
+			return graphql.Do(params)
+		}
+
+		do := func() *graphql.Result {
+			return doWith(newData())
+		}
+
+		It("return internal errors with the code and message", func() {
+			r := do()
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Internal))
+			Expect(r.Errors[0].Message).To(HavePrefix("login failed: "))
+			Expect(output.String()).To(BeEmpty())
+		})
+
+		It("mask and log internal errors in production", func() {
+			config.Production = true
+
+			r := do()
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Internal))
+			Expect(r.Errors[0].Message).To(Equal("internal error"))
+			Expect(output.String()).To(HavePrefix("Mutation.login: login failed: "))
+		})
+
+		It("do not mask other errors in production", func() {
+			config.Production = true
+			params.RequestString = `mutation { login(email:"admin@example.com", password:"wrong") { token } }`
+
+			r := do()
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
+			Expect(r.Errors[0].Message).To(Equal("invalid email or password"))
+			Expect(output.String()).To(BeEmpty())
+		})
+
+		It("return internal errors of the data with the code and message", func() {
+			params.RequestString = `{ users { id } }`
+
+			r := doWith(failingData{newData()})
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Internal))
+			Expect(r.Errors[0].Message).To(Equal("database is locked"))
+		})
+
+		It("mask and log internal errors of batched lists in production", func() {
+			config.Production = true
+			params.RequestString = `{ user(id:0) { albums { id } } }`
+
+			r := doWith(failingData{newData()})
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(r.Errors[0].Message).To(Equal("internal error"))
+			Expect(output.String()).To(HavePrefix("User.albums: database is locked"))
+		})
+
+		It("return a code for every error of a field", func() {
+			params.RequestString = `{ users(limit:-1) { id } }`
+
+			r := do()
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+		})
+
+		It("do not mask invalid paging in production", func() {
+			config.Production = true
+			params.RequestString = `{ users(limit:-1) { id } }`
+
+			r := do()
+
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+			Expect(r.Errors[0].Message).To(Equal("limit must not be negative"))
+			Expect(output.String()).To(BeEmpty())
+		})
+	})
+})
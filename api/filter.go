@@ -0,0 +1,204 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+	"golang.org/x/exp/constraints"
+)
+
+var intFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "IntFilter",
+	Description: "Conditions on an Int field. Every given condition must match.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"eq": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Int,
+			Description: "Equal to the value.",
+		},
+		"in": &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewList(graphql.NewNonNull(graphql.Int)),
+			Description: "Equal to one of the values.",
+		},
+		"gt": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Int,
+			Description: "Greater than the value.",
+		},
+		"gte": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Int,
+			Description: "Greater than or equal to the value.",
+		},
+		"lt": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Int,
+			Description: "Less than the value.",
+		},
+		"lte": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Int,
+			Description: "Less than or equal to the value.",
+		},
+	},
+})
+
+var stringFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "StringFilter",
+	Description: "Conditions on a String field. Every given condition must match. Comparisons are case sensitive.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"eq": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "Equal to the value.",
+		},
+		"in": &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+			Description: "Equal to one of the values.",
+		},
+		"contains": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "Contains the value.",
+		},
+		"startsWith": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "Starts with the value.",
+		},
+	},
+})
+
+// newEnumFilterType returns the conditions on a field of the enum.
+func newEnumFilterType(enum *graphql.Enum) *graphql.InputObject {
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        enum.Name() + "Filter",
+		Description: "Conditions on a " + enum.Name() + " field. Every given condition must match.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"eq": &graphql.InputObjectFieldConfig{
+				Type:        enum,
+				Description: "Equal to the value.",
+			},
+			"in": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.NewNonNull(enum)),
+				Description: "Equal to one of the values.",
+			},
+		},
+	})
+}
+
+// newWhereType returns the where input of a type, with the filters on its fields
+// and the AND, OR and NOT combinators.
+func newWhereType(name string, description string, fields graphql.InputObjectConfigFieldMap) *graphql.InputObject {
+	var where *graphql.InputObject
+
+	where = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        name,
+		Description: description + " Every given field must match.",
+		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			fields["AND"] = &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.NewNonNull(where)),
+				Description: "Matches if every filter matches.",
+			}
+			fields["OR"] = &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.NewNonNull(where)),
+				Description: "Matches if any filter matches.",
+			}
+			fields["NOT"] = &graphql.InputObjectFieldConfig{
+				Type:        where,
+				Description: "Matches if the filter does not match.",
+			}
+			return fields
+		}),
+	})
+
+	return where
+}
+
+// whereArg returns the where argument for a list of items.
+func whereArg(items string, where *graphql.InputObject) *graphql.ArgumentConfig {
+	return &graphql.ArgumentConfig{
+		Description: "filter the " + items,
+		Type:        where,
+	}
+}
+
+// filterField returns the condition described by the filter on a field.
+type filterField[T any] func(filter map[string]interface{}) func(item T) bool
+
+// fieldFilter returns the conditions on a field of the items.
+func fieldFilter[T any, V constraints.Ordered](value func(item T) V) filterField[T] {
+	return func(filter map[string]interface{}) func(item T) bool {
+		conditions := make([]func(item T) bool, 0, len(filter))
+
+		for operator, arg := range filter {
+			if arg == nil {
+				continue
+			}
+
+			switch operator {
+			case "eq":
+				operand := arg.(V)
+				conditions = append(conditions, func(item T) bool { return value(item) == operand })
+			case "in":
+				operands := make(map[V]bool)
+				for _, operand := range arg.([]interface{}) {
+					operands[operand.(V)] = true
+				}
+				conditions = append(conditions, func(item T) bool { return operands[value(item)] })
+			case "gt":
+				operand := arg.(V)
+				conditions = append(conditions, func(item T) bool { return value(item) > operand })
+			case "gte":
+				operand := arg.(V)
+				conditions = append(conditions, func(item T) bool { return value(item) >= operand })
+			case "lt":
+				operand := arg.(V)
+				conditions = append(conditions, func(item T) bool { return value(item) < operand })
+			case "lte":
+				operand := arg.(V)
+				conditions = append(conditions, func(item T) bool { return value(item) <= operand })
+			case "contains":
+				operand := arg.(string)
+				conditions = append(conditions, func(item T) bool { return strings.Contains(interface{}(value(item)).(string), operand) })
+			case "startsWith":
+				operand := arg.(string)
+				conditions = append(conditions, func(item T) bool { return strings.HasPrefix(interface{}(value(item)).(string), operand) })
+			}
+		}
+
+		return utils.All(conditions...)
+	}
+}
+
+// whereCondition returns the condition described by the where input, using the filters on each field.
+func whereCondition[T any](where map[string]interface{}, fields map[string]filterField[T]) func(item T) bool {
+	conditions := make([]func(item T) bool, 0, len(where))
+
+	for name, arg := range where {
+		if arg == nil {
+			continue
+		}
+
+		switch name {
+		case "AND":
+			conditions = append(conditions, utils.All(whereConditions(arg.([]interface{}), fields)...))
+		case "OR":
+			conditions = append(conditions, utils.Any(whereConditions(arg.([]interface{}), fields)...))
+		case "NOT":
+			conditions = append(conditions, utils.Negate(whereCondition(arg.(map[string]interface{}), fields)))
+		default:
+			conditions = append(conditions, fields[name](arg.(map[string]interface{})))
+		}
+	}
+
+	return utils.All(conditions...)
+}
+
+func whereConditions[T any](wheres []interface{}, fields map[string]filterField[T]) []func(item T) bool {
+	return utils.Transform(wheres, func(where interface{}) func(item T) bool {
+		return whereCondition(where.(map[string]interface{}), fields)
+	})
+}
+
+// filterWhere returns the items matching the where argument, or every item if it is not present.
+func filterWhere[S []T, T any](items S, args map[string]interface{}, fields map[string]filterField[T]) S {
+	where, ok := args["where"].(map[string]interface{})
+	if !ok {
+		return items
+	}
+	return utils.Where(items, whereCondition(where, fields))
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Filters", func() {
+	Context("conditions", func() {
+		type item struct {
+			number int
+			text   string
+		}
+
+		items := []item{
+			{0, "zero"}, {1, "one"}, {2, "two"}, {3, "three"}, {4, "four"},
+			{5, "five"}, {6, "six"}, {7, "seven"}, {8, "eight"}, {9, "nine"},
+		}
+
+		fields := map[string]filterField[item]{
+			"number": fieldFilter(func(item item) int { return item.number }),
+			"text":   fieldFilter(func(item item) string { return item.text }),
+		}
+
+		numbers := func(items []item) []int {
+			return utils.Transform(items, func(item item) int { return item.number })
+		}
+
+		DescribeTable("match the items", func(where map[string]interface{}, expected []int) {
+			result := utils.Where(items, whereCondition(where, fields))
+
+			Expect(numbers(result)).To(Equal(expected))
+		},
+			Entry("no filters", map[string]interface{}{}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}),
+			Entry("empty filter", map[string]interface{}{"number": map[string]interface{}{}}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}),
+			Entry("null filter", map[string]interface{}{"number": nil}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}),
+			Entry("null condition", map[string]interface{}{"number": map[string]interface{}{"eq": nil}}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}),
+
+			Entry("int eq", map[string]interface{}{"number": map[string]interface{}{"eq": 3}}, []int{3}),
+			Entry("int in", map[string]interface{}{"number": map[string]interface{}{"in": []interface{}{1, 5, 42}}}, []int{1, 5}),
+			Entry("int in nothing", map[string]interface{}{"number": map[string]interface{}{"in": []interface{}{}}}, []int{}),
+			Entry("int gt", map[string]interface{}{"number": map[string]interface{}{"gt": 7}}, []int{8, 9}),
+			Entry("int gte", map[string]interface{}{"number": map[string]interface{}{"gte": 7}}, []int{7, 8, 9}),
+			Entry("int lt", map[string]interface{}{"number": map[string]interface{}{"lt": 2}}, []int{0, 1}),
+			Entry("int lte", map[string]interface{}{"number": map[string]interface{}{"lte": 2}}, []int{0, 1, 2}),
+			Entry("int range", map[string]interface{}{"number": map[string]interface{}{"gte": 3, "lt": 6}}, []int{3, 4, 5}),
+			Entry("empty int range", map[string]interface{}{"number": map[string]interface{}{"gt": 6, "lt": 3}}, []int{}),
+
+			Entry("string eq", map[string]interface{}{"text": map[string]interface{}{"eq": "six"}}, []int{6}),
+			Entry("string eq is case sensitive", map[string]interface{}{"text": map[string]interface{}{"eq": "Six"}}, []int{}),
+			Entry("string in", map[string]interface{}{"text": map[string]interface{}{"in": []interface{}{"one", "two", "many"}}}, []int{1, 2}),
+			Entry("string contains", map[string]interface{}{"text": map[string]interface{}{"contains": "e"}}, []int{0, 1, 3, 5, 7, 8, 9}),
+			Entry("string contains empty", map[string]interface{}{"text": map[string]interface{}{"contains": ""}}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}),
+			Entry("string startsWith", map[string]interface{}{"text": map[string]interface{}{"startsWith": "t"}}, []int{2, 3}),
+			Entry("string conditions", map[string]interface{}{"text": map[string]interface{}{"startsWith": "f", "contains": "v"}}, []int{5}),
+
+			Entry("fields", map[string]interface{}{
+				"number": map[string]interface{}{"lt": 5},
+				"text":   map[string]interface{}{"contains": "o"},
+			}, []int{0, 1, 2, 4}),
+
+			Entry("AND", map[string]interface{}{"AND": []interface{}{
+				map[string]interface{}{"number": map[string]interface{}{"gt": 2}},
+				map[string]interface{}{"text": map[string]interface{}{"startsWith": "f"}},
+			}}, []int{4, 5}),
+			Entry("empty AND", map[string]interface{}{"AND": []interface{}{}}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}),
+			Entry("OR", map[string]interface{}{"OR": []interface{}{
+				map[string]interface{}{"number": map[string]interface{}{"lt": 1}},
+				map[string]interface{}{"text": map[string]interface{}{"startsWith": "s"}},
+			}}, []int{0, 6, 7}),
+			Entry("empty OR", map[string]interface{}{"OR": []interface{}{}}, []int{}),
+			Entry("NOT", map[string]interface{}{"NOT": map[string]interface{}{
+				"number": map[string]interface{}{"in": []interface{}{0, 1, 2, 3, 4}},
+			}}, []int{5, 6, 7, 8, 9}),
+			Entry("NOT and a field", map[string]interface{}{
+				"number": map[string]interface{}{"lt": 5},
+				"NOT":    map[string]interface{}{"text": map[string]interface{}{"contains": "o"}},
+			}, []int{3}),
+			Entry("nested", map[string]interface{}{"OR": []interface{}{
+				map[string]interface{}{"AND": []interface{}{
+					map[string]interface{}{"number": map[string]interface{}{"gte": 8}},
+					map[string]interface{}{"NOT": map[string]interface{}{"text": map[string]interface{}{"eq": "nine"}}},
+				}},
+				map[string]interface{}{"NOT": map[string]interface{}{"NOT": map[string]interface{}{
+					"number": map[string]interface{}{"eq": 1},
+				}}},
+			}}, []int{1, 8}),
+		)
+
+		It("returns every item without a where argument", func() {
+			Expect(filterWhere(items, map[string]interface{}{}, fields)).To(Equal(items))
+		})
+	})
+
+	Context("queries", func() {
+		var testData data.IWritableData
+		var params graphql.Params
+
+		BeforeEach(func() {
+			config := data.DefaultGeneratorConfig()
+			config.Users = 3
+			config.AlbumsPerUser = data.Range{Min: 2, Max: 2}
+			config.PhotosPerAlbum = data.Range{Min: 2, Max: 2}
+			testData, _ = NewTestDataWithConfig(config)
+
+			api := NewAPI(testData, NewAuthenticationProvider())
+			params = graphql.Params{
+				Schema:  api.Schema,
+				Context: WithIdentity(context.Background(), Identity{UserID: 0}),
+			}
+		})
+
+		ids := func(r *graphql.Result, key string) []int {
+			Expect(r.Errors).To(BeEmpty())
+			items := getData[[]map[string]int](r, key)
+			return utils.Transform(items, func(item map[string]int) int { return item["id"] })
+		}
+
+		DescribeTable("filter the lists", func(query string, key string, expected []int) {
+			params.RequestString = query
+
+			Expect(ids(graphql.Do(params), key)).To(Equal(expected))
+		},
+			Entry("users by id", `{ users(where:{id:{in:[0, 2]}}) { id } }`, "users", []int{0, 2}),
+			Entry("users by role", `{ users(where:{role:{eq:ADMIN}}) { id } }`, "users", []int{0}),
+			Entry("users by roles", `{ users(where:{role:{in:[USER]}}) { id } }`, "users", []int{1, 2}),
+			Entry("users by email", `{ users(where:{email:{contains:"@"}}) { id } }`, "users", []int{0, 1, 2}),
+			Entry("users then paged", `{ users(where:{id:{gte:1}}, limit:1) { id } }`, "users", []int{1}),
+			Entry("albums by user and id", `{ albums(where:{userid:{eq:1}, id:{gte:3}}) { id } }`, "albums", []int{3}),
+			Entry("albums with OR", `{ albums(where:{OR:[{id:{lt:1}}, {id:{gt:4}}]}) { id } }`, "albums", []int{0, 5}),
+			Entry("albums with the userid argument", `{ albums(userid:2, where:{id:{lte:4}}) { id } }`, "albums", []int{4}),
+			Entry("photos with NOT", `{ photos(where:{NOT:{albumid:{in:[0, 1, 2, 3]}}}) { id } }`, "photos", []int{8, 9, 10, 11}),
+			Entry("photos with AND", `{ photos(where:{AND:[{id:{gt:2}}, {id:{lt:5}}]}) { id } }`, "photos", []int{3, 4}),
+			Entry("photos then offset", `{ photos(albumid:1, where:{id:{gte:0}}, offset:1) { id } }`, "photos", []int{3}),
+		)
+
+		It("filters by description", func() {
+			word := strings.Fields(must(testData.GetPhoto(0)).Description)[1]
+			expected := utils.Where(must(testData.GetPhotos()), func(photo data.Photo) bool {
+				return strings.Contains(photo.Description, word)
+			})
+
+			params.RequestString = `query ($word: String!) { photos(where:{description:{contains:$word}}) { id } }`
+			params.VariableValues = map[string]interface{}{"word": word}
+
+			Expect(ids(graphql.Do(params), "photos")).To(Equal(utils.Transform(expected, func(photo data.Photo) int { return photo.ID })))
+		})
+
+		It("accepts the where input as a variable", func() {
+			params.RequestString = `query ($where: AlbumWhere) { albums(where:$where) { id } }`
+			params.VariableValues = map[string]interface{}{
+				"where": map[string]interface{}{
+					"OR": []interface{}{
+						map[string]interface{}{"id": map[string]interface{}{"in": []interface{}{1, 2}}},
+						map[string]interface{}{"description": map[string]interface{}{"eq": must(testData.GetAlbum(5)).Description}},
+					},
+				},
+			}
+
+			Expect(ids(graphql.Do(params), "albums")).To(Equal([]int{1, 2, 5}))
+		})
+
+		It("filters connections before paging", func() {
+			params.RequestString = `{ albumsConnection(where:{userid:{in:[1, 2]}}, first:1) { totalCount edges { node { id } } } }`
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(BeEmpty())
+
+			connection := getData[utils.Connection[data.Album]](r, "albumsConnection")
+			Expect(connection.TotalCount).To(Equal(4))
+			Expect(connection.Edges).To(HaveLen(1))
+			Expect(connection.Edges[0].Node.ID).To(Equal(2))
+		})
+
+		Context("on emails", func() {
+			It("only match the emails the caller can see", func() {
+				params.Context = WithIdentity(context.Background(), Identity{UserID: 1})
+				params.RequestString = `{ usersConnection(where:{email:{contains:"@"}}) { totalCount } }`
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+				Expect(getData[utils.Connection[data.User]](r, "usersConnection").TotalCount).To(Equal(1))
+			})
+
+			It("do not reveal the emails of other users when negated", func() {
+				params.Context = WithIdentity(context.Background(), Identity{UserID: 1})
+				params.RequestString = `{ users(where:{NOT:{email:{eq:"` + must(testData.GetUser(2)).Email + `"}}}) { id } }`
+
+				Expect(ids(graphql.Do(params), "users")).To(Equal([]int{0, 1, 2}))
+			})
+
+			It("match nothing for an anonymous caller", func() {
+				params.Context = context.Background()
+				params.RequestString = `{ users(where:{email:{contains:"@"}}) { id } }`
+
+				Expect(ids(graphql.Do(params), "users")).To(BeEmpty())
+			})
+		})
+
+		It("rejects unknown operators", func() {
+			params.RequestString = `{ users(where:{role:{contains:"ADMIN"}}) { id } }`
+
+			r := graphql.Do(params)
+			Expect(r.Errors).To(HaveLen(1))
+		})
+	})
+})
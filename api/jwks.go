@@ -0,0 +1,106 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// JSONWebKey is the public part of a signing key, as published in a JSON Web Key Set (RFC 7517).
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+
+	// RSA keys.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC keys.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is the set of keys that tokens are verified with.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// newJSONWebKey returns the JWK of an RSA or EC public key, without a key ID.
+func newJSONWebKey(key interface{}, algorithm string) (JSONWebKey, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: algorithm,
+			N:         base64URL(key.N.Bytes()),
+			E:         base64URL(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return JSONWebKey{
+			KeyType:   "EC",
+			Use:       "sig",
+			Algorithm: algorithm,
+			Curve:     key.Curve.Params().Name,
+			X:         base64URL(key.X.FillBytes(make([]byte, size))),
+			Y:         base64URL(key.Y.FillBytes(make([]byte, size))),
+		}, nil
+	default:
+		return JSONWebKey{}, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// keyID returns the JWK thumbprint (RFC 7638) of the key, which is used as its "kid".
+// HMAC secrets are never published, so their thumbprint is only used to find the secret again.
+func keyID(key interface{}) (string, error) {
+	var members interface{}
+
+	switch key := key.(type) {
+	case []byte:
+		members = struct {
+			K   string `json:"k"`
+			Kty string `json:"kty"`
+		}{base64URL(key), "oct"}
+	default:
+		jwk, err := newJSONWebKey(key, "")
+		if err != nil {
+			return "", err
+		}
+
+		// The required members, in lexicographic order.
+		if jwk.KeyType == "RSA" {
+			members = struct {
+				E   string `json:"e"`
+				Kty string `json:"kty"`
+				N   string `json:"n"`
+			}{jwk.E, jwk.KeyType, jwk.N}
+		} else {
+			members = struct {
+				Crv string `json:"crv"`
+				Kty string `json:"kty"`
+				X   string `json:"x"`
+				Y   string `json:"y"`
+			}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y}
+		}
+	}
+
+	bytes, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(bytes)
+	return base64URL(sum[:]), nil
+}
+
+func base64URL(bytes []byte) string {
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// publicKeyFromJWK converts a published key back into the public key, as a consumer of the JWKS would.
+func publicKeyFromJWK(jwk JSONWebKey) interface{} {
+	decode := func(s string) *big.Int {
+		bytes, err := base64.RawURLEncoding.DecodeString(s)
+		Expect(err).To(BeNil())
+		return new(big.Int).SetBytes(bytes)
+	}
+
+	switch jwk.KeyType {
+	case "RSA":
+		return &rsa.PublicKey{N: decode(jwk.N), E: int(decode(jwk.E).Int64())}
+	case "EC":
+		Expect(jwk.Curve).To(Equal("P-256"))
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: decode(jwk.X), Y: decode(jwk.Y)}
+	}
+
+	Fail("unexpected key type " + jwk.KeyType)
+	return nil
+}
+
+var _ = Describe("JSON Web Keys", func() {
+	It("uses the RFC 7638 thumbprint as the key ID", func() {
+		// The example key from RFC 7638 section 3.1.
+		n, _ := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+
+		id, err := keyID(key)
+
+		Expect(err).To(BeNil())
+		Expect(id).To(Equal("NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"))
+	})
+
+	It("round trips an RSA key", func() {
+		key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+		jwk, err := newJSONWebKey(&key.PublicKey, "RS256")
+
+		Expect(err).To(BeNil())
+		Expect(jwk.KeyType).To(Equal("RSA"))
+		Expect(jwk.E).To(Equal("AQAB"))
+		Expect(publicKeyFromJWK(jwk)).To(Equal(&key.PublicKey))
+	})
+
+	It("round trips an EC key with fixed length coordinates", func() {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+		jwk, err := newJSONWebKey(&key.PublicKey, "ES256")
+
+		Expect(err).To(BeNil())
+		Expect(jwk.KeyType).To(Equal("EC"))
+		Expect(jwk.Curve).To(Equal("P-256"))
+		Expect(jwk.X).To(HaveLen(43))
+		Expect(jwk.Y).To(HaveLen(43))
+		Expect(key.PublicKey.Equal(publicKeyFromJWK(jwk))).To(BeTrue())
+	})
+
+	It("gives HMAC secrets a key ID without publishing them", func() {
+		id, err := keyID([]byte("secret"))
+		Expect(err).To(BeNil())
+		Expect(id).ToNot(BeEmpty())
+
+		_, err = newJSONWebKey([]byte("secret"), "HS256")
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+)
+
+// loader batches the loads of values by key, and caches the values.
+//
+// Resolvers call load for each key and return the thunk to graphql, which calls the thunks
+// once every field at the same depth has been resolved. The first thunk called loads the
+// values of every key queued until then in a single batch.
+//
+// If a batch fails, its error is returned for each of its keys.
+type loader[K comparable, V any] struct {
+	batch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	queued  []K
+	pending map[K]bool
+	values  map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](batch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		batch:   batch,
+		pending: make(map[K]bool),
+		values:  make(map[K]V),
+		errs:    make(map[K]error),
+	}
+}
+
+// load queues the key to be loaded, and returns a thunk for its value.
+func (l *loader[K, V]) load(key K) func() (V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.loaded(key) && !l.pending[key] {
+		l.queued = append(l.queued, key)
+		l.pending[key] = true
+	}
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if !l.loaded(key) {
+			l.dispatch()
+		}
+		return l.values[key], l.errs[key]
+	}
+}
+
+// loaded returns whether the key has been loaded, or failed to be.
+func (l *loader[K, V]) loaded(key K) bool {
+	_, ok := l.values[key]
+	if !ok {
+		_, ok = l.errs[key]
+	}
+	return ok
+}
+
+// dispatch loads every queued key.
+func (l *loader[K, V]) dispatch() {
+	values, err := l.batch(l.queued)
+	for _, key := range l.queued {
+		if err != nil {
+			l.errs[key] = err
+		} else {
+			l.values[key] = values[key]
+		}
+	}
+
+	l.queued = nil
+	l.pending = make(map[K]bool)
+}
+
+// loaders are the loaders of a request.
+type loaders struct {
+	albumsByUserID  *loader[int, []data.Album]
+	photosByAlbumID *loader[int, []data.Photo]
+}
+
+func albumsByUserID(loaders *loaders) *loader[int, []data.Album] {
+	return loaders.albumsByUserID
+}
+
+func photosByAlbumID(loaders *loaders) *loader[int, []data.Photo] {
+	return loaders.photosByAlbumID
+}
+
+// loadersFor returns the loaders of the request, if the field may use them.
+// Mutations are not batched, as graphql only calls the thunks after every mutation has run.
+func loadersFor(p graphql.ResolveParams) (*loaders, bool) {
+	if isMutation(p) {
+		return nil, false
+	}
+
+	request, ok := requestFor(p)
+	if !ok {
+		return nil, false
+	}
+	return &request.loaders, true
+}
+
+// loadList resolves a field to the list loaded by the loader, or looked up directly if the field
+// may not use loaders, and then paged. Paging arguments are checked before loading, as graphql
+// drops the code of errors returned by thunks.
+func loadList[T any](p graphql.ResolveParams, key int, loaderOf func(*loaders) *loader[int, []T], lookup func(key int) ([]T, error)) (interface{}, error) {
+	if _, err := utils.TryPageIfPresent([]T{}, p.Args); err != nil {
+		return nil, err
+	}
+
+	return load(p, key, loaderOf, lookup, func(items []T) (interface{}, error) {
+		return utils.TryPageIfPresent(items, p.Args)
+	})
+}
+
+// loadConnection resolves a field to a connection of the list loaded by the loader, or looked up directly
+// if the field may not use loaders. Like loadList, the connection arguments are checked before loading.
+func loadConnection[T any](p graphql.ResolveParams, key int, loaderOf func(*loaders) *loader[int, []T], lookup func(key int) ([]T, error), cursors utils.Cursors[T]) (interface{}, error) {
+	if err := utils.CheckConnectionArgs(p.Args, cursors); err != nil {
+		return nil, err
+	}
+
+	return load(p, key, loaderOf, lookup, func(items []T) (interface{}, error) {
+		return utils.NewConnection(items, p.Args, cursors)
+	})
+}
+
+// load resolves a field to the result of the value loaded by the loader, or looked up directly
+// if the field may not use loaders.
+func load[V any](p graphql.ResolveParams, key int, loaderOf func(*loaders) *loader[int, V], lookup func(key int) (V, error), result func(value V) (interface{}, error)) (interface{}, error) {
+	requestLoaders, ok := loadersFor(p)
+	if !ok {
+		value, err := lookup(key)
+		if err != nil {
+			return nil, dataError(err)
+		}
+		return result(value)
+	}
+
+	thunk := loaderOf(requestLoaders).load(key)
+	return func() (interface{}, error) {
+		value, err := thunk()
+		if err != nil {
+			return nil, dataError(err)
+		}
+		return result(value)
+	}, nil
+}
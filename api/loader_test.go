@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// countingData counts the lookups of albums and photos.
+type countingData struct {
+	data.IWritableData
+
+	albumsByUserID   int
+	albumsByUserIDs  [][]int
+	photosByAlbumID  int
+	photosByAlbumIDs [][]int
+}
+
+func (d *countingData) GetAlbumsByUserID(userID int) ([]data.Album, error) {
+	d.albumsByUserID++
+	return d.IWritableData.GetAlbumsByUserID(userID)
+}
+
+func (d *countingData) GetAlbumsByUserIDs(userIDs []int) (map[int][]data.Album, error) {
+	d.albumsByUserIDs = append(d.albumsByUserIDs, userIDs)
+	return d.IWritableData.GetAlbumsByUserIDs(userIDs)
+}
+
+func (d *countingData) GetPhotosByAlbumID(albumID int) ([]data.Photo, error) {
+	d.photosByAlbumID++
+	return d.IWritableData.GetPhotosByAlbumID(albumID)
+}
+
+func (d *countingData) GetPhotosByAlbumIDs(albumIDs []int) (map[int][]data.Photo, error) {
+	d.photosByAlbumIDs = append(d.photosByAlbumIDs, albumIDs)
+	return d.IWritableData.GetPhotosByAlbumIDs(albumIDs)
+}
+
+// perItemData looks up the albums and photos of each user and album in turn, as if there were no batching.
+type perItemData struct {
+	data.IWritableData
+}
+
+func (d perItemData) GetAlbumsByUserIDs(userIDs []int) (map[int][]data.Album, error) {
+	albums := make(map[int][]data.Album, len(userIDs))
+	for _, id := range userIDs {
+		userAlbums, err := d.GetAlbumsByUserID(id)
+		if err != nil {
+			return nil, err
+		}
+		albums[id] = userAlbums
+	}
+	return albums, nil
+}
+
+func (d perItemData) GetPhotosByAlbumIDs(albumIDs []int) (map[int][]data.Photo, error) {
+	photos := make(map[int][]data.Photo, len(albumIDs))
+	for _, id := range albumIDs {
+		albumPhotos, err := d.GetPhotosByAlbumID(id)
+		if err != nil {
+			return nil, err
+		}
+		photos[id] = albumPhotos
+	}
+	return photos, nil
+}
+
+var _ = Describe("Loader", func() {
+	var batches [][]int
+	var l *loader[int, string]
+
+	BeforeEach(func() {
+		batches = nil
+		l = newLoader(func(keys []int) (map[int]string, error) {
+			batches = append(batches, keys)
+			values := make(map[int]string, len(keys))
+			for _, key := range keys {
+				values[key] = string(rune('a' + key))
+			}
+			return values, nil
+		})
+	})
+
+	It("loads every queued key in one batch", func() {
+		thunks := []func() (string, error){l.load(0), l.load(1), l.load(2)}
+
+		Expect(batches).To(BeEmpty())
+		Expect(thunks[1]()).To(Equal("b"))
+		Expect(thunks[0]()).To(Equal("a"))
+		Expect(thunks[2]()).To(Equal("c"))
+		Expect(batches).To(Equal([][]int{{0, 1, 2}}))
+	})
+
+	It("loads each key once", func() {
+		a, b := l.load(0), l.load(0)
+
+		Expect(a()).To(Equal("a"))
+		Expect(b()).To(Equal("a"))
+		Expect(l.load(0)()).To(Equal("a"))
+		Expect(batches).To(Equal([][]int{{0}}))
+	})
+
+	It("loads keys queued after a batch in the next batch", func() {
+		l.load(0)()
+		b, c := l.load(1), l.load(2)
+
+		Expect(c()).To(Equal("c"))
+		Expect(b()).To(Equal("b"))
+		Expect(batches).To(Equal([][]int{{0}, {1, 2}}))
+	})
+})
+
+var _ = Describe("Batching", func() {
+	var testData data.IWritableData
+	var counting *countingData
+	var params graphql.Params
+
+	BeforeEach(func() {
+		config := data.DefaultGeneratorConfig()
+		config.Users = 3
+		config.AlbumsPerUser = data.Range{Min: 2, Max: 2}
+		config.PhotosPerAlbum = data.Range{Min: 2, Max: 2}
+		testData, _ = NewTestDataWithConfig(config)
+		counting = &countingData{IWritableData: testData}
+
+		api := NewAPI(counting, NewAuthenticationProvider())
+		params = graphql.Params{
+			Schema:  api.Schema,
+			Context: WithIdentity(context.Background(), Identity{UserID: 0}),
+		}
+	})
+
+	do := func(query string) *graphql.Result {
+		params.RequestString = query
+		return graphql.Do(params)
+	}
+
+	It("looks up the albums and photos of every user at once", func() {
+		r := do(`{ users { id albums { id photos { id albumid description } } } }`)
+		Expect(r.Errors).To(BeEmpty())
+
+		users := getData[[]data.User](r, "users")
+		Expect(users).To(HaveLen(3))
+		for _, user := range users {
+			Expect(user.Albums).To(HaveLen(2))
+			for _, album := range user.Albums {
+				Expect(album.Photos).To(Equal(must(testData.GetPhotosByAlbumID(album.ID))))
+			}
+		}
+
+		Expect(counting.albumsByUserID).To(BeZero())
+		Expect(counting.albumsByUserIDs).To(HaveLen(1))
+		Expect(counting.albumsByUserIDs[0]).To(ConsistOf(0, 1, 2))
+		Expect(counting.photosByAlbumID).To(BeZero())
+		Expect(counting.photosByAlbumIDs).To(HaveLen(1))
+		Expect(counting.photosByAlbumIDs[0]).To(ConsistOf(0, 1, 2, 3, 4, 5))
+	})
+
+	It("looks up the album and photo connections of every user at once", func() {
+		r := do(`{ users { albumsConnection(first:1) { edges { node { id photosConnection { totalCount } } } } } }`)
+		Expect(r.Errors).To(BeEmpty())
+
+		for _, user := range r.Data.(map[string]interface{})["users"].([]interface{}) {
+			edges := user.(map[string]interface{})["albumsConnection"].(map[string]interface{})["edges"].([]interface{})
+			Expect(edges).To(HaveLen(1))
+			Expect(edges[0]).To(HaveKeyWithValue("node", HaveKeyWithValue("photosConnection", HaveKeyWithValue("totalCount", 2))))
+		}
+
+		Expect(counting.albumsByUserID).To(BeZero())
+		Expect(counting.albumsByUserIDs).To(HaveLen(1))
+		Expect(counting.albumsByUserIDs[0]).To(ConsistOf(0, 1, 2))
+		Expect(counting.photosByAlbumID).To(BeZero())
+		Expect(counting.photosByAlbumIDs).To(HaveLen(1))
+		Expect(counting.photosByAlbumIDs[0]).To(ConsistOf(0, 2, 4))
+	})
+
+	It("looks up each user's albums once", func() {
+		r := do(`{ a: user(id:1) { albums { id } } b: user(id:1) { albums { id } } c: user(id:2) { albums { id } } }`)
+		Expect(r.Errors).To(BeEmpty())
+
+		Expect(counting.albumsByUserIDs).To(HaveLen(1))
+		Expect(counting.albumsByUserIDs[0]).To(ConsistOf(1, 2))
+	})
+
+	It("pages each list", func() {
+		r := do(`{ users { albums(offset:1) { photos(limit:1) { id albumid description } } } }`)
+		Expect(r.Errors).To(BeEmpty())
+
+		users := getData[[]data.User](r, "users")
+		Expect(users[2].Albums).To(HaveLen(1))
+		Expect(users[2].Albums[0].Photos).To(Equal(must(testData.GetPhotosByAlbumID(5))[:1]))
+	})
+
+	It("rejects invalid paging before looking anything up", func() {
+		r := do(`{ users { albums(limit:-1) { id } } }`)
+
+		Expect(r.Errors).To(HaveLen(3))
+		Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+		Expect(counting.albumsByUserIDs).To(BeEmpty())
+	})
+
+	It("rejects invalid connection arguments before looking anything up", func() {
+		r := do(`{ users { albumsConnection(after:"not a cursor") { totalCount } } }`)
+
+		Expect(r.Errors).To(HaveLen(3))
+		Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+		Expect(counting.albumsByUserIDs).To(BeEmpty())
+	})
+
+	It("does not cache across requests", func() {
+		do(`{ user(id:0) { albums { id } } }`)
+		testData.CreateAlbum(data.Album{UserID: 0})
+
+		r := do(`{ user(id:0) { albums { id } } }`)
+		Expect(r.Errors).To(BeEmpty())
+
+		Expect(getData[data.User](r, "user").Albums).To(HaveLen(3))
+		Expect(counting.albumsByUserIDs).To(HaveLen(2))
+	})
+
+	It("does not batch mutations", func() {
+		r := do(`mutation {
+			createAlbum(input:{userid:0, description:"New"}) { id }
+			updateUser(id:0, input:{name:"Renamed"}) { albums { id } }
+		}`)
+		Expect(r.Errors).To(BeEmpty())
+
+		Expect(getData[data.User](r, "updateUser").Albums).To(HaveLen(3))
+		Expect(counting.albumsByUserID).To(Equal(1))
+		Expect(counting.albumsByUserIDs).To(BeEmpty())
+	})
+})
+
+// Resolves the albums and photos of every user, as lists and as connections, in a dataset of 50 users
+// with 10 albums of 10 photos each.
+func BenchmarkNestedLists(b *testing.B) {
+	_, testData := newBenchmarkData(b, 50)
+
+	const lists = `{ users { id albums { id photos { id } } } }`
+	const connections = `{ users { id albumsConnection { edges { node { id photosConnection { edges { node { id } } } } } } } }`
+
+	benchmarks := []struct {
+		name      string
+		dataModel data.IData
+		query     string
+	}{
+		{"per item", perItemData{testData}, lists},
+		{"batched", testData, lists},
+		{"connections per item", perItemData{testData}, connections},
+		{"connections batched", testData, connections},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			params := graphql.Params{
+				Schema:        NewAPI(benchmark.dataModel, NewAuthenticationProvider()).Schema,
+				RequestString: benchmark.query,
+				Context:       context.Background(),
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if r := graphql.Do(params); len(r.Errors) > 0 {
+					b.Fatal(r.Errors)
+				}
+			}
+		})
+	}
+}
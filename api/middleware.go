@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/graphql-go/graphql"
+)
+
+type contextKey int
+
+const identityKey contextKey = iota
+
+var errUnauthenticated = apierrors.New(apierrors.Unauthenticated, "unauthenticated")
+
+// WithIdentity returns a copy of ctx carrying the authenticated caller.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// IdentityFromContext returns the authenticated caller, if there is one.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	if ctx == nil {
+		return Identity{}, false
+	}
+	identity, ok := ctx.Value(identityKey).(Identity)
+	return identity, ok
+}
+
+// AuthenticationMiddleware validates the "Authorization: Bearer" token of each request
+// and adds the authenticated caller to the request context.
+// Requests without a valid token are passed on anonymously.
+func AuthenticationMiddleware(auth IAuthenticationProvider, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+
+		if found && strings.EqualFold(scheme, "Bearer") {
+			if identity, err := auth.ValidateToken(strings.TrimSpace(token)); err == nil {
+				r = r.WithContext(WithIdentity(r.Context(), identity))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticated marks a field as requiring an authenticated caller.
+func authenticated(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if _, ok := IdentityFromContext(p.Context); !ok {
+			return nil, errUnauthenticated
+		}
+		return resolve(p)
+	}
+}
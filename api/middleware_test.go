@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuthenticationMiddleware", func() {
+	auth := NewAuthenticationProvider()
+
+	var identity Identity
+	var authenticated bool
+	var handler http.Handler
+
+	BeforeEach(func() {
+		identity, authenticated = Identity{}, false
+		handler = AuthenticationMiddleware(auth, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, authenticated = IdentityFromContext(r.Context())
+		}))
+	})
+
+	serve := func(authorization string) {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	It("adds the identity of a valid bearer token", func() {
+		token, _ := auth.GetToken(4)
+
+		serve("Bearer " + token)
+
+		Expect(authenticated).To(BeTrue())
+		Expect(identity.UserID).To(Equal(4))
+		Expect(identity.TokenID).ToNot(BeEmpty())
+	})
+
+	It("accepts a lower case scheme", func() {
+		token, _ := auth.GetToken(4)
+
+		serve("bearer " + token)
+
+		Expect(authenticated).To(BeTrue())
+	})
+
+	DescribeTable("passes requests on anonymously", func(authorization func() string) {
+		serve(authorization())
+
+		Expect(authenticated).To(BeFalse())
+	},
+		Entry("no header", func() string { return "" }),
+		Entry("invalid token", func() string { return "Bearer not a token" }),
+		Entry("other scheme", func() string {
+			token, _ := auth.GetToken(4)
+			return "Basic " + token
+		}),
+		Entry("token from another provider", func() string {
+			token, _ := newInvalidAuthenticationProvider().GetToken(4)
+			return "Bearer " + token
+		}),
+	)
+})
+
+var _ = Describe("Protected fields", func() {
+	testData := NewTestData()
+	api := NewAPI(testData, NewAuthenticationProvider())
+
+	DescribeTable("require authentication", func(query string) {
+		r := graphql.Do(graphql.Params{
+			Schema:        api.Schema,
+			RequestString: query,
+			Context:       context.Background(),
+		})
+
+		Expect(r.Errors).To(HaveLen(1))
+		Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated))
+	},
+		Entry("User.passwordHash", `{ user(id:0) { passwordHash } }`),
+		Entry("createUser", `mutation { createUser(input:{name:"a", username:"a", email:"a", password:"a"}) { id } }`),
+		Entry("updateAlbum", `mutation { updateAlbum(id:0, input:{description:"a"}) { id } }`),
+		Entry("deletePhoto", `mutation { deletePhoto(id:0) { id } }`),
+	)
+
+	It("allows public fields without authentication", func() {
+		r := graphql.Do(graphql.Params{
+			Schema:        api.Schema,
+			RequestString: `{ user(id:0) { id name } }`,
+			Context:       context.Background(),
+		})
+
+		Expect(r.Errors).To(BeEmpty())
+	})
+
+	It("allows protected fields with authentication", func() {
+		r := graphql.Do(graphql.Params{
+			Schema:        api.Schema,
+			RequestString: `{ user(id:0) { passwordHash } }`,
+			Context:       WithIdentity(context.Background(), Identity{UserID: 0}),
+		})
+
+		Expect(r.Errors).To(BeEmpty())
+		Expect(getData[map[string]string](r, "user")["passwordHash"]).To(Equal(must(testData.GetUser(0)).PasswordHash))
+	})
+})
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newCrudMutationFields returns the create, update and delete mutations for users, albums and photos.
+// Users may only change their own data, unless they are an administrator.
+func newCrudMutationFields(dataModel data.IWritableData, policy authorizationPolicy, passwordCost int, userType, albumType, photoType *graphql.Object) graphql.Fields {
+	createUserInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "CreateUserInput",
+		Description: "A new user.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "The name of the user.",
+			},
+			"username": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "The username of the user.",
+			},
+			"email": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "The email of the user.",
+			},
+			"password": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "The password of the user.",
+			},
+		},
+	})
+
+	updateUserInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "UpdateUserInput",
+		Description: "Changes to a user. Omitted fields are left unchanged. Passwords are changed by changePassword, or resetPassword.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "The name of the user.",
+			},
+			"username": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "The username of the user.",
+			},
+			"email": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "The email of the user.",
+			},
+		},
+	})
+
+	createAlbumInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "CreateAlbumInput",
+		Description: "A new album.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"userid": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "The id of the user.",
+			},
+			"description": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "The description of the album.",
+			},
+		},
+	})
+
+	updateAlbumInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "UpdateAlbumInput",
+		Description: "Changes to an album. Omitted fields are left unchanged.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"userid": &graphql.InputObjectFieldConfig{
+				Type:        graphql.Int,
+				Description: "The id of the user.",
+			},
+			"description": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "The description of the album.",
+			},
+		},
+	})
+
+	createPhotoInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "CreatePhotoInput",
+		Description: "A new photo.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"albumid": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "The id of the album.",
+			},
+			"description": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "The description of the photo.",
+			},
+		},
+	})
+
+	updatePhotoInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "UpdatePhotoInput",
+		Description: "Changes to a photo. Omitted fields are left unchanged.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"albumid": &graphql.InputObjectFieldConfig{
+				Type:        graphql.Int,
+				Description: "The id of the album.",
+			},
+			"description": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "The description of the photo.",
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"createUser": &graphql.Field{
+			Type:        userType,
+			Description: "Create a user",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(createUserInput),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizeAdmin(p.Context); err != nil {
+					return nil, err
+				}
+
+				input := p.Args["input"].(map[string]interface{})
+				user := data.User{
+					Name:     input["name"].(string),
+					Username: input["username"].(string),
+					Email:    input["email"].(string),
+					Role:     data.RoleUser,
+				}
+				password := input["password"].(string)
+
+				var errs validationError
+				validateEmail(&errs, user.Email, "input", "email")
+				validateRequired(&errs, user.Username, "input", "username")
+				validateRequired(&errs, user.Name, "input", "name")
+				validatePassword(&errs, password, "input", "password")
+
+				if err := errs.err(); err != nil {
+					return nil, err
+				}
+
+				if err := setPassword(&user, password, passwordCost); err != nil {
+					return nil, err
+				}
+
+				return resolveItem(dataModel.CreateUser(user))
+			}),
+		},
+		"updateUser": &graphql.Field{
+			Type:        userType,
+			Description: "Update a user",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Description: "id of the user",
+					Type:        graphql.NewNonNull(graphql.Int),
+				},
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(updateUserInput),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizeUser(p.Context, p.Args["id"].(int)); err != nil {
+					return nil, err
+				}
+
+				input := p.Args["input"].(map[string]interface{})
+				user, err := dataModel.GetUser(p.Args["id"].(int))
+				if err != nil {
+					return nil, dataError(err)
+				}
+
+				setIfPresent(input, "name", &user.Name)
+				setIfPresent(input, "username", &user.Username)
+				setIfPresent(input, "email", &user.Email)
+
+				var errs validationError
+				validateEmail(&errs, user.Email, "input", "email")
+				validateRequired(&errs, user.Username, "input", "username")
+				validateRequired(&errs, user.Name, "input", "name")
+
+				if err := errs.err(); err != nil {
+					return nil, err
+				}
+
+				return resolveItem(dataModel.UpdateUser(user))
+			}),
+		},
+		"deleteUser": &graphql.Field{
+			Type:        userType,
+			Description: "Delete a user, along with their albums and photos",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Description: "id of the user",
+					Type:        graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizeUser(p.Context, p.Args["id"].(int)); err != nil {
+					return nil, err
+				}
+
+				return resolveItem(dataModel.DeleteUser(p.Args["id"].(int)))
+			}),
+		},
+		"createAlbum": &graphql.Field{
+			Type:        albumType,
+			Description: "Create an album",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(createAlbumInput),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				input := p.Args["input"].(map[string]interface{})
+				album := data.Album{
+					UserID: input["userid"].(int),
+				}
+
+				if err := policy.of(p).authorizeUser(p.Context, album.UserID); err != nil {
+					return nil, err
+				}
+
+				setIfPresent(input, "description", &album.Description)
+
+				return resolveItem(dataModel.CreateAlbum(album))
+			}),
+		},
+		"updateAlbum": &graphql.Field{
+			Type:        albumType,
+			Description: "Update an album",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Description: "id of the album",
+					Type:        graphql.NewNonNull(graphql.Int),
+				},
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(updateAlbumInput),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizeAlbum(p.Context, p.Args["id"].(int)); err != nil {
+					return nil, err
+				}
+
+				input := p.Args["input"].(map[string]interface{})
+				album, err := dataModel.GetAlbum(p.Args["id"].(int))
+				if err != nil {
+					return nil, dataError(err)
+				}
+
+				setIfPresent(input, "userid", &album.UserID)
+				setIfPresent(input, "description", &album.Description)
+
+				if err := policy.of(p).authorizeUser(p.Context, album.UserID); err != nil {
+					return nil, err
+				}
+
+				return resolveItem(dataModel.UpdateAlbum(album))
+			}),
+		},
+		"deleteAlbum": &graphql.Field{
+			Type:        albumType,
+			Description: "Delete an album, along with its photos",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Description: "id of the album",
+					Type:        graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizeAlbum(p.Context, p.Args["id"].(int)); err != nil {
+					return nil, err
+				}
+
+				return resolveItem(dataModel.DeleteAlbum(p.Args["id"].(int)))
+			}),
+		},
+		"createPhoto": &graphql.Field{
+			Type:        photoType,
+			Description: "Create a photo",
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(createPhotoInput),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				input := p.Args["input"].(map[string]interface{})
+				photo := data.Photo{
+					AlbumID: input["albumid"].(int),
+				}
+
+				if err := policy.of(p).authorizeAlbum(p.Context, photo.AlbumID); err != nil {
+					return nil, err
+				}
+
+				setIfPresent(input, "description", &photo.Description)
+
+				return resolveItem(dataModel.CreatePhoto(photo))
+			}),
+		},
+		"updatePhoto": &graphql.Field{
+			Type:        photoType,
+			Description: "Update a photo",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Description: "id of the photo",
+					Type:        graphql.NewNonNull(graphql.Int),
+				},
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(updatePhotoInput),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizePhoto(p.Context, p.Args["id"].(int)); err != nil {
+					return nil, err
+				}
+
+				input := p.Args["input"].(map[string]interface{})
+				photo, err := dataModel.GetPhoto(p.Args["id"].(int))
+				if err != nil {
+					return nil, dataError(err)
+				}
+
+				setIfPresent(input, "albumid", &photo.AlbumID)
+				setIfPresent(input, "description", &photo.Description)
+
+				if err := policy.of(p).authorizeAlbum(p.Context, photo.AlbumID); err != nil {
+					return nil, err
+				}
+
+				return resolveItem(dataModel.UpdatePhoto(photo))
+			}),
+		},
+		"deletePhoto": &graphql.Field{
+			Type:        photoType,
+			Description: "Delete a photo",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Description: "id of the photo",
+					Type:        graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizePhoto(p.Context, p.Args["id"].(int)); err != nil {
+					return nil, err
+				}
+
+				return resolveItem(dataModel.DeletePhoto(p.Args["id"].(int)))
+			}),
+		},
+	}
+}
+
+// setIfPresent sets target to the value of the input field, if it was provided.
+func setIfPresent[T any](input map[string]interface{}, field string, target *T) {
+	if value, ok := input[field].(T); ok {
+		*target = value
+	}
+}
+
+func setPassword(user *data.User, password string, cost int) error {
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = string(hashedPass[:])
+	return nil
+}
@@ -0,0 +1,439 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = Describe("Mutations", func() {
+	var testData data.IWritableData
+	var variables map[string]interface{}
+	var params graphql.Params
+	var mutation string
+
+	BeforeEach(func() {
+		config := data.DefaultGeneratorConfig()
+		config.Users = 3
+		config.AlbumsPerUser = data.Range{Min: 2, Max: 2}
+		config.PhotosPerAlbum = data.Range{Min: 2, Max: 2}
+		testData, _ = NewTestDataWithConfig(config)
+
+		api := NewAPI(testData, NewAuthenticationProvider())
+		variables = make(map[string]interface{})
+		params = graphql.Params{
+			Schema:         api.Schema,
+			VariableValues: variables,
+			Context:        WithIdentity(context.Background(), Identity{UserID: 0}),
+		}
+	})
+
+	JustBeforeEach(func() {
+		params.RequestString = mutation
+	})
+
+	Context("Users", func() {
+		When("createUser", func() {
+			BeforeEach(func() {
+				mutation = `
+					mutation ($input: CreateUserInput!) {
+						createUser(input:$input) {
+							id
+							name
+							username
+							email
+						}
+					}`
+				variables["input"] = map[string]interface{}{
+					"name":     "New User",
+					"username": "new.user",
+					"email":    "new.user@email.co.uk",
+					"password": "NewPassword1",
+				}
+			})
+
+			It("creates the user", func() {
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				user := getData[data.User](r, "createUser")
+
+				Expect(user.ID).To(Equal(3))
+				Expect(user.Name).To(Equal("New User"))
+
+				stored := must(testData.GetUser(user.ID))
+				Expect(stored.Email).To(Equal("new.user@email.co.uk"))
+				Expect(bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("NewPassword1"))).To(Succeed())
+			})
+
+			It("rejects a duplicate email", func() {
+				variables["input"].(map[string]interface{})["email"] = must(testData.GetUser(0)).Email
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+				Expect(testData.GetUsers()).To(HaveLen(3))
+			})
+
+			It("reports every invalid field of the input", func() {
+				input := variables["input"].(map[string]interface{})
+				input["email"] = "not an email"
+				input["name"] = " "
+				input["password"] = "a"
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+				Expect(invalidFields(r.Errors[0])).To(Equal([]fieldError{
+					{Path: []string{"input", "email"}, Message: "is not a valid email address"},
+					{Path: []string{"input", "name"}, Message: "is required"},
+					{Path: []string{"input", "password"}, Message: "must be at least 8 characters"},
+				}))
+				Expect(testData.GetUsers()).To(HaveLen(3))
+			})
+
+			It("rejects a password that bcrypt would truncate", func() {
+				variables["input"].(map[string]interface{})["password"] = strings.Repeat("a1", 37)
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+				Expect(invalidFields(r.Errors[0])).To(Equal([]fieldError{
+					{Path: []string{"input", "password"}, Message: "must be at most 72 bytes"},
+				}))
+			})
+		})
+
+		When("updateUser", func() {
+			BeforeEach(func() {
+				mutation = `
+					mutation ($id: Int!, $input: UpdateUserInput!) {
+						updateUser(id:$id, input:$input) {
+							id
+							name
+							username
+						}
+					}`
+			})
+
+			It("updates only the provided fields", func() {
+				original := must(testData.GetUser(1))
+				variables["id"] = 1
+				variables["input"] = map[string]interface{}{
+					"name": "Renamed User",
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				user := getData[data.User](r, "updateUser")
+
+				Expect(user.Name).To(Equal("Renamed User"))
+				Expect(user.Username).To(Equal(original.Username))
+				Expect(must(testData.GetUser(1)).Name).To(Equal("Renamed User"))
+				Expect(must(testData.GetUser(1)).PasswordHash).To(Equal(original.PasswordHash))
+			})
+
+			It("rejects an unknown user", func() {
+				variables["id"] = -1
+				variables["input"] = map[string]interface{}{
+					"name": "Nobody",
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
+			})
+
+			DescribeTable("rejects an invalid email",
+				func(email string) {
+					original := must(testData.GetUser(1))
+					variables["id"] = 1
+					variables["input"] = map[string]interface{}{
+						"email": email,
+					}
+
+					r := graphql.Do(params)
+					Expect(r.Errors).To(HaveLen(1))
+					Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+					Expect(invalidFields(r.Errors[0])).To(Equal([]fieldError{
+						{Path: []string{"input", "email"}, Message: "is not a valid email address"},
+					}))
+					Expect(must(testData.GetUser(1)).Email).To(Equal(original.Email))
+				},
+				Entry("empty", ""),
+				Entry("malformed", "not an email"),
+			)
+
+			It("cannot change the password", func() {
+				original := must(testData.GetUser(1))
+				variables["id"] = 1
+				variables["input"] = map[string]interface{}{
+					"password": "NewPassword1",
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(r.Errors[0].Message).To(ContainSubstring(`In field "password": Unknown field.`))
+				Expect(must(testData.GetUser(1)).PasswordHash).To(Equal(original.PasswordHash))
+			})
+		})
+
+		When("deleteUser", func() {
+			BeforeEach(func() {
+				mutation = `
+					mutation ($id: Int!) {
+						deleteUser(id:$id) {
+							id
+							name
+						}
+					}`
+			})
+
+			It("returns the deleted user and removes their albums and photos", func() {
+				expected := must(testData.GetUser(1))
+				albums := must(testData.GetAlbumsByUserID(1))
+				variables["id"] = 1
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				user := getData[data.User](r, "deleteUser")
+
+				Expect(user.Name).To(Equal(expected.Name))
+				Expect(testData.GetUsers()).To(HaveLen(2))
+				Expect(testData.GetAlbumsByUserID(1)).To(BeEmpty())
+				for _, album := range albums {
+					Expect(testData.GetPhotosByAlbumID(album.ID)).To(BeEmpty())
+				}
+				Expect(testData.GetAlbums()).To(HaveLen(4))
+				Expect(testData.GetPhotos()).To(HaveLen(8))
+			})
+
+			It("rejects an unknown user", func() {
+				variables["id"] = -1
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
+			})
+		})
+	})
+
+	Context("Albums", func() {
+		When("createAlbum", func() {
+			BeforeEach(func() {
+				mutation = `
+					mutation ($input: CreateAlbumInput!) {
+						createAlbum(input:$input) {
+							id
+							userid
+							description
+						}
+					}`
+			})
+
+			It("creates the album", func() {
+				variables["input"] = map[string]interface{}{
+					"userid":      2,
+					"description": "New Album",
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				album := getData[data.Album](r, "createAlbum")
+
+				Expect(album).To(Equal(data.Album{ID: 6, UserID: 2, Description: "New Album"}))
+				Expect(testData.GetAlbumsByUserID(2)).To(ContainElement(album))
+			})
+
+			It("rejects an unknown user", func() {
+				variables["input"] = map[string]interface{}{
+					"userid": -1,
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
+			})
+		})
+
+		When("updateAlbum", func() {
+			BeforeEach(func() {
+				mutation = `
+					mutation ($id: Int!, $input: UpdateAlbumInput!) {
+						updateAlbum(id:$id, input:$input) {
+							id
+							userid
+							description
+						}
+					}`
+			})
+
+			It("moves the album to another user", func() {
+				original := must(testData.GetAlbum(0))
+				variables["id"] = 0
+				variables["input"] = map[string]interface{}{
+					"userid": 2,
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				album := getData[data.Album](r, "updateAlbum")
+
+				Expect(album).To(Equal(data.Album{ID: 0, UserID: 2, Description: original.Description}))
+				Expect(testData.GetAlbumsByUserID(0)).To(HaveLen(1))
+			})
+
+			It("rejects an unknown user", func() {
+				variables["id"] = 0
+				variables["input"] = map[string]interface{}{
+					"userid": -1,
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
+			})
+		})
+
+		When("deleteAlbum", func() {
+			BeforeEach(func() {
+				mutation = `
+					mutation ($id: Int!) {
+						deleteAlbum(id:$id) {
+							id
+							description
+						}
+					}`
+			})
+
+			It("returns the deleted album and removes its photos", func() {
+				expected := must(testData.GetAlbum(0))
+				variables["id"] = 0
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				album := getData[data.Album](r, "deleteAlbum")
+
+				Expect(album.Description).To(Equal(expected.Description))
+				Expect(testData.GetAlbums()).To(HaveLen(5))
+				Expect(testData.GetPhotosByAlbumID(0)).To(BeEmpty())
+				Expect(testData.GetPhotos()).To(HaveLen(10))
+			})
+		})
+	})
+
+	Context("Photos", func() {
+		When("createPhoto", func() {
+			BeforeEach(func() {
+				mutation = `
+					mutation ($input: CreatePhotoInput!) {
+						createPhoto(input:$input) {
+							id
+							albumid
+							description
+						}
+					}`
+			})
+
+			It("creates the photo", func() {
+				variables["input"] = map[string]interface{}{
+					"albumid":     3,
+					"description": "New Photo",
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				photo := getData[data.Photo](r, "createPhoto")
+
+				Expect(photo).To(Equal(data.Photo{ID: 12, AlbumID: 3, Description: "New Photo"}))
+				Expect(testData.GetPhotosByAlbumID(3)).To(ContainElement(photo))
+			})
+
+			It("rejects an unknown album", func() {
+				variables["input"] = map[string]interface{}{
+					"albumid": -1,
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
+			})
+		})
+
+		When("updatePhoto", func() {
+			BeforeEach(func() {
+				mutation = `
+					mutation ($id: Int!, $input: UpdatePhotoInput!) {
+						updatePhoto(id:$id, input:$input) {
+							id
+							albumid
+							description
+						}
+					}`
+			})
+
+			It("updates the description", func() {
+				variables["id"] = 5
+				variables["input"] = map[string]interface{}{
+					"description": "Updated Photo",
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				photo := getData[data.Photo](r, "updatePhoto")
+
+				Expect(photo).To(Equal(data.Photo{ID: 5, AlbumID: 2, Description: "Updated Photo"}))
+				Expect(must(testData.GetPhoto(5))).To(Equal(photo))
+			})
+
+			It("rejects an unknown photo", func() {
+				variables["id"] = -1
+				variables["input"] = map[string]interface{}{
+					"description": "Updated Photo",
+				}
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
+			})
+		})
+
+		When("deletePhoto", func() {
+			BeforeEach(func() {
+				mutation = `
+					mutation ($id: Int!) {
+						deletePhoto(id:$id) {
+							id
+							description
+						}
+					}`
+			})
+
+			It("returns the deleted photo", func() {
+				expected := must(testData.GetPhoto(5))
+				variables["id"] = 5
+
+				r := graphql.Do(params)
+				Expect(r.Errors).To(BeEmpty())
+
+				photo := getData[data.Photo](r, "deletePhoto")
+
+				Expect(photo.Description).To(Equal(expected.Description))
+				Expect(testData.GetPhotos()).To(HaveLen(11))
+			})
+		})
+	})
+})
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+	"golang.org/x/exp/constraints"
+)
+
+const (
+	ascending  = "ASC"
+	descending = "DESC"
+)
+
+var orderDirectionType = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "OrderDirection",
+	Description: "The direction to sort in.",
+	Values: graphql.EnumValueConfigMap{
+		ascending: &graphql.EnumValueConfig{
+			Value:       ascending,
+			Description: "From lowest to highest.",
+		},
+		descending: &graphql.EnumValueConfig{
+			Value:       descending,
+			Description: "From highest to lowest.",
+		},
+	},
+})
+
+// newOrderByType returns the input for sorting a type by one of its fields,
+// along with the <Type>OrderField enum of the fields.
+func newOrderByType(name string, fields graphql.EnumValueConfigMap) *graphql.InputObject {
+	fieldType := graphql.NewEnum(graphql.EnumConfig{
+		Name:        name + "OrderField",
+		Description: "The fields to sort " + name + " by.",
+		Values:      fields,
+	})
+
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        name + "OrderBy",
+		Description: "Sorts by a field of " + name + ".",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(fieldType),
+				Description: "The field to sort by.",
+			},
+			"direction": &graphql.InputObjectFieldConfig{
+				Type:         orderDirectionType,
+				DefaultValue: ascending,
+				Description:  "The direction to sort in.",
+			},
+		},
+	})
+}
+
+// orderByArg returns the orderBy argument for a list of items.
+func orderByArg(items string, orderBy *graphql.InputObject) *graphql.ArgumentConfig {
+	return &graphql.ArgumentConfig{
+		Description: "sort the " + items + " by each field in turn, otherwise they are sorted by id",
+		Type:        graphql.NewList(graphql.NewNonNull(orderBy)),
+	}
+}
+
+// orderField compares items by a field, in either direction.
+type orderField[T any] struct {
+	ascending  utils.Comparison[T]
+	descending utils.Comparison[T]
+}
+
+func newOrderField[T any, V constraints.Ordered](value func(item T) V) orderField[T] {
+	return orderField[T]{
+		ascending:  utils.Ascending(value),
+		descending: utils.Descending(value),
+	}
+}
+
+// orderItems returns the items sorted by the orderBy argument, or the items unchanged if it is not present.
+func orderItems[S []T, T any](items S, args map[string]interface{}, fields map[string]orderField[T]) S {
+	orderBy, ok := args["orderBy"].([]interface{})
+	if !ok || len(orderBy) == 0 {
+		return items
+	}
+
+	comparisons := utils.Transform(orderBy, func(arg interface{}) utils.Comparison[T] {
+		order := arg.(map[string]interface{})
+		field := fields[order["field"].(string)]
+
+		if order["direction"] == descending {
+			return field.descending
+		}
+		return field.ascending
+	})
+
+	return utils.OrderBy(items, comparisons...)
+}
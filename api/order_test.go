@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ordering", func() {
+	var testData data.IWritableData
+	var params graphql.Params
+
+	BeforeEach(func() {
+		config := data.DefaultGeneratorConfig()
+		config.Users = 4
+		config.Admins = 2
+		config.AlbumsPerUser = data.Range{Min: 2, Max: 2}
+		config.PhotosPerAlbum = data.Range{Min: 2, Max: 2}
+		testData, _ = NewTestDataWithConfig(config)
+
+		api := NewAPI(testData, NewAuthenticationProvider())
+		params = graphql.Params{
+			Schema:  api.Schema,
+			Context: WithIdentity(context.Background(), Identity{UserID: 0}),
+		}
+	})
+
+	ids := func(query string, key string) []int {
+		params.RequestString = query
+		r := graphql.Do(params)
+		Expect(r.Errors).To(BeEmpty())
+
+		items := getData[[]map[string]int](r, key)
+		return utils.Transform(items, func(item map[string]int) int { return item["id"] })
+	}
+
+	userIDs := func(users []data.User) []int {
+		return utils.Transform(users, func(user data.User) int { return user.ID })
+	}
+
+	albumIDs := func(albums []data.Album) []int {
+		return utils.Transform(albums, func(album data.Album) int { return album.ID })
+	}
+
+	photoIDs := func(photos []data.Photo) []int {
+		return utils.Transform(photos, func(photo data.Photo) int { return photo.ID })
+	}
+
+	It("sorts by id without an orderBy argument", func() {
+		Expect(ids(`{ users { id } }`, "users")).To(Equal([]int{0, 1, 2, 3}))
+	})
+
+	It("sorts by id with an empty orderBy argument", func() {
+		Expect(ids(`{ users(orderBy:[]) { id } }`, "users")).To(Equal([]int{0, 1, 2, 3}))
+	})
+
+	It("sorts ascending by default", func() {
+		expected := utils.OrderBy(must(testData.GetUsers()), utils.Ascending(func(user data.User) string { return user.Name }))
+
+		Expect(ids(`{ users(orderBy:[{field:NAME}]) { id } }`, "users")).To(Equal(userIDs(expected)))
+	})
+
+	DescribeTable("sorts users", func(orderBy string, comparisons ...utils.Comparison[data.User]) {
+		expected := utils.OrderBy(must(testData.GetUsers()), comparisons...)
+
+		Expect(ids(`{ users(orderBy:`+orderBy+`) { id } }`, "users")).To(Equal(userIDs(expected)))
+	},
+		Entry("by id descending", `[{field:ID, direction:DESC}]`,
+			utils.Descending(func(user data.User) int { return user.ID })),
+		Entry("by name", `[{field:NAME, direction:ASC}]`,
+			utils.Ascending(func(user data.User) string { return user.Name })),
+		Entry("by username descending", `[{field:USERNAME, direction:DESC}]`,
+			utils.Descending(func(user data.User) string { return user.Username })),
+		Entry("by role then id descending", `[{field:ROLE}, {field:ID, direction:DESC}]`,
+			utils.Ascending(func(user data.User) data.Role { return user.Role }),
+			utils.Descending(func(user data.User) int { return user.ID })),
+	)
+
+	It("sorts equal users by id", func() {
+		Expect(ids(`{ users(orderBy:[{field:ROLE, direction:DESC}]) { id } }`, "users")).To(Equal([]int{2, 3, 0, 1}))
+	})
+
+	DescribeTable("sorts albums", func(orderBy string, comparisons ...utils.Comparison[data.Album]) {
+		expected := utils.OrderBy(must(testData.GetAlbums()), comparisons...)
+
+		Expect(ids(`{ albums(orderBy:`+orderBy+`) { id } }`, "albums")).To(Equal(albumIDs(expected)))
+	},
+		Entry("by user descending", `[{field:USERID, direction:DESC}]`,
+			utils.Descending(func(album data.Album) int { return album.UserID })),
+		Entry("by description", `[{field:DESCRIPTION}]`,
+			utils.Ascending(func(album data.Album) string { return album.Description })),
+		Entry("by user descending then description", `[{field:USERID, direction:DESC}, {field:DESCRIPTION}]`,
+			utils.Descending(func(album data.Album) int { return album.UserID }),
+			utils.Ascending(func(album data.Album) string { return album.Description })),
+	)
+
+	DescribeTable("sorts photos", func(orderBy string, comparisons ...utils.Comparison[data.Photo]) {
+		expected := utils.OrderBy(must(testData.GetPhotos()), comparisons...)
+
+		Expect(ids(`{ photos(orderBy:`+orderBy+`) { id } }`, "photos")).To(Equal(photoIDs(expected)))
+	},
+		Entry("by album descending", `[{field:ALBUMID, direction:DESC}]`,
+			utils.Descending(func(photo data.Photo) int { return photo.AlbumID })),
+		Entry("by description descending", `[{field:DESCRIPTION, direction:DESC}]`,
+			utils.Descending(func(photo data.Photo) string { return photo.Description })),
+	)
+
+	It("filters, then sorts, then pages", func() {
+		Expect(ids(`{ albums(where:{userid:{in:[1, 2]}}, orderBy:[{field:ID, direction:DESC}], offset:1, limit:2) { id } }`, "albums")).To(Equal([]int{4, 3}))
+	})
+
+	It("sorts connections before paging", func() {
+		params.RequestString = `{ photosConnection(orderBy:[{field:ID, direction:DESC}], first:2) { totalCount edges { node { id } cursor } } }`
+		r := graphql.Do(params)
+		Expect(r.Errors).To(BeEmpty())
+
+		connection := getData[utils.Connection[data.Photo]](r, "photosConnection")
+		Expect(connection.TotalCount).To(Equal(16))
+		Expect(connection.Edges[0].Node.ID).To(Equal(15))
+		Expect(connection.Edges[1].Node.ID).To(Equal(14))
+
+		params.RequestString = `{ photosConnection(orderBy:[{field:ID, direction:DESC}], first:2, after:"` + connection.Edges[1].Cursor + `") { edges { node { id } } } }`
+		r = graphql.Do(params)
+		Expect(r.Errors).To(BeEmpty())
+
+		connection = getData[utils.Connection[data.Photo]](r, "photosConnection")
+		Expect(connection.Edges[0].Node.ID).To(Equal(13))
+		Expect(connection.Edges[1].Node.ID).To(Equal(12))
+	})
+
+	It("accepts orderBy as a variable", func() {
+		params.RequestString = `query ($orderBy: [UserOrderBy!]) { users(orderBy:$orderBy) { id } }`
+		params.VariableValues = map[string]interface{}{
+			"orderBy": []interface{}{map[string]interface{}{"field": "ID", "direction": "DESC"}},
+		}
+
+		Expect(ids(params.RequestString, "users")).To(Equal([]int{3, 2, 1, 0}))
+	})
+
+	It("cannot sort users by email", func() {
+		params.RequestString = `{ users(orderBy:[{field:EMAIL}]) { id } }`
+
+		r := graphql.Do(params)
+		Expect(r.Errors).To(HaveLen(1))
+	})
+})
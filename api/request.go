@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// request holds the state of a GraphQL request.
+type request struct {
+	dataModel data.IData
+
+	snapshotOnce sync.Once
+	snapshot     data.IData
+	// snapshotCloser closes the snapshot, if it needs closing.
+	snapshotCloser io.Closer
+
+	loaders loaders
+}
+
+// data returns the data that the queries of the request read. If the data model can take snapshots,
+// this is a snapshot taken the first time it is called, so the request sees the data as it was then,
+// whatever is written meanwhile.
+func (request *request) data() data.IData {
+	request.snapshotOnce.Do(func() {
+		request.snapshot = request.dataModel
+		if snapshotData, ok := request.dataModel.(data.ISnapshotData); ok {
+			request.snapshot = snapshotData.Snapshot()
+			request.snapshotCloser, _ = request.snapshot.(io.Closer)
+		}
+	})
+	return request.snapshot
+}
+
+// close closes the snapshot of the request, if one was taken and it is an io.Closer. A query whose context
+// is cancelled can still be running, so close waits for a snapshot being taken, and leaves it reading the
+// data model if none has been.
+func (request *request) close() {
+	request.snapshotOnce.Do(func() {
+		request.snapshot = request.dataModel
+	})
+	if request.snapshotCloser != nil {
+		request.snapshotCloser.Close()
+	}
+}
+
+type requestKey struct{}
+
+// requestExtension gives each GraphQL request its own state, so that nothing is shared between requests.
+type requestExtension struct {
+	dataModel data.IData
+}
+
+func (ext requestExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	request := &request{dataModel: ext.dataModel}
+	request.loaders = loaders{
+		albumsByUserID: newLoader(func(userIDs []int) (map[int][]data.Album, error) {
+			return request.data().GetAlbumsByUserIDs(userIDs)
+		}),
+		photosByAlbumID: newLoader(func(albumIDs []int) (map[int][]data.Photo, error) {
+			return request.data().GetPhotosByAlbumIDs(albumIDs)
+		}),
+	}
+
+	return context.WithValue(ctx, requestKey{}, request)
+}
+
+func (ext requestExtension) Name() string {
+	return "request"
+}
+
+func (ext requestExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (ext requestExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+// ExecutionDidStart closes the snapshot of the request once it has been executed, if it needs closing.
+func (ext requestExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {
+		if request, ok := ctx.Value(requestKey{}).(*request); ok {
+			request.close()
+		}
+	}
+}
+
+func (ext requestExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	return ctx, func(interface{}, error) {}
+}
+
+func (ext requestExtension) HasResult() bool {
+	return false
+}
+
+func (ext requestExtension) GetResult(ctx context.Context) interface{} {
+	return nil
+}
+
+// requestFor returns the state of the request that the field belongs to.
+func requestFor(p graphql.ResolveParams) (*request, bool) {
+	if p.Context == nil {
+		return nil, false
+	}
+	request, ok := p.Context.Value(requestKey{}).(*request)
+	return request, ok
+}
+
+// isMutation returns whether the field belongs to a mutation.
+func isMutation(p graphql.ResolveParams) bool {
+	operation, ok := p.Info.Operation.(*ast.OperationDefinition)
+	return ok && operation.Operation == ast.OperationTypeMutation
+}
+
+// dataFor returns the data that the field reads: the snapshot of the request for queries, so that every
+// field of a query sees the same data, and the data model itself for mutations, which see their own writes.
+func dataFor(p graphql.ResolveParams, dataModel data.IData) data.IData {
+	if isMutation(p) {
+		return dataModel
+	}
+	if request, ok := requestFor(p); ok {
+		return request.data()
+	}
+	return dataModel
+}
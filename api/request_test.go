@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// interleavingData runs write the first time the users are read from a snapshot,
+// as if another request had written the data while a query was running.
+type interleavingData struct {
+	*testData
+	write func()
+	once  sync.Once
+}
+
+func (d *interleavingData) Snapshot() data.IData {
+	return interleavingSnapshot{IData: d.testData.Snapshot(), data: d}
+}
+
+type interleavingSnapshot struct {
+	data.IData
+	data *interleavingData
+}
+
+func (snapshot interleavingSnapshot) GetUsers() ([]data.User, error) {
+	snapshot.data.once.Do(snapshot.data.write)
+	return snapshot.IData.GetUsers()
+}
+
+// closingData takes snapshots that count the times they are closed. It fails the test if it is closed itself.
+type closingData struct {
+	*testData
+	closed int
+}
+
+func (d *closingData) Snapshot() data.IData {
+	return closingSnapshot{IData: d.testData.Snapshot(), data: d}
+}
+
+func (d *closingData) Close() error {
+	Fail("the data model was closed")
+	return nil
+}
+
+type closingSnapshot struct {
+	data.IData
+	data *closingData
+}
+
+func (snapshot closingSnapshot) Close() error {
+	snapshot.data.closed++
+	return nil
+}
+
+var _ = Describe("Requests", func() {
+	var testData *testData
+	var schema graphql.Schema
+
+	BeforeEach(func() {
+		config := data.DefaultGeneratorConfig()
+		config.Users = 3
+		config.AlbumsPerUser = data.Range{Min: 2, Max: 2}
+		config.PhotosPerAlbum = data.Range{Min: 2, Max: 2}
+		dataset := must(data.Generate(config))
+		dataset.Passwords = nil
+		testData = must(newTestData(dataset, bcrypt.MinCost))
+	})
+
+	do := func(query string) *graphql.Result {
+		return graphql.Do(graphql.Params{
+			Schema:        schema,
+			RequestString: query,
+			Context:       WithIdentity(context.Background(), Identity{UserID: 0}),
+		})
+	}
+
+	Context("with writes while a query runs", func() {
+		BeforeEach(func() {
+			dataModel := &interleavingData{testData: testData, write: func() {
+				must(testData.CreateAlbum(data.Album{UserID: 0, Description: "New"}))
+				must(testData.DeleteUser(1))
+			}}
+			schema = NewAPI(dataModel, NewAuthenticationProvider()).Schema
+		})
+
+		It("reads every field of a query from the same snapshot", func() {
+			r := do(`{ users { id albums { id } } }`)
+			Expect(r.Errors).To(BeEmpty())
+
+			users := getData[[]data.User](r, "users")
+			Expect(users).To(HaveLen(3))
+			Expect(users[0].Albums).To(HaveLen(2))
+			Expect(users[1].Albums).To(HaveLen(2))
+
+			Expect(testData.GetAlbumsByUserID(0)).To(HaveLen(3))
+			Expect(testData.GetUsers()).To(HaveLen(2))
+		})
+
+		It("reads the data as written for the next query", func() {
+			do(`{ users { id } }`)
+
+			r := do(`{ users { id albums { id } } }`)
+			Expect(r.Errors).To(BeEmpty())
+
+			users := getData[[]data.User](r, "users")
+			Expect(users).To(HaveLen(2))
+			Expect(users[0].Albums).To(HaveLen(3))
+		})
+	})
+
+	Context("with the caller deleted while a query runs", func() {
+		BeforeEach(func() {
+			dataModel := &interleavingData{testData: testData, write: func() {
+				must(testData.DeleteUser(0))
+			}}
+			schema = NewAPI(dataModel, NewAuthenticationProvider()).Schema
+		})
+
+		It("authorizes the query against the same snapshot", func() {
+			r := do(`{ users { id email } }`)
+			Expect(r.Errors).To(BeEmpty())
+
+			users := getData[[]data.User](r, "users")
+			Expect(users).To(HaveLen(3))
+			Expect(users[0].Email).ToNot(BeEmpty())
+		})
+	})
+
+	Context("with snapshots that need closing", func() {
+		var dataModel *closingData
+
+		BeforeEach(func() {
+			dataModel = &closingData{testData: testData}
+			schema = NewAPI(dataModel, NewAuthenticationProvider()).Schema
+		})
+
+		It("closes the snapshot of a query once it has run", func() {
+			r := do(`{ users { id albums { id } } }`)
+			Expect(r.Errors).To(BeEmpty())
+
+			Expect(dataModel.closed).To(Equal(1))
+		})
+
+		It("does not close the data model when no snapshot was taken", func() {
+			r := do(`mutation { createAlbum(input:{userid:0, description:"New"}) { id } }`)
+			Expect(r.Errors).To(BeEmpty())
+
+			Expect(dataModel.closed).To(BeZero())
+		})
+	})
+
+	Context("with concurrent queries and mutations", func() {
+		BeforeEach(func() {
+			schema = NewAPI(testData, NewAuthenticationProvider()).Schema
+		})
+
+		It("gives each query a consistent view", func() {
+			var wg sync.WaitGroup
+
+			for i := 0; i < 20; i++ {
+				wg.Add(2)
+
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					r := do(fmt.Sprintf(`mutation {
+						createAlbum(input:{userid:%d, description:"New"}) { id }
+						updateAlbum(id:%d, input:{userid:%d}) { id }
+					}`, i%3, i%6, (i+1)%3))
+					Expect(r.Errors).To(BeEmpty())
+				}(i)
+
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					r := do(`{ albums { id } users { albums { id } } }`)
+					Expect(r.Errors).To(BeEmpty())
+
+					albums := getData[[]data.Album](r, "albums")
+					users := getData[[]data.User](r, "users")
+
+					var albumsOfUsers []data.Album
+					for _, user := range users {
+						albumsOfUsers = append(albumsOfUsers, user.Albums...)
+					}
+					Expect(utils.Transform(albumsOfUsers, func(album data.Album) int { return album.ID })).
+						To(ConsistOf(utils.Transform(albums, func(album data.Album) int { return album.ID })))
+				}()
+			}
+
+			wg.Wait()
+
+			Expect(testData.GetAlbums()).To(HaveLen(26))
+		})
+	})
+})
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+)
+
+var errInvalidRefreshToken = apierrors.New(apierrors.Unauthenticated, "invalid refresh token")
+
+type refreshSession struct {
+	userID    int
+	expiresAt time.Time
+}
+
+// revocationStore keeps the outstanding refresh tokens and the IDs of revoked access tokens in memory.
+// Entries are forgotten once they expire, as an expired token is rejected anyway.
+type revocationStore struct {
+	mu            sync.Mutex
+	refreshTokens map[string]refreshSession
+	revoked       map[string]time.Time
+	// expiries orders the entries by when they expire, so that pruning only visits the expired ones.
+	expiries expiryHeap
+}
+
+func newRevocationStore() *revocationStore {
+	return &revocationStore{
+		refreshTokens: make(map[string]refreshSession),
+		revoked:       make(map[string]time.Time),
+	}
+}
+
+// addRefreshToken records a refresh token issued to the user.
+func (store *revocationStore) addRefreshToken(token string, userID int, expiresAt time.Time, now time.Time) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.prune(now)
+	store.refreshTokens[token] = refreshSession{userID: userID, expiresAt: expiresAt}
+	heap.Push(&store.expiries, expiry{key: token, refresh: true, expiresAt: expiresAt})
+}
+
+// useRefreshToken removes the refresh token, so it can only be used once, and returns the user it was issued to.
+func (store *revocationStore) useRefreshToken(token string, now time.Time) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	session, ok := store.refreshTokens[token]
+	if !ok || !now.Before(session.expiresAt) {
+		return 0, errInvalidRefreshToken
+	}
+
+	delete(store.refreshTokens, token)
+	return session.userID, nil
+}
+
+// revoke rejects the access token with the ID until it expires.
+func (store *revocationStore) revoke(tokenID string, expiresAt time.Time, now time.Time) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.prune(now)
+	store.revoked[tokenID] = expiresAt
+	heap.Push(&store.expiries, expiry{key: tokenID, expiresAt: expiresAt})
+}
+
+func (store *revocationStore) isRevoked(tokenID string) bool {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	_, ok := store.revoked[tokenID]
+	return ok
+}
+
+// prune forgets the entries that have expired. An entry is only forgotten if it has not been replaced
+// by one that expires later, and entries that are already gone, such as used refresh tokens, are skipped.
+func (store *revocationStore) prune(now time.Time) {
+	for len(store.expiries) > 0 && !now.Before(store.expiries[0].expiresAt) {
+		entry := heap.Pop(&store.expiries).(expiry)
+
+		if entry.refresh {
+			if session, ok := store.refreshTokens[entry.key]; ok && !now.Before(session.expiresAt) {
+				delete(store.refreshTokens, entry.key)
+			}
+		} else if expiresAt, ok := store.revoked[entry.key]; ok && !now.Before(expiresAt) {
+			delete(store.revoked, entry.key)
+		}
+	}
+}
+
+// expiry is when a refresh token, or the revocation of an access token, expires.
+type expiry struct {
+	key       string
+	refresh   bool
+	expiresAt time.Time
+}
+
+// expiryHeap is a heap.Interface of expiries, which expire soonest first.
+type expiryHeap []expiry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap) Push(x interface{}) {
+	*h = append(*h, x.(expiry))
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
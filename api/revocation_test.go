@@ -0,0 +1,72 @@
+package api
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Revocation store", func() {
+	var store *revocationStore
+	var now time.Time
+
+	BeforeEach(func() {
+		store = newRevocationStore()
+		now = time.Now()
+	})
+
+	It("forgets revoked tokens once they expire", func() {
+		store.revoke("expired", now.Add(time.Minute), now)
+		store.revoke("current", now.Add(time.Hour), now)
+
+		store.revoke("new", now.Add(time.Hour), now.Add(2*time.Minute))
+
+		Expect(store.revoked).To(HaveLen(2))
+		Expect(store.isRevoked("expired")).To(BeFalse())
+		Expect(store.isRevoked("current")).To(BeTrue())
+	})
+
+	It("forgets refresh tokens once they expire", func() {
+		store.addRefreshToken("expired", 1, now.Add(time.Minute), now)
+		store.addRefreshToken("current", 2, now.Add(time.Hour), now)
+
+		store.addRefreshToken("new", 3, now.Add(time.Hour), now.Add(2*time.Minute))
+
+		Expect(store.refreshTokens).To(HaveLen(2))
+		Expect(store.refreshTokens).ToNot(HaveKey("expired"))
+	})
+
+	It("only visits the entries that have expired", func() {
+		store.revoke("expired", now.Add(time.Minute), now)
+		store.addRefreshToken("used", 1, now.Add(time.Minute), now)
+		store.addRefreshToken("current", 2, now.Add(time.Hour), now)
+		_, err := store.useRefreshToken("used", now)
+		Expect(err).To(BeNil())
+
+		store.revoke("new", now.Add(time.Hour), now.Add(2*time.Minute))
+
+		Expect(store.expiries).To(HaveLen(2))
+		Expect(store.revoked).To(HaveLen(1))
+		Expect(store.refreshTokens).To(HaveLen(1))
+	})
+
+	It("keeps a revoked token that was revoked again until it expires", func() {
+		store.revoke("token", now.Add(time.Minute), now)
+		store.revoke("token", now.Add(time.Hour), now)
+
+		store.revoke("new", now.Add(time.Hour), now.Add(2*time.Minute))
+
+		Expect(store.isRevoked("token")).To(BeTrue())
+	})
+
+	It("returns the user of a refresh token", func() {
+		store.addRefreshToken("token", 4, now.Add(time.Hour), now)
+
+		userID, err := store.useRefreshToken("token", now)
+
+		Expect(err).To(BeNil())
+		Expect(userID).To(Equal(4))
+		Expect(store.refreshTokens).To(BeEmpty())
+	})
+})
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// builtInScalars are defined by every GraphQL server, so they are not printed.
+var builtInScalars = []string{"Boolean", "Float", "ID", "Int", "String"}
+
+// PrintSchema returns the schema in the GraphQL schema definition language, with its types and fields in
+// order of their names so that the output only changes when the schema does.
+func PrintSchema(schema graphql.Schema) string {
+	var definitions []string
+
+	if definition := printSchemaDefinition(schema); definition != "" {
+		definitions = append(definitions, definition)
+	}
+
+	typeMap := schema.TypeMap()
+	names := maps.Keys(typeMap)
+	sort.Strings(names)
+
+	for _, name := range names {
+		if strings.HasPrefix(name, "__") || slices.Contains(builtInScalars, name) {
+			continue
+		}
+		definitions = append(definitions, printType(typeMap[name]))
+	}
+
+	return strings.Join(definitions, "\n\n") + "\n"
+}
+
+// printSchemaDefinition returns the schema definition, or nothing if the root types have their usual names.
+func printSchemaDefinition(schema graphql.Schema) string {
+	roots := []struct {
+		operation string
+		root      *graphql.Object
+	}{
+		{"query", schema.QueryType()},
+		{"mutation", schema.MutationType()},
+		{"subscription", schema.SubscriptionType()},
+	}
+
+	usual := true
+	var fields []string
+	for _, root := range roots {
+		if root.root == nil {
+			continue
+		}
+		usual = usual && root.root.Name() == strings.ToUpper(root.operation[:1])+root.operation[1:]
+		fields = append(fields, fmt.Sprintf("  %s: %s", root.operation, root.root.Name()))
+	}
+
+	if usual {
+		return ""
+	}
+	return "schema {\n" + strings.Join(fields, "\n") + "\n}"
+}
+
+func printType(t graphql.Type) string {
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		return printDescription(t.Description(), "") + "scalar " + t.Name()
+
+	case *graphql.Object:
+		declaration := "type " + t.Name()
+		if len(t.Interfaces()) > 0 {
+			names := make([]string, len(t.Interfaces()))
+			for i, implemented := range t.Interfaces() {
+				names[i] = implemented.Name()
+			}
+			declaration += " implements " + strings.Join(names, " & ")
+		}
+		// Object.Description always returns nothing, so the description is read from the object.
+		return printDescription(t.PrivateDescription, "") + declaration + printFields(t.Fields())
+
+	case *graphql.Interface:
+		return printDescription(t.Description(), "") + "interface " + t.Name() + printFields(t.Fields())
+
+	case *graphql.Union:
+		names := make([]string, len(t.Types()))
+		for i, member := range t.Types() {
+			names[i] = member.Name()
+		}
+		return printDescription(t.Description(), "") + "union " + t.Name() + " = " + strings.Join(names, " | ")
+
+	case *graphql.Enum:
+		values := slices.Clone(t.Values())
+		sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+
+		var lines []string
+		for _, value := range values {
+			lines = append(lines, printDescription(value.Description, "  ")+"  "+value.Name+printDeprecation(value.DeprecationReason))
+		}
+		return printDescription(t.Description(), "") + "enum " + t.Name() + " {\n" + strings.Join(lines, "\n") + "\n}"
+
+	case *graphql.InputObject:
+		fields := t.Fields()
+		names := maps.Keys(fields)
+		sort.Strings(names)
+
+		var lines []string
+		for _, name := range names {
+			field := fields[name]
+			lines = append(lines, printDescription(field.Description(), "  ")+"  "+name+": "+field.Type.String()+printDefault(field.Type, field.DefaultValue))
+		}
+		return printDescription(t.Description(), "") + "input " + t.Name() + " {\n" + strings.Join(lines, "\n") + "\n}"
+	}
+
+	return fmt.Sprintf("# %s cannot be printed", t.Name())
+}
+
+func printFields(fields graphql.FieldDefinitionMap) string {
+	names := maps.Keys(fields)
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		field := fields[name]
+		lines = append(lines, printDescription(field.Description, "  ")+"  "+name+printArgs(field.Args)+": "+field.Type.String()+printDeprecation(field.DeprecationReason))
+	}
+	return " {\n" + strings.Join(lines, "\n") + "\n}"
+}
+
+// printArgs returns the arguments of a field on one line, or on a line each if any of them have a description.
+func printArgs(args []*graphql.Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	args = slices.Clone(args)
+	sort.Slice(args, func(i, j int) bool { return args[i].Name() < args[j].Name() })
+
+	described := false
+	printed := make([]string, len(args))
+	for i, arg := range args {
+		printed[i] = arg.Name() + ": " + arg.Type.String() + printDefault(arg.Type, arg.DefaultValue)
+		described = described || arg.Description() != ""
+	}
+
+	if !described {
+		return "(" + strings.Join(printed, ", ") + ")"
+	}
+
+	var lines []string
+	for i, arg := range args {
+		lines = append(lines, printDescription(arg.Description(), "    ")+"    "+printed[i])
+	}
+	return "(\n" + strings.Join(lines, "\n") + "\n  )"
+}
+
+func printDefault(t graphql.Input, value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return " = " + printValue(t, value)
+}
+
+// printValue returns the GraphQL literal of an internal value of the type.
+func printValue(t graphql.Input, value interface{}) string {
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return printValue(t.OfType.(graphql.Input), value)
+
+	case *graphql.List:
+		items := reflect.ValueOf(value)
+		if items.Kind() != reflect.Slice {
+			return printValue(t.OfType.(graphql.Input), value)
+		}
+		printed := make([]string, items.Len())
+		for i := range printed {
+			printed[i] = printValue(t.OfType.(graphql.Input), items.Index(i).Interface())
+		}
+		return "[" + strings.Join(printed, ", ") + "]"
+
+	case *graphql.Enum:
+		return fmt.Sprint(t.Serialize(value))
+
+	case *graphql.InputObject:
+		fields, _ := value.(map[string]interface{})
+		names := maps.Keys(fields)
+		sort.Strings(names)
+
+		printed := make([]string, len(names))
+		for i, name := range names {
+			printed[i] = name + ": " + printValue(t.Fields()[name].Type, fields[name])
+		}
+		return "{" + strings.Join(printed, ", ") + "}"
+	}
+
+	return printLiteral(value)
+}
+
+// printLiteral returns a string, number or boolean as a GraphQL literal, which is written as in JSON.
+func printLiteral(value interface{}) string {
+	var literal strings.Builder
+	encoder := json.NewEncoder(&literal)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(literal.String(), "\n")
+}
+
+func printDeprecation(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return " @deprecated(reason: " + printLiteral(reason) + ")"
+}
+
+// printDescription returns the description as a string, or a block string if it has more than one line,
+// on the lines before a definition with the indent.
+func printDescription(description, indent string) string {
+	if description == "" {
+		return ""
+	}
+
+	if !strings.Contains(description, "\n") {
+		return indent + printLiteral(description) + "\n"
+	}
+
+	lines := strings.Split(strings.ReplaceAll(description, `"""`, `\"""`), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return indent + `"""` + "\n" + strings.Join(lines, "\n") + "\n" + indent + `"""` + "\n"
+}
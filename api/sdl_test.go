@@ -0,0 +1,126 @@
+package api
+
+import (
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = Describe("PrintSchema", func() {
+	It("prints every kind of type in order of their names", func() {
+		colour := graphql.NewEnum(graphql.EnumConfig{
+			Name: "Colour",
+			Values: graphql.EnumValueConfigMap{
+				"RED":  &graphql.EnumValueConfig{Value: 1, Description: "Like <fire> & \"blood\"."},
+				"BLUE": &graphql.EnumValueConfig{Value: 2, DeprecationReason: "Use RED."},
+			},
+		})
+		named := graphql.NewInterface(graphql.InterfaceConfig{
+			Name:   "Named",
+			Fields: graphql.Fields{"name": &graphql.Field{Type: graphql.String}},
+		})
+		pen := graphql.NewObject(graphql.ObjectConfig{
+			Name:        "Pen",
+			Description: "A pen.\nIt writes.",
+			Interfaces:  []*graphql.Interface{named},
+			Fields: graphql.Fields{
+				"name":   &graphql.Field{Type: graphql.String},
+				"colour": &graphql.Field{Type: graphql.NewNonNull(colour)},
+			},
+			IsTypeOf: func(p graphql.IsTypeOfParams) bool { return true },
+		})
+		filter := graphql.NewInputObject(graphql.InputObjectConfig{
+			Name: "PenFilter",
+			Fields: graphql.InputObjectConfigFieldMap{
+				"colours": &graphql.InputObjectFieldConfig{Type: graphql.NewList(colour), DefaultValue: []interface{}{1, 2}},
+				"name":    &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "The name."},
+			},
+		})
+		schema, err := graphql.NewSchema(graphql.SchemaConfig{
+			Query: graphql.NewObject(graphql.ObjectConfig{
+				Name: "Root",
+				Fields: graphql.Fields{
+					"pens": &graphql.Field{
+						Type: graphql.NewList(pen),
+						Args: graphql.FieldConfigArgument{
+							"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10},
+							"filter": &graphql.ArgumentConfig{Type: filter},
+						},
+					},
+					"thing": &graphql.Field{
+						Type: graphql.NewUnion(graphql.UnionConfig{
+							Name:        "Thing",
+							Types:       []*graphql.Object{pen},
+							ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object { return pen },
+						}),
+						Args: graphql.FieldConfigArgument{
+							"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID), Description: "The id."},
+						},
+						DeprecationReason: "Use pens.",
+					},
+				},
+			}),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(PrintSchema(schema)).To(Equal(`schema {
+  query: Root
+}
+
+enum Colour {
+  BLUE @deprecated(reason: "Use RED.")
+  "Like <fire> & \"blood\"."
+  RED
+}
+
+interface Named {
+  name: String
+}
+
+"""
+A pen.
+It writes.
+"""
+type Pen implements Named {
+  colour: Colour!
+  name: String
+}
+
+input PenFilter {
+  colours: [Colour] = [RED, BLUE]
+  "The name."
+  name: String
+}
+
+type Root {
+  pens(filter: PenFilter, limit: Int = 10): [Pen]
+  thing(
+    "The id."
+    id: ID!
+  ): Thing @deprecated(reason: "Use pens.")
+}
+
+union Thing = Pen
+`))
+	})
+
+	It("prints the API schema so that it can be parsed", func() {
+		testData, err := NewTestDataWithDataset(data.Dataset{}, bcrypt.MinCost)
+		Expect(err).ToNot(HaveOccurred())
+		schema := NewAPI(testData, NewAuthenticationProvider()).Schema
+
+		printed := PrintSchema(schema)
+
+		_, err = parser.Parse(parser.ParseParams{Source: printed})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(printed).ToNot(ContainSubstring("schema {"))
+		Expect(printed).To(ContainSubstring("\ntype Query {\n"))
+		Expect(printed).To(ContainSubstring("\ntype Mutation {\n"))
+		Expect(printed).To(ContainSubstring("\nscalar DateTime\n"))
+		Expect(printed).ToNot(ContainSubstring("__Schema"))
+		Expect(PrintSchema(schema)).To(Equal(printed))
+	})
+})
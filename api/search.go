@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"github.com/graphql-go/graphql"
+)
+
+var searchableType = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "SearchableType",
+	Description: "A type of item that can be searched for.",
+	Values: graphql.EnumValueConfigMap{
+		"USER": &graphql.EnumValueConfig{
+			Value:       data.SearchUser,
+			Description: "Users, by name and username.",
+		},
+		"ALBUM": &graphql.EnumValueConfig{
+			Value:       data.SearchAlbum,
+			Description: "Albums, by description.",
+		},
+		"PHOTO": &graphql.EnumValueConfig{
+			Value:       data.SearchPhoto,
+			Description: "Photos, by description.",
+		},
+	},
+})
+
+// newSearchField returns the query for searching users, albums and photos.
+func newSearchField(dataModel data.IData, userType, albumType, photoType *graphql.Object) *graphql.Field {
+	searchResultType := graphql.NewUnion(graphql.UnionConfig{
+		Name:        "SearchResult",
+		Description: "An item matching a search.",
+		Types:       []*graphql.Object{userType, albumType, photoType},
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			switch p.Value.(type) {
+			case data.User:
+				return userType
+			case data.Album:
+				return albumType
+			case data.Photo:
+				return photoType
+			}
+			return nil
+		},
+	})
+
+	return &graphql.Field{
+		Type:        graphql.NewList(searchResultType),
+		Description: "Users, albums and photos matching the text, most relevant first",
+		Args: pagingArgs("results", graphql.FieldConfigArgument{
+			"text": &graphql.ArgumentConfig{
+				Description: "words to search for, matched case insensitively",
+				Type:        graphql.NewNonNull(graphql.String),
+			},
+			"types": &graphql.ArgumentConfig{
+				Description: "types of item to search, otherwise every type is searched",
+				Type:        graphql.NewList(graphql.NewNonNull(searchableType)),
+			},
+		}),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			var types []data.SearchType
+			if args, ok := p.Args["types"].([]interface{}); ok {
+				types = utils.Transform(args, func(arg interface{}) data.SearchType { return arg.(data.SearchType) })
+			}
+
+			snapshot := dataFor(p, dataModel)
+
+			results, err := snapshot.Search(p.Args["text"].(string), types)
+			if err != nil {
+				return nil, dataError(err)
+			}
+
+			results, err = utils.TryPageIfPresent(results, p.Args)
+			if err != nil {
+				return nil, err
+			}
+
+			items := make([]interface{}, 0, len(results))
+			for _, result := range results {
+				item, err := searchItem(snapshot, result)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+
+			return items, nil
+		},
+	}
+}
+
+func searchItem(dataModel data.IData, result data.SearchResult) (interface{}, error) {
+	switch result.Type {
+	case data.SearchUser:
+		return dataModel.GetUser(result.ID)
+	case data.SearchAlbum:
+		return dataModel.GetAlbum(result.ID)
+	case data.SearchPhoto:
+		return dataModel.GetPhoto(result.ID)
+	}
+	return nil, fmt.Errorf("unknown search type %q", result.Type)
+}
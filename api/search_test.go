@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Search", func() {
+	type searchResult struct {
+		Typename    string `json:"__typename"`
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	var testData data.IWritableData
+	var params graphql.Params
+
+	BeforeEach(func() {
+		config := data.DefaultGeneratorConfig()
+		config.Users = 2
+		config.AlbumsPerUser = data.Range{Min: 2, Max: 2}
+		config.PhotosPerAlbum = data.Range{Min: 2, Max: 2}
+		testData, _ = NewTestDataWithConfig(config)
+
+		user, _ := testData.CreateUser(data.User{Name: "Ada Wombat", Username: "ada", Email: "ada@example.com"})
+		album, _ := testData.CreateAlbum(data.Album{UserID: user.ID, Description: "Wombat Lights"})
+		testData.CreatePhoto(data.Photo{AlbumID: album.ID, Description: "The wombat at dusk, seen from the wombat wall."})
+
+		api := NewAPI(testData, NewAuthenticationProvider())
+		params = graphql.Params{
+			Schema:  api.Schema,
+			Context: context.Background(),
+		}
+	})
+
+	search := func(query string) []searchResult {
+		params.RequestString = query
+		r := graphql.Do(params)
+		Expect(r.Errors).To(BeEmpty())
+
+		return getData[[]searchResult](r, "search")
+	}
+
+	It("returns users, albums and photos", func() {
+		results := search(`{
+			search(text:"wombat") {
+				__typename
+				... on User { id name }
+				... on Album { id description }
+				... on Photo { id description }
+			}
+		}`)
+
+		Expect(results).To(ConsistOf(
+			searchResult{Typename: "User", ID: 2, Name: "Ada Wombat"},
+			searchResult{Typename: "Album", ID: 4, Description: "Wombat Lights"},
+			searchResult{Typename: "Photo", ID: 8, Description: "The wombat at dusk, seen from the wombat wall."},
+		))
+	})
+
+	It("returns the most relevant results first", func() {
+		results := search(`{ search(text:"WOMBAT dusk") { __typename ... on Photo { id } } }`)
+
+		Expect(results[0]).To(Equal(searchResult{Typename: "Photo", ID: 8}))
+	})
+
+	It("only returns the given types", func() {
+		results := search(`{ search(text:"wombat", types:[ALBUM, PHOTO]) { __typename } }`)
+
+		Expect(results).To(ConsistOf(
+			searchResult{Typename: "Album"},
+			searchResult{Typename: "Photo"},
+		))
+	})
+
+	It("pages the results", func() {
+		results := search(`{ search(text:"wombat", offset:1, limit:1) { __typename } }`)
+
+		Expect(results).To(HaveLen(1))
+	})
+
+	It("returns nothing if no item matches", func() {
+		Expect(search(`{ search(text:"zzzz") { __typename } }`)).To(BeEmpty())
+	})
+
+	It("keeps up with changes to the data", func() {
+		testData.UpdateAlbum(data.Album{ID: 4, UserID: 2, Description: "Beach Huts"})
+		testData.DeletePhoto(8)
+		testData.CreateAlbum(data.Album{UserID: 0, Description: "Wombat Master"})
+
+		results := search(`{ search(text:"wombat") { __typename ... on Album { id } } }`)
+
+		Expect(results).To(ConsistOf(
+			searchResult{Typename: "User"},
+			searchResult{Typename: "Album", ID: 5},
+		))
+	})
+
+	It("does not return deleted users' albums and photos", func() {
+		testData.DeleteUser(2)
+
+		Expect(search(`{ search(text:"wombat") { __typename } }`)).To(BeEmpty())
+	})
+
+	It("does not search by email", func() {
+		Expect(search(`{ search(text:"example") { __typename } }`)).To(BeEmpty())
+	})
+})
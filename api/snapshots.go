@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+)
+
+// AdminPath is where AdminHandler expects to be served, with the routes:
+//
+//	GET    /admin/snapshots                list the names of the saved snapshots
+//	PUT    /admin/snapshots/{name}         save the data as a snapshot
+//	POST   /admin/snapshots/{name}/restore restore a snapshot
+//	DELETE /admin/snapshots/{name}         delete a snapshot
+//	POST   /admin/reset                    reset the data to how it was created
+const AdminPath = "/admin/"
+
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// newSnapshotQueryField returns the query listing the saved snapshots, which only administrators may use.
+func newSnapshotQueryField(dataModel data.IRestorableData, policy authorizationPolicy) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+		Description: "Names of the saved snapshots of the data. Only for administrators",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if err := policy.of(p).authorizeAdmin(p.Context); err != nil {
+				return nil, err
+			}
+			return dataModel.Snapshots(), nil
+		},
+	}
+}
+
+// newSnapshotMutationFields returns the mutations for saving and restoring snapshots of the data,
+// so that tests which change the data can be isolated from each other. Only administrators may use them.
+func newSnapshotMutationFields(dataModel data.IRestorableData, policy authorizationPolicy) graphql.Fields {
+	nameArgs := graphql.FieldConfigArgument{
+		"name": &graphql.ArgumentConfig{
+			Description: "name of the snapshot, of letters, digits, '.', '_' and '-'",
+			Type:        graphql.NewNonNull(graphql.String),
+		},
+	}
+
+	snapshotField := func(description string, change func(name string) error) *graphql.Field {
+		return &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: description,
+			Args:        nameArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizeAdmin(p.Context); err != nil {
+					return nil, err
+				}
+
+				name := p.Args["name"].(string)
+
+				var errs validationError
+				validateSnapshotName(&errs, name, "name")
+				if err := errs.err(); err != nil {
+					return nil, err
+				}
+
+				if err := change(name); err != nil {
+					return nil, dataError(err)
+				}
+				return true, nil
+			},
+		}
+	}
+
+	return graphql.Fields{
+		"saveSnapshot":    snapshotField("Save the data as a snapshot, replacing any snapshot with the same name", dataModel.SaveSnapshot),
+		"restoreSnapshot": snapshotField("Restore the data saved in a snapshot", dataModel.RestoreSnapshot),
+		"deleteSnapshot":  snapshotField("Delete a snapshot", dataModel.DeleteSnapshot),
+		"resetData": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "Reset the data to how it was when the server started. Snapshots are kept",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if err := policy.of(p).authorizeAdmin(p.Context); err != nil {
+					return nil, err
+				}
+				if err := dataModel.Reset(); err != nil {
+					return nil, dataError(err)
+				}
+				return true, nil
+			},
+		},
+	}
+}
+
+func validateSnapshotName(errs *validationError, name string, path ...string) {
+	if !snapshotNamePattern.MatchString(name) {
+		errs.add("must be 1 to 64 letters, digits, '.', '_' or '-'", path...)
+	}
+}
+
+// AdminHandler serves the snapshot and reset endpoints described at AdminPath, for administrators only.
+// The caller is expected to have been added to the request context by AuthenticationMiddleware.
+//
+// Successful changes respond with 204 No Content. Errors respond with their code and message as JSON.
+func AdminHandler(dataModel data.IRestorableData) http.Handler {
+	policy := authorizationPolicy{dataModel: dataModel}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := policy.authorizeAdmin(r.Context()); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		route := strings.Split(strings.TrimPrefix(r.URL.Path, AdminPath), "/")
+
+		switch {
+		case len(route) == 1 && route[0] == "reset":
+			if !allowMethods(w, r, http.MethodPost) {
+				return
+			}
+			respond(w, dataModel.Reset())
+
+		case len(route) == 1 && route[0] == "snapshots":
+			if !allowMethods(w, r, http.MethodGet, http.MethodHead) {
+				return
+			}
+			writeJSON(w, r, map[string][]string{"snapshots": dataModel.Snapshots()})
+
+		case len(route) == 2 && route[0] == "snapshots":
+			switch r.Method {
+			case http.MethodPut:
+				respond(w, snapshotChange(route[1], dataModel.SaveSnapshot))
+			case http.MethodDelete:
+				respond(w, snapshotChange(route[1], dataModel.DeleteSnapshot))
+			default:
+				allowMethods(w, r, http.MethodPut, http.MethodDelete)
+			}
+
+		case len(route) == 3 && route[0] == "snapshots" && route[2] == "restore":
+			if !allowMethods(w, r, http.MethodPost) {
+				return
+			}
+			respond(w, snapshotChange(route[1], dataModel.RestoreSnapshot))
+
+		default:
+			writeError(w, apierrors.New(apierrors.NotFound, "not found"))
+		}
+	})
+}
+
+// snapshotChange validates the name of the snapshot before changing it.
+func snapshotChange(name string, change func(name string) error) error {
+	var errs validationError
+	validateSnapshotName(&errs, name, "name")
+	if err := errs.err(); err != nil {
+		return err
+	}
+	return dataError(change(name))
+}
+
+// allowMethods responds with 405 Method Not Allowed, and returns false, if the request has none of the methods.
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+// respond responds with 204 No Content, or with the error.
+func respond(w http.ResponseWriter, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var statusOfCode = map[apierrors.Code]int{
+	apierrors.Unauthenticated: http.StatusUnauthorized,
+	apierrors.Forbidden:       http.StatusForbidden,
+	apierrors.NotFound:        http.StatusNotFound,
+	apierrors.BadUserInput:    http.StatusBadRequest,
+}
+
+// writeError responds with the status of the error's code, and its code and message as JSON.
+// The message of internal errors is not given, as it may reveal details of the server.
+func writeError(w http.ResponseWriter, err error) {
+	code := apierrors.CodeOf(err)
+	status, ok := statusOfCode[code]
+	message := err.Error()
+	if !ok {
+		status = http.StatusInternalServerError
+		message = "internal error"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": string(code), "message": message})
+}
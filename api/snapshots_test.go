@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/graphql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = Describe("Snapshots", func() {
+	const (
+		admin = 0
+		user  = 1
+	)
+
+	var testData data.IRestorableData
+
+	BeforeEach(func() {
+		config := data.DefaultGeneratorConfig()
+		config.Users = 3
+		config.AlbumsPerUser = data.Range{Min: 2, Max: 2}
+		config.PhotosPerAlbum = data.Range{Min: 2, Max: 2}
+		writable, err := NewTestDataWithConfig(config)
+		Expect(err).ToNot(HaveOccurred())
+		testData = writable.(data.IRestorableData)
+	})
+
+	Context("mutations", func() {
+		var schema graphql.Schema
+
+		BeforeEach(func() {
+			schema = NewAPI(testData, NewAuthenticationProvider()).Schema
+		})
+
+		// Runs the request as the user, or anonymously if the user is negative.
+		do := func(userID int, request string) *graphql.Result {
+			ctx := context.Background()
+			if userID >= 0 {
+				ctx = WithIdentity(ctx, Identity{UserID: userID})
+			}
+			return graphql.Do(graphql.Params{Schema: schema, RequestString: request, Context: ctx})
+		}
+
+		It("save and restore snapshots", func() {
+			r := do(admin, `mutation { saveSnapshot(name: "clean") }`)
+			Expect(r.Errors).To(BeEmpty())
+			Expect(getData[bool](r, "saveSnapshot")).To(BeTrue())
+
+			Expect(do(admin, `mutation { deletePhoto(id: 0) { id } }`).Errors).To(BeEmpty())
+			Expect(do(admin, `{ snapshots }`).Data).To(Equal(map[string]interface{}{"snapshots": []interface{}{"clean"}}))
+
+			r = do(admin, `mutation { restoreSnapshot(name: "clean") }`)
+			Expect(r.Errors).To(BeEmpty())
+			Expect(testData.GetPhoto(0)).To(HaveField("ID", 0))
+
+			r = do(admin, `mutation { deleteSnapshot(name: "clean") }`)
+			Expect(r.Errors).To(BeEmpty())
+			Expect(testData.Snapshots()).To(BeEmpty())
+		})
+
+		It("reset the data", func() {
+			Expect(do(admin, `mutation { deleteUser(id: 2) { id } }`).Errors).To(BeEmpty())
+
+			r := do(admin, `mutation { resetData }`)
+			Expect(r.Errors).To(BeEmpty())
+			Expect(testData.GetUsers()).To(HaveLen(3))
+		})
+
+		It("are only for administrators", func() {
+			for _, request := range []string{
+				`{ snapshots }`,
+				`mutation { saveSnapshot(name: "clean") }`,
+				`mutation { restoreSnapshot(name: "clean") }`,
+				`mutation { deleteSnapshot(name: "clean") }`,
+				`mutation { resetData }`,
+			} {
+				r := do(user, request)
+				Expect(r.Errors).To(HaveLen(1), request)
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Forbidden), request)
+
+				r = do(-1, request)
+				Expect(r.Errors).To(HaveLen(1), request)
+				Expect(errorCode(r.Errors[0])).To(Equal(apierrors.Unauthenticated), request)
+			}
+			Expect(testData.Snapshots()).To(BeEmpty())
+		})
+
+		It("reject invalid names", func() {
+			r := do(admin, `mutation { saveSnapshot(name: "a/b") }`)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.BadUserInput))
+			Expect(testData.Snapshots()).To(BeEmpty())
+		})
+
+		It("report unknown snapshots as not found", func() {
+			r := do(admin, `mutation { restoreSnapshot(name: "unknown") }`)
+			Expect(r.Errors).To(HaveLen(1))
+			Expect(errorCode(r.Errors[0])).To(Equal(apierrors.NotFound))
+		})
+
+		It("are left out when they are turned off", func() {
+			api, err := NewAPIWithConfig(testData, NewAuthenticationProvider(), Config{PasswordCost: bcrypt.MinCost})
+			Expect(err).ToNot(HaveOccurred())
+			schema = api.Schema
+
+			Expect(schema.QueryType().Fields()).ToNot(HaveKey("snapshots"))
+			Expect(schema.MutationType().Fields()).ToNot(HaveKey("saveSnapshot"))
+			Expect(schema.MutationType().Fields()).ToNot(HaveKey("resetData"))
+		})
+	})
+
+	Context("admin endpoints", func() {
+		var handler http.Handler
+
+		BeforeEach(func() {
+			handler = AdminHandler(testData)
+		})
+
+		// Sends the request as the user, or anonymously if the user is negative.
+		do := func(userID int, method, path string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(method, path, nil)
+			if userID >= 0 {
+				r = r.WithContext(WithIdentity(r.Context(), Identity{UserID: userID}))
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+
+		errorOf := func(w *httptest.ResponseRecorder) map[string]string {
+			var body map[string]string
+			Expect(json.Unmarshal(w.Body.Bytes(), &body)).To(Succeed())
+			return body
+		}
+
+		It("save, list, restore and delete snapshots", func() {
+			Expect(do(admin, http.MethodPut, "/admin/snapshots/clean").Code).To(Equal(http.StatusNoContent))
+			must(testData.DeleteUser(2))
+
+			w := do(admin, http.MethodGet, "/admin/snapshots")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`{"snapshots": ["clean"]}`))
+
+			Expect(do(admin, http.MethodPost, "/admin/snapshots/clean/restore").Code).To(Equal(http.StatusNoContent))
+			Expect(testData.GetUsers()).To(HaveLen(3))
+
+			Expect(do(admin, http.MethodDelete, "/admin/snapshots/clean").Code).To(Equal(http.StatusNoContent))
+			Expect(testData.Snapshots()).To(BeEmpty())
+		})
+
+		It("reset the data", func() {
+			must(testData.DeleteUser(2))
+
+			Expect(do(admin, http.MethodPost, "/admin/reset").Code).To(Equal(http.StatusNoContent))
+			Expect(testData.GetUsers()).To(HaveLen(3))
+		})
+
+		It("are only for administrators", func() {
+			w := do(user, http.MethodPut, "/admin/snapshots/clean")
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+			Expect(errorOf(w)).To(HaveKeyWithValue("code", string(apierrors.Forbidden)))
+
+			w = do(-1, http.MethodPost, "/admin/reset")
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(errorOf(w)).To(HaveKeyWithValue("code", string(apierrors.Unauthenticated)))
+
+			Expect(testData.Snapshots()).To(BeEmpty())
+		})
+
+		It("report unknown snapshots and routes as not found", func() {
+			w := do(admin, http.MethodPost, "/admin/snapshots/unknown/restore")
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+			Expect(errorOf(w)).To(HaveKeyWithValue("code", string(apierrors.NotFound)))
+
+			Expect(do(admin, http.MethodDelete, "/admin/snapshots/unknown").Code).To(Equal(http.StatusNotFound))
+			Expect(do(admin, http.MethodGet, "/admin/other").Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("reject invalid names", func() {
+			w := do(admin, http.MethodPut, "/admin/snapshots/"+strings.Repeat("a", 65))
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(errorOf(w)).To(HaveKeyWithValue("code", string(apierrors.BadUserInput)))
+		})
+
+		It("reject other methods", func() {
+			w := do(admin, http.MethodGet, "/admin/reset")
+			Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(w.Header().Get("Allow")).To(Equal("POST"))
+
+			w = do(admin, http.MethodPost, "/admin/snapshots/clean")
+			Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(w.Header().Get("Allow")).To(Equal("PUT, DELETE"))
+		})
+	})
+})
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/mail"
+	"strings"
+	"unicode"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/apierrors"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores anything after the first 72 bytes.
+	maxPasswordLength = 72
+)
+
+// fieldError is a problem with one argument, identified by its path within the arguments.
+type fieldError struct {
+	Path    []string `json:"path"`
+	Message string   `json:"message"`
+}
+
+// validationError reports every invalid argument of a field at once.
+// It is returned to the client with the path of each argument in the error extensions.
+type validationError []fieldError
+
+func (errs validationError) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = strings.Join(err.Path, ".") + " " + err.Message
+	}
+	return "invalid input: " + strings.Join(messages, "; ")
+}
+
+func (errs validationError) Code() apierrors.Code {
+	return apierrors.BadUserInput
+}
+
+func (errs validationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":   string(apierrors.BadUserInput),
+		"fields": []fieldError(errs),
+	}
+}
+
+// add records a problem with the argument at the path.
+func (errs *validationError) add(message string, path ...string) {
+	*errs = append(*errs, fieldError{Path: path, Message: message})
+}
+
+// err returns the validation error, or nil if every argument was valid.
+func (errs validationError) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateRequired(errs *validationError, value string, path ...string) {
+	if strings.TrimSpace(value) == "" {
+		errs.add("is required", path...)
+	}
+}
+
+func validateEmail(errs *validationError, email string, path ...string) {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		errs.add("is not a valid email address", path...)
+	}
+}
+
+// validatePassword requires passwords of 8 to 72 bytes with at least one letter and one digit.
+func validatePassword(errs *validationError, password string, path ...string) {
+	if len(password) < minPasswordLength {
+		errs.add("must be at least 8 characters", path...)
+		return
+	}
+
+	if len(password) > maxPasswordLength {
+		errs.add("must be at most 72 bytes", path...)
+		return
+	}
+
+	if strings.IndexFunc(password, unicode.IsLetter) < 0 || strings.IndexFunc(password, unicode.IsDigit) < 0 {
+		errs.add("must contain a letter and a digit", path...)
+	}
+}
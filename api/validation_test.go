@@ -0,0 +1,43 @@
+package api
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validation", func() {
+	DescribeTable("passwords", func(password string, expected []fieldError) {
+		var errs validationError
+
+		validatePassword(&errs, password, "password")
+
+		Expect([]fieldError(errs)).To(Equal(expected))
+	},
+		Entry("valid", "Password1", nil),
+		Entry("too short", "Pass1", []fieldError{{Path: []string{"password"}, Message: "must be at least 8 characters"}}),
+		Entry("too long", strings.Repeat("Password1", 9), []fieldError{{Path: []string{"password"}, Message: "must be at most 72 bytes"}}),
+		Entry("no digit", "Password", []fieldError{{Path: []string{"password"}, Message: "must contain a letter and a digit"}}),
+		Entry("no letter", "12345678", []fieldError{{Path: []string{"password"}, Message: "must contain a letter and a digit"}}),
+	)
+
+	DescribeTable("emails", func(email string, valid bool) {
+		var errs validationError
+
+		validateEmail(&errs, email, "email")
+
+		Expect(errs.err() == nil).To(Equal(valid))
+	},
+		Entry("valid", "someone@email.co.uk", true),
+		Entry("missing domain", "someone", false),
+		Entry("display name", "Someone <someone@email.co.uk>", false),
+		Entry("empty", "", false),
+	)
+
+	It("has no error when every argument is valid", func() {
+		var errs validationError
+
+		Expect(errs.err()).To(BeNil())
+	})
+})
@@ -0,0 +1,77 @@
+// Package apierrors provides errors that carry a code, which GraphQL clients
+// receive in the extensions of each error.
+package apierrors
+
+import "errors"
+
+// Code identifies the kind of an error, so that clients do not have to match on messages.
+type Code string
+
+const (
+	// Unauthenticated is for callers without a valid token or credentials.
+	Unauthenticated Code = "UNAUTHENTICATED"
+	// Forbidden is for callers that are not allowed to see or change the data.
+	Forbidden Code = "FORBIDDEN"
+	// NotFound is for items that do not exist.
+	NotFound Code = "NOT_FOUND"
+	// BadUserInput is for invalid arguments.
+	BadUserInput Code = "BAD_USER_INPUT"
+	// Internal is for everything else, which the caller cannot fix.
+	Internal Code = "INTERNAL"
+)
+
+// Coder is implemented by errors that carry a code.
+type Coder interface {
+	Code() Code
+}
+
+// Error is an error with a code.
+// It implements gqlerrors.ExtendedError, so the code is returned to clients as extensions.code.
+type Error struct {
+	code    Code
+	message string
+	err     error
+}
+
+// New returns an error with the code and message.
+func New(code Code, message string) *Error {
+	return &Error{code: code, message: message}
+}
+
+// Wrap returns an error with the code and the message of err, which it wraps.
+func Wrap(code Code, err error) *Error {
+	return &Error{code: code, message: err.Error(), err: err}
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+func (e *Error) Code() Code {
+	return e.code
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code": string(e.code),
+	}
+}
+
+// CodeOf returns the code of the first error in the chain of err that has one,
+// or Internal if none do.
+func CodeOf(err error) Code {
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.Code()
+	}
+	return Internal
+}
+
+// Is reports whether the code of err is the code.
+func Is(err error, code Code) bool {
+	return err != nil && CodeOf(err) == code
+}
@@ -0,0 +1,62 @@
+package apierrors
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Error", func() {
+	It("has the code and message", func() {
+		err := New(Forbidden, "forbidden")
+
+		Expect(err.Code()).To(Equal(Forbidden))
+		Expect(err).To(MatchError("forbidden"))
+		Expect(err.Unwrap()).To(BeNil())
+	})
+
+	It("wraps errors", func() {
+		cause := errors.New("user 1 not found")
+		err := Wrap(NotFound, cause)
+
+		Expect(err.Code()).To(Equal(NotFound))
+		Expect(err).To(MatchError("user 1 not found"))
+		Expect(err).To(MatchError(cause))
+	})
+
+	It("renders the code into the extensions", func() {
+		var err gqlerrors.ExtendedError = New(BadUserInput, "invalid")
+
+		Expect(err.Extensions()).To(Equal(map[string]interface{}{"code": "BAD_USER_INPUT"}))
+	})
+
+	It("renders the code into formatted GraphQL errors", func() {
+		err := gqlerrors.FormatError(gqlerrors.NewError("invalid", nil, "", nil, nil, New(BadUserInput, "invalid")))
+
+		Expect(err.Extensions).To(HaveKeyWithValue("code", "BAD_USER_INPUT"))
+	})
+})
+
+var _ = Describe("CodeOf", func() {
+	type coded struct{ error }
+
+	DescribeTable("returns the code of the error", func(err error, code Code) {
+		Expect(CodeOf(err)).To(Equal(code))
+	},
+		Entry("with a code", New(Unauthenticated, "unauthenticated"), Unauthenticated),
+		Entry("wrapping an error with a code", fmt.Errorf("login: %w", New(Unauthenticated, "unauthenticated")), Unauthenticated),
+		Entry("without a code", errors.New("failed"), Internal),
+		Entry("wrapping an error without a code", Wrap(Forbidden, errors.New("failed")), Forbidden),
+		Entry("embedding an error without a code", coded{errors.New("failed")}, Internal),
+	)
+
+	It("reports whether an error has the code", func() {
+		Expect(Is(New(NotFound, "not found"), NotFound)).To(BeTrue())
+		Expect(Is(New(NotFound, "not found"), Forbidden)).To(BeFalse())
+		Expect(Is(errors.New("failed"), Internal)).To(BeTrue())
+		Expect(Is(nil, Internal)).To(BeFalse())
+	})
+})
@@ -0,0 +1,527 @@
+// Package config reads the configuration of the server and its commands from defaults, an optional YAML or
+// TOML file, environment variables and flags, in increasing order of precedence.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/api"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the name of the environment variable of each flag, which is the flag's name in upper case
+// with '-' replaced by '_'. For example, FAKEDATA_JWT_LIFETIME sets -jwt-lifetime.
+const EnvPrefix = "FAKEDATA_"
+
+// ConfigEnv is the environment variable naming the config file, if -config is not given.
+const ConfigEnv = EnvPrefix + "CONFIG"
+
+type Config struct {
+	Server   ServerConfig  `yaml:"server" toml:"server"`
+	Data     DataConfig    `yaml:"data" toml:"data"`
+	Auth     AuthConfig    `yaml:"auth" toml:"auth"`
+	Features FeatureConfig `yaml:"features" toml:"features"`
+}
+
+type ServerConfig struct {
+	// Listen is the host:port the server listens on. The host may be empty to listen on every interface,
+	// and the port may be 0 to listen on any free port.
+	Listen string `yaml:"listen" toml:"listen"`
+	// Path is where the GraphQL endpoint is served.
+	Path string    `yaml:"path" toml:"path"`
+	TLS  TLSConfig `yaml:"tls" toml:"tls"`
+
+	// ReadTimeout is how long a client has to send a request.
+	ReadTimeout time.Duration `yaml:"read-timeout" toml:"read-timeout"`
+	// WriteTimeout is how long a request has to be handled and responded to.
+	WriteTimeout time.Duration `yaml:"write-timeout" toml:"write-timeout"`
+	// IdleTimeout is how long a kept alive connection waits for the next request.
+	IdleTimeout time.Duration `yaml:"idle-timeout" toml:"idle-timeout"`
+	// ShutdownTimeout is how long requests in flight have to finish when the server shuts down.
+	ShutdownTimeout time.Duration `yaml:"shutdown-timeout" toml:"shutdown-timeout"`
+}
+
+// TLSConfig is the PEM encoded certificate and key of the server. The server uses HTTPS if they are given.
+type TLSConfig struct {
+	Cert string `yaml:"cert" toml:"cert"`
+	Key  string `yaml:"key" toml:"key"`
+}
+
+// DataConfig chooses the data: a SQLite database, fixtures, or otherwise data generated with the seed.
+type DataConfig struct {
+	DB             string     `yaml:"db" toml:"db"`
+	Fixtures       string     `yaml:"fixtures" toml:"fixtures"`
+	Seed           int64      `yaml:"seed" toml:"seed"`
+	Users          int        `yaml:"users" toml:"users"`
+	Admins         int        `yaml:"admins" toml:"admins"`
+	AlbumsPerUser  data.Range `yaml:"albums-per-user" toml:"albums-per-user"`
+	PhotosPerAlbum data.Range `yaml:"photos-per-album" toml:"photos-per-album"`
+}
+
+type AuthConfig struct {
+	SigningMethod    string        `yaml:"signing-method" toml:"signing-method"`
+	Key              string        `yaml:"key" toml:"key"`
+	PrivateKey       string        `yaml:"private-key" toml:"private-key"`
+	PublicKey        string        `yaml:"public-key" toml:"public-key"`
+	VerificationKeys []string      `yaml:"verification-keys,omitempty" toml:"verification-keys"`
+	Issuer           string        `yaml:"issuer" toml:"issuer"`
+	Audience         string        `yaml:"audience" toml:"audience"`
+	Lifetime         time.Duration `yaml:"lifetime" toml:"lifetime"`
+	RefreshLifetime  time.Duration `yaml:"refresh-lifetime" toml:"refresh-lifetime"`
+	PasswordCost     int           `yaml:"password-cost" toml:"password-cost"`
+}
+
+type FeatureConfig struct {
+	GraphiQL bool `yaml:"graphiql" toml:"graphiql"`
+	Pretty   bool `yaml:"pretty" toml:"pretty"`
+	// Snapshots serves the mutations and admin endpoints that save, restore and reset the data.
+	Snapshots bool `yaml:"snapshots" toml:"snapshots"`
+	// JWKS serves the public keys of RS256 and ES256 tokens, and the OpenID configuration.
+	JWKS       bool `yaml:"jwks" toml:"jwks"`
+	Production bool `yaml:"production" toml:"production"`
+}
+
+// Default serves generated data at localhost:8080/graphql, with GraphiQL and every feature turned on.
+func Default() Config {
+	generator := data.DefaultGeneratorConfig()
+	auth := api.DefaultAuthenticationConfig()
+
+	return Config{
+		Server: ServerConfig{
+			Listen:          ":8080",
+			Path:            "/graphql",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 15 * time.Second,
+		},
+		Data: DataConfig{
+			Seed:           generator.Seed,
+			Users:          generator.Users,
+			Admins:         generator.Admins,
+			AlbumsPerUser:  generator.AlbumsPerUser,
+			PhotosPerAlbum: generator.PhotosPerAlbum,
+		},
+		Auth: AuthConfig{
+			SigningMethod:   auth.SigningMethod,
+			Issuer:          auth.Issuer,
+			Audience:        auth.Audience,
+			Lifetime:        auth.Lifetime,
+			RefreshLifetime: auth.RefreshLifetime,
+			PasswordCost:    bcrypt.DefaultCost,
+		},
+		Features: FeatureConfig{
+			GraphiQL:  true,
+			Pretty:    true,
+			Snapshots: true,
+			JWKS:      true,
+		},
+	}
+}
+
+func (config DataConfig) GeneratorConfig() data.GeneratorConfig {
+	return data.GeneratorConfig{
+		Seed:           config.Seed,
+		Users:          config.Users,
+		Admins:         config.Admins,
+		AlbumsPerUser:  config.AlbumsPerUser,
+		PhotosPerAlbum: config.PhotosPerAlbum,
+	}
+}
+
+func (config AuthConfig) AuthenticationConfig() api.AuthenticationConfig {
+	return api.AuthenticationConfig{
+		Issuer:               config.Issuer,
+		Audience:             config.Audience,
+		Lifetime:             config.Lifetime,
+		RefreshLifetime:      config.RefreshLifetime,
+		SigningMethod:        config.SigningMethod,
+		HMACKey:              config.Key,
+		PrivateKeyPath:       config.PrivateKey,
+		PublicKeyPath:        config.PublicKey,
+		VerificationKeyPaths: config.VerificationKeys,
+	}
+}
+
+func (config Config) APIConfig() api.Config {
+	return api.Config{
+		PasswordCost: config.Auth.PasswordCost,
+		Production:   config.Features.Production,
+		Snapshots:    config.Features.Snapshots,
+	}
+}
+
+// Group is a set of settings that a command uses, which only the flags of the groups are defined for
+// and only the settings of the groups are validated.
+type Group int
+
+const (
+	// Server is where the server listens, and its TLS certificate.
+	Server Group = 1 << iota
+	// Database is the SQLite database to use instead of a dataset.
+	Database
+	// Fixtures are the fixtures to use as the dataset.
+	Fixtures
+	// Generator generates the dataset if there are no fixtures.
+	Generator
+	// Auth is how tokens are made.
+	Auth
+	// Passwords is the cost that passwords are hashed at.
+	Passwords
+	// Features turn parts of the server on and off.
+	Features
+)
+
+// Flags defines the flags of a command's configuration on a flag set, and loads the configuration.
+type Flags struct {
+	// PrintConfig is set by -print-config, to print the configuration instead of running the command.
+	PrintConfig bool
+
+	flags  *flag.FlagSet
+	groups Group
+	// names are the flags of the configuration, which can also be set by environment variables.
+	names  []string
+	path   string
+	config Config
+}
+
+// settings are the keys in a config file of the settings that can be set by flags.
+var settings = map[string]string{
+	"listen":                "server.listen",
+	"path":                  "server.path",
+	"tls-cert":              "server.tls.cert",
+	"tls-key":               "server.tls.key",
+	"read-timeout":          "server.read-timeout",
+	"write-timeout":         "server.write-timeout",
+	"idle-timeout":          "server.idle-timeout",
+	"shutdown-timeout":      "server.shutdown-timeout",
+	"db":                    "data.db",
+	"fixtures":              "data.fixtures",
+	"seed":                  "data.seed",
+	"users":                 "data.users",
+	"admins":                "data.admins",
+	"albums-per-user":       "data.albums-per-user",
+	"photos-per-album":      "data.photos-per-album",
+	"jwt-signing-method":    "auth.signing-method",
+	"jwt-key":               "auth.key",
+	"jwt-private-key":       "auth.private-key",
+	"jwt-public-key":        "auth.public-key",
+	"jwt-verification-keys": "auth.verification-keys",
+	"jwt-issuer":            "auth.issuer",
+	"jwt-audience":          "auth.audience",
+	"jwt-lifetime":          "auth.lifetime",
+	"jwt-refresh-lifetime":  "auth.refresh-lifetime",
+	"password-cost":         "auth.password-cost",
+	"graphiql":              "features.graphiql",
+	"pretty":                "features.pretty",
+	"snapshots":             "features.snapshots",
+	"jwks":                  "features.jwks",
+	"production":            "features.production",
+}
+
+// NewFlags defines the flags of the groups on the flag set, along with -config and -print-config.
+// Commands may define flags of their own on the same flag set.
+func NewFlags(flags *flag.FlagSet, groups Group) *Flags {
+	f := &Flags{flags: flags, groups: groups, config: Default()}
+	c := &f.config
+
+	flags.StringVar(&f.path, "config", "", "YAML or TOML config file, "+ConfigEnv+" if not given")
+	flags.BoolVar(&f.PrintConfig, "print-config", false, "print the configuration as YAML and exit")
+
+	if groups&Server != 0 {
+		flags.StringVar(&c.Server.Listen, "listen", c.Server.Listen, "host:port to listen on")
+		flags.StringVar(&c.Server.Path, "path", c.Server.Path, "path of the GraphQL endpoint")
+		flags.StringVar(&c.Server.TLS.Cert, "tls-cert", c.Server.TLS.Cert, "PEM certificate to serve HTTPS with")
+		flags.StringVar(&c.Server.TLS.Key, "tls-key", c.Server.TLS.Key, "PEM key of the certificate")
+		flags.DurationVar(&c.Server.ReadTimeout, "read-timeout", c.Server.ReadTimeout, "time a client has to send a request")
+		flags.DurationVar(&c.Server.WriteTimeout, "write-timeout", c.Server.WriteTimeout, "time a request has to be handled and responded to")
+		flags.DurationVar(&c.Server.IdleTimeout, "idle-timeout", c.Server.IdleTimeout, "time a kept alive connection waits for the next request")
+		flags.DurationVar(&c.Server.ShutdownTimeout, "shutdown-timeout", c.Server.ShutdownTimeout, "time requests in flight have to finish when the server is stopped")
+	}
+	if groups&Database != 0 {
+		flags.StringVar(&c.Data.DB, "db", c.Data.DB, "path of a SQLite database, seeded with the seed command, to use instead of generated data")
+	}
+	if groups&Fixtures != 0 {
+		flags.StringVar(&c.Data.Fixtures, "fixtures", c.Data.Fixtures, "JSON or YAML fixture file, or directory of them, to load instead of generating data")
+	}
+	if groups&Generator != 0 {
+		flags.Int64Var(&c.Data.Seed, "seed", c.Data.Seed, "seed for the generated data")
+		flags.IntVar(&c.Data.Users, "users", c.Data.Users, "number of users to generate")
+		flags.IntVar(&c.Data.Admins, "admins", c.Data.Admins, "number of generated users that are administrators")
+		flags.Var(&c.Data.AlbumsPerUser, "albums-per-user", "number of albums per user, as N or MIN-MAX")
+		flags.Var(&c.Data.PhotosPerAlbum, "photos-per-album", "number of photos per album, as N or MIN-MAX")
+	}
+	if groups&Auth != 0 {
+		flags.StringVar(&c.Auth.SigningMethod, "jwt-signing-method", c.Auth.SigningMethod, "token signing method, one of HS256, RS256 or ES256")
+		flags.StringVar(&c.Auth.Key, "jwt-key", c.Auth.Key, "secret for HS256 tokens, random if empty")
+		flags.StringVar(&c.Auth.PrivateKey, "jwt-private-key", c.Auth.PrivateKey, "PEM private key for RS256 or ES256 tokens")
+		flags.StringVar(&c.Auth.PublicKey, "jwt-public-key", c.Auth.PublicKey, "PEM public key for RS256 or ES256 tokens, derived from the private key if empty")
+		flags.Var((*listValue)(&c.Auth.VerificationKeys), "jwt-verification-keys", "comma separated PEM public keys of previous signing keys that tokens are still accepted from")
+		flags.StringVar(&c.Auth.Issuer, "jwt-issuer", c.Auth.Issuer, "issuer of the tokens")
+		flags.StringVar(&c.Auth.Audience, "jwt-audience", c.Auth.Audience, "audience of the tokens, not checked if empty")
+		flags.DurationVar(&c.Auth.Lifetime, "jwt-lifetime", c.Auth.Lifetime, "lifetime of the tokens")
+		flags.DurationVar(&c.Auth.RefreshLifetime, "jwt-refresh-lifetime", c.Auth.RefreshLifetime, "lifetime of the refresh tokens")
+	}
+	if groups&Passwords != 0 {
+		flags.IntVar(&c.Auth.PasswordCost, "password-cost", c.Auth.PasswordCost, "bcrypt cost of the passwords of fixtures, and of registered and changed passwords")
+	}
+	if groups&Features != 0 {
+		flags.BoolVar(&c.Features.GraphiQL, "graphiql", c.Features.GraphiQL, "serve GraphiQL to browsers")
+		flags.BoolVar(&c.Features.Pretty, "pretty", c.Features.Pretty, "indent the JSON of responses")
+		flags.BoolVar(&c.Features.Snapshots, "snapshots", c.Features.Snapshots, "serve the mutations and admin endpoints that save, restore and reset the data")
+		flags.BoolVar(&c.Features.JWKS, "jwks", c.Features.JWKS, "serve the public keys of RS256 and ES256 tokens, and the OpenID configuration")
+		flags.BoolVar(&c.Features.Production, "production", c.Features.Production, "hide the message of internal errors from clients, logging them instead")
+	}
+
+	flags.VisitAll(func(fl *flag.Flag) {
+		if _, ok := settings[fl.Name]; ok {
+			f.names = append(f.names, fl.Name)
+		}
+	})
+	return f
+}
+
+// Load parses the arguments and returns the configuration, with the defaults overridden by the config file,
+// then by environment variables, then by the flags.
+//
+// Errors parsing the flags are returned as they are, after the flag set has reported them.
+// Otherwise every problem with the configuration is returned together as Errors.
+func (f *Flags) Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	if err := f.flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	// The flags are set again last, so that they take precedence over the file and environment.
+	given := map[string]string{}
+	f.flags.Visit(func(fl *flag.Flag) { given[fl.Name] = fl.Value.String() })
+	f.config = Default()
+
+	var errs Errors
+
+	path := f.path
+	if path == "" {
+		path, _ = lookupEnv(ConfigEnv)
+	}
+	if path != "" {
+		errs = append(errs, readFile(path, &f.config)...)
+	}
+
+	for _, name := range f.names {
+		env := envName(name)
+		if value, ok := lookupEnv(env); ok {
+			if err := f.flags.Lookup(name).Value.Set(value); err != nil {
+				errs.add("%s: invalid value %q: %v", env, value, err)
+			}
+		}
+	}
+
+	for name, value := range given {
+		f.flags.Set(name, value)
+	}
+
+	for _, arg := range f.flags.Args() {
+		errs.add("unexpected argument %q", arg)
+	}
+
+	errs = append(errs, f.config.validate(f.groups)...)
+
+	if len(errs) > 0 {
+		return f.config, errs
+	}
+	return f.config, nil
+}
+
+func envName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// describe names a setting by its key in a config file, its flag and its environment variable.
+func describe(flagName string) string {
+	return fmt.Sprintf("%s (-%s, %s)", settings[flagName], flagName, envName(flagName))
+}
+
+// readFile reads a YAML or TOML config file, chosen by its extension, into the config.
+// Settings that are not in the file keep their value. Unknown settings are errors.
+func readFile(path string, config *Config) Errors {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Errors{err}
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return Errors{fmt.Errorf("%s: %w", path, err)}
+		}
+
+	case ".toml":
+		metadata, err := toml.Decode(string(content), config)
+		if err != nil {
+			return Errors{fmt.Errorf("%s: %w", path, err)}
+		}
+		var errs Errors
+		for _, key := range metadata.Undecoded() {
+			errs.add("%s: unknown setting %q", path, key.String())
+		}
+		return errs
+
+	default:
+		return Errors{fmt.Errorf("%s: config file must be .yaml, .yml or .toml", path)}
+	}
+	return nil
+}
+
+// validate returns the problems with the settings of the groups.
+func (config Config) validate(groups Group) Errors {
+	var errs Errors
+
+	if groups&Server != 0 {
+		if _, port, err := net.SplitHostPort(config.Server.Listen); err != nil {
+			errs.add("%s must be host:port: %v", describe("listen"), err)
+		} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+			errs.add("%s has an invalid port %q", describe("listen"), port)
+		}
+
+		path := config.Server.Path
+		switch {
+		case !strings.HasPrefix(path, "/"):
+			errs.add("%s must start with /", describe("path"))
+		case path == api.JWKSPath, path == api.OpenIDConfigurationPath, strings.HasPrefix(path, api.AdminPath):
+			errs.add("%s %q is used by another endpoint", describe("path"), path)
+		}
+
+		tls := config.Server.TLS
+		if (tls.Cert == "") != (tls.Key == "") {
+			errs.add("%s and %s must be given together", describe("tls-cert"), describe("tls-key"))
+		}
+		errs.addMissingFile("tls-cert", tls.Cert)
+		errs.addMissingFile("tls-key", tls.Key)
+
+		errs.addNotPositive("read-timeout", config.Server.ReadTimeout)
+		errs.addNotPositive("write-timeout", config.Server.WriteTimeout)
+		errs.addNotPositive("idle-timeout", config.Server.IdleTimeout)
+		errs.addNotPositive("shutdown-timeout", config.Server.ShutdownTimeout)
+	}
+
+	if groups&Database != 0 && groups&Fixtures != 0 && config.Data.DB != "" && config.Data.Fixtures != "" {
+		errs.add("%s and %s cannot be used together; seed the database with the fixtures instead", describe("db"), describe("fixtures"))
+	}
+	if groups&Fixtures != 0 {
+		errs.addMissingFile("fixtures", config.Data.Fixtures)
+	}
+	if groups&Generator != 0 {
+		if err := config.Data.GeneratorConfig().Validate(); err != nil {
+			errs.add("data: %v", err)
+		}
+	}
+
+	if groups&Auth != 0 {
+		auth := config.Auth
+		switch auth.SigningMethod {
+		case "HS256":
+			if auth.PrivateKey != "" || auth.PublicKey != "" || len(auth.VerificationKeys) > 0 {
+				errs.add("%s: keys are only used by RS256 and ES256", describe("jwt-signing-method"))
+			}
+		case "RS256", "ES256":
+			if auth.PrivateKey == "" {
+				errs.add("%s: %s requires %s", describe("jwt-signing-method"), auth.SigningMethod, describe("jwt-private-key"))
+			}
+		default:
+			errs.add("%s must be one of HS256, RS256 or ES256, not %q", describe("jwt-signing-method"), auth.SigningMethod)
+		}
+		errs.addMissingFile("jwt-private-key", auth.PrivateKey)
+		errs.addMissingFile("jwt-public-key", auth.PublicKey)
+		for _, key := range auth.VerificationKeys {
+			errs.addMissingFile("jwt-verification-keys", key)
+		}
+
+		errs.addNotPositive("jwt-lifetime", auth.Lifetime)
+		errs.addNotPositive("jwt-refresh-lifetime", auth.RefreshLifetime)
+	}
+
+	if groups&Passwords != 0 {
+		if cost := config.Auth.PasswordCost; cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			errs.add("%s must be between %d and %d", describe("password-cost"), bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	}
+
+	return errs
+}
+
+// Print writes the configuration as YAML, which can be used as a config file. The HS256 secret is redacted.
+func Print(w io.Writer, config Config) error {
+	if config.Auth.Key != "" {
+		config.Auth.Key = "REDACTED"
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Errors are all the problems with a configuration, so that they can be fixed together.
+type Errors []error
+
+func (errs Errors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (errs *Errors) add(format string, args ...interface{}) {
+	*errs = append(*errs, fmt.Errorf(format, args...))
+}
+
+// addMissingFile adds an error if the file of the setting is given but does not exist.
+func (errs *Errors) addMissingFile(flagName, path string) {
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		errs.add("%s: %v", describe(flagName), err)
+	}
+}
+
+func (errs *Errors) addNotPositive(flagName string, duration time.Duration) {
+	if duration <= 0 {
+		errs.add("%s must be positive", describe(flagName))
+	}
+}
+
+// listValue is a flag of a comma separated list, which replaces the list when it is set.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	*l = nil
+	if s != "" {
+		*l = strings.Split(s, ",")
+	}
+	return nil
+}
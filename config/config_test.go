@@ -0,0 +1,267 @@
+package config_test
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/config"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	const all = config.Server | config.Database | config.Fixtures | config.Generator | config.Auth | config.Passwords | config.Features
+
+	var dir string
+	var env map[string]string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		env = map[string]string{}
+	})
+
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+		return path
+	}
+
+	newFlags := func(groups config.Group) (*flag.FlagSet, *config.Flags) {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		return flags, config.NewFlags(flags, groups)
+	}
+
+	load := func(groups config.Group, args ...string) (config.Config, error) {
+		_, flags := newFlags(groups)
+		return flags.Load(args, lookupEnv)
+	}
+
+	It("defaults to serving generated data", func() {
+		c, err := load(all)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(config.Default()))
+		Expect(c.Server.Listen).To(Equal(":8080"))
+		Expect(c.Server.Path).To(Equal("/graphql"))
+		Expect(c.Data.GeneratorConfig()).To(Equal(data.DefaultGeneratorConfig()))
+	})
+
+	It("reads a YAML config file", func() {
+		path := writeFile("config.yaml", `
+server:
+  listen: 127.0.0.1:9000
+  path: /api
+data:
+  users: 3
+  albums-per-user: 1-5
+auth:
+  lifetime: 5m
+  verification-keys: [a.pem]
+features:
+  graphiql: false
+`)
+
+		c, err := load(config.Server|config.Generator|config.Features, "-config", path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Server.Listen).To(Equal("127.0.0.1:9000"))
+		Expect(c.Server.Path).To(Equal("/api"))
+		Expect(c.Data.Users).To(Equal(3))
+		Expect(c.Data.AlbumsPerUser).To(Equal(data.Range{Min: 1, Max: 5}))
+		Expect(c.Data.PhotosPerAlbum).To(Equal(config.Default().Data.PhotosPerAlbum))
+		Expect(c.Auth.Lifetime).To(Equal(5 * time.Minute))
+		Expect(c.Auth.VerificationKeys).To(Equal([]string{"a.pem"}))
+		Expect(c.Features.GraphiQL).To(BeFalse())
+		Expect(c.Features.Pretty).To(BeTrue())
+	})
+
+	It("reads a TOML config file", func() {
+		path := writeFile("config.toml", `
+[server]
+listen = ":9000"
+
+[data]
+seed = 42
+photos-per-album = 2
+
+[auth]
+refresh-lifetime = "48h"
+password-cost = 4
+
+[features]
+production = true
+`)
+
+		c, err := load(all, "-config", path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Server.Listen).To(Equal(":9000"))
+		Expect(c.Data.Seed).To(Equal(int64(42)))
+		Expect(c.Data.PhotosPerAlbum).To(Equal(data.Range{Min: 2, Max: 2}))
+		Expect(c.Auth.RefreshLifetime).To(Equal(48 * time.Hour))
+		Expect(c.Auth.PasswordCost).To(Equal(4))
+		Expect(c.Features.Production).To(BeTrue())
+	})
+
+	It("reads the config file named by the environment", func() {
+		env[config.ConfigEnv] = writeFile("config.yml", "data:\n  users: 7\n")
+
+		c, err := load(all)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Data.Users).To(Equal(7))
+	})
+
+	It("prefers flags to the environment, and the environment to the config file", func() {
+		path := writeFile("config.yaml", "data:\n  users: 1\n  admins: 1\n  seed: 1\n")
+		env["FAKEDATA_ADMINS"] = "0"
+		env["FAKEDATA_SEED"] = "2"
+		env["FAKEDATA_JWT_VERIFICATION_KEYS"] = writeFile("a.pem", "")
+
+		c, err := load(all, "-config", path, "-seed", "3", "-jwt-signing-method", "RS256", "-jwt-private-key", writeFile("b.pem", ""))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Data.Users).To(Equal(1))
+		Expect(c.Data.Admins).To(Equal(0))
+		Expect(c.Data.Seed).To(Equal(int64(3)))
+		Expect(c.Auth.VerificationKeys).To(Equal([]string{filepath.Join(dir, "a.pem")}))
+	})
+
+	It("replaces lists given by flags", func() {
+		env["FAKEDATA_JWT_VERIFICATION_KEYS"] = writeFile("a.pem", "")
+		b := writeFile("b.pem", "")
+		c := writeFile("c.pem", "")
+
+		cfg, err := load(all, "-jwt-signing-method", "ES256", "-jwt-private-key", b, "-jwt-verification-keys", b+","+c)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.Auth.VerificationKeys).To(Equal([]string{b, c}))
+	})
+
+	It("only defines the flags of its groups", func() {
+		flags, _ := newFlags(config.Fixtures | config.Generator)
+
+		Expect(flags.Lookup("fixtures")).ToNot(BeNil())
+		Expect(flags.Lookup("users")).ToNot(BeNil())
+		Expect(flags.Lookup("config")).ToNot(BeNil())
+		Expect(flags.Lookup("print-config")).ToNot(BeNil())
+		Expect(flags.Lookup("db")).To(BeNil())
+		Expect(flags.Lookup("listen")).To(BeNil())
+		Expect(flags.Lookup("jwt-key")).To(BeNil())
+		Expect(flags.Lookup("password-cost")).To(BeNil())
+	})
+
+	It("ignores the environment of settings outside its groups", func() {
+		env["FAKEDATA_LISTEN"] = "nowhere"
+
+		_, err := load(config.Generator)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("leaves flags of the command alone", func() {
+		flags, configFlags := newFlags(config.Generator)
+		format := flags.String("format", "json", "")
+
+		_, err := configFlags.Load([]string{"-format", "csv", "--print-config"}, lookupEnv)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*format).To(Equal("csv"))
+		Expect(configFlags.PrintConfig).To(BeTrue())
+	})
+
+	It("returns errors parsing the flags as they are", func() {
+		_, err := load(all, "-unknown")
+		Expect(err).To(HaveOccurred())
+		Expect(err).ToNot(BeAssignableToTypeOf(config.Errors{}))
+
+		_, err = load(all, "-h")
+		Expect(err).To(MatchError(flag.ErrHelp))
+	})
+
+	It("reports every problem together", func() {
+		path := writeFile("config.yaml", "server:\n  path: graphql\n")
+		env["FAKEDATA_USERS"] = "many"
+
+		_, err := load(all,
+			"-config", path,
+			"-listen", "localhost",
+			"-db", "data.db",
+			"-fixtures", filepath.Join(dir, "missing.json"),
+			"-tls-cert", writeFile("cert.pem", ""),
+			"-jwt-signing-method", "RS256",
+			"-jwt-lifetime", "-1h",
+			"-shutdown-timeout", "0s",
+			"-password-cost", "100",
+			"-admins", "-1",
+			"extra",
+		)
+
+		var errs config.Errors
+		Expect(err).To(BeAssignableToTypeOf(errs))
+		errs = err.(config.Errors)
+		Expect(errs).To(HaveLen(12))
+		Expect(err.Error()).To(And(
+			ContainSubstring("FAKEDATA_USERS: invalid value \"many\""),
+			ContainSubstring(`unexpected argument "extra"`),
+			ContainSubstring("server.listen (-listen, FAKEDATA_LISTEN) must be host:port"),
+			ContainSubstring("server.path (-path, FAKEDATA_PATH) must start with /"),
+			ContainSubstring("must be given together"),
+			ContainSubstring("cannot be used together"),
+			ContainSubstring("missing.json"),
+			ContainSubstring("data: number of admins must not be negative"),
+			ContainSubstring("RS256 requires auth.private-key"),
+			ContainSubstring("auth.lifetime (-jwt-lifetime, FAKEDATA_JWT_LIFETIME) must be positive"),
+			ContainSubstring("server.shutdown-timeout (-shutdown-timeout, FAKEDATA_SHUTDOWN_TIMEOUT) must be positive"),
+			ContainSubstring("auth.password-cost (-password-cost, FAKEDATA_PASSWORD_COST) must be between 4 and 31"),
+		))
+	})
+
+	It("rejects paths used by other endpoints", func() {
+		_, err := load(all, "-path", "/admin/graphql")
+		Expect(err).To(MatchError(ContainSubstring("is used by another endpoint")))
+	})
+
+	It("rejects unknown settings", func() {
+		_, err := load(all, "-config", writeFile("config.yaml", "server:\n  port: 80\n"))
+		Expect(err).To(MatchError(ContainSubstring("field port not found")))
+
+		_, err = load(all, "-config", writeFile("config.toml", "[server]\nport = 80\n[other]\nx = 1\n"))
+		Expect(err).To(MatchError(And(
+			ContainSubstring(`unknown setting "server.port"`),
+			ContainSubstring(`unknown setting "other.x"`),
+		)))
+
+		_, err = load(all, "-config", writeFile("config.json", "{}"))
+		Expect(err).To(MatchError(ContainSubstring("must be .yaml, .yml or .toml")))
+	})
+
+	It("prints the configuration as a config file", func() {
+		original, err := load(all, "-users", "4", "-albums-per-user", "2-3", "-jwt-lifetime", "90m")
+		Expect(err).ToNot(HaveOccurred())
+
+		var buffer bytes.Buffer
+		Expect(config.Print(&buffer, original)).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring("server:\n  listen: :8080\n"))
+		Expect(buffer.String()).To(ContainSubstring("albums-per-user: 2-3\n"))
+		Expect(buffer.String()).To(ContainSubstring("lifetime: 1h30m0s\n"))
+
+		printed, err := load(all, "-config", writeFile("printed.yaml", buffer.String()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(printed).To(Equal(original))
+	})
+
+	It("does not print the secret", func() {
+		c, err := load(all, "-jwt-key", "hunter2")
+		Expect(err).ToNot(HaveOccurred())
+
+		var buffer bytes.Buffer
+		Expect(config.Print(&buffer, c)).To(Succeed())
+		Expect(buffer.String()).ToNot(ContainSubstring("hunter2"))
+		Expect(buffer.String()).To(ContainSubstring("key: REDACTED\n"))
+		Expect(c.Auth.AuthenticationConfig().HMACKey).To(Equal("hunter2"))
+	})
+})
@@ -0,0 +1,497 @@
+// Package conformance is a Ginkgo test suite that any implementation of data.IData can run
+// against itself, to check that it behaves like the in-memory store the API was built on.
+//
+// Run it from a Ginkgo suite of the implementation:
+//
+//	var _ = conformance.DescribeData("My store", func(dataset data.Dataset) (data.IData, error) {
+//		return NewMyStore(dataset)
+//	})
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// NewData returns a new store holding the dataset. The dataset has no passwords to hash.
+type NewData func(dataset data.Dataset) (data.IData, error)
+
+// Dataset returns the dataset that each spec starts from. Its items are out of order,
+// and it has a user without albums and an album without photos.
+func Dataset() data.Dataset {
+	return data.Dataset{
+		Users: []data.User{
+			{ID: 2, Name: "Bob Smith", Username: "bob", Email: "bob@example.com", Role: data.RoleUser},
+			{ID: 0, Name: "Alice Jones", Username: "alice", Email: "alice@example.com", PasswordHash: "hash", Role: data.RoleAdmin},
+			{ID: 3, Name: "Carol White", Username: "carol", Email: "carol@example.com", Role: data.RoleUser},
+		},
+		Albums: []data.Album{
+			{ID: 3, UserID: 0, Description: "Holiday in France"},
+			{ID: 1, UserID: 2, Description: "Birthday party"},
+			{ID: 0, UserID: 0, Description: "Family"},
+			{ID: 4, UserID: 2, Description: "Empty album"},
+		},
+		Photos: []data.Photo{
+			{ID: 2, AlbumID: 0, Description: "Beach at sunset"},
+			{ID: 1, AlbumID: 3, Description: "Eiffel tower at night"},
+			{ID: 0, AlbumID: 0, Description: "Grandma"},
+			{ID: 5, AlbumID: 1, Description: "Birthday cake"},
+		},
+	}
+}
+
+// DescribeData adds the specs of the suite for the store returned by newData to the Ginkgo suite.
+// The specs that change the data only run if the store is a data.IWritableData,
+// and the specs of snapshots only run if it is a data.ISnapshotData.
+func DescribeData(text string, newData NewData) bool {
+	return Describe(text, func() {
+		var store data.IData
+
+		BeforeEach(func() {
+			var err error
+			store, err = newData(Dataset())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		describeReads(func() data.IData { return store })
+
+		Context("when writable", func() {
+			var writable data.IWritableData
+
+			BeforeEach(func() {
+				var ok bool
+				if writable, ok = store.(data.IWritableData); !ok {
+					Skip(fmt.Sprintf("%T is not writable", store))
+				}
+			})
+
+			describeWrites(func() data.IWritableData { return writable })
+		})
+
+		Context("when it takes snapshots", func() {
+			var snapshots data.ISnapshotData
+
+			BeforeEach(func() {
+				var ok bool
+				if snapshots, ok = store.(data.ISnapshotData); !ok {
+					Skip(fmt.Sprintf("%T does not take snapshots", store))
+				}
+			})
+
+			describeSnapshots(func() data.ISnapshotData { return snapshots })
+		})
+
+		Context("when it saves snapshots", func() {
+			var restorable data.IRestorableData
+
+			BeforeEach(func() {
+				var ok bool
+				if restorable, ok = store.(data.IRestorableData); !ok {
+					Skip(fmt.Sprintf("%T does not save snapshots", store))
+				}
+			})
+
+			describeRestores(func() data.IRestorableData { return restorable })
+		})
+	})
+}
+
+func describeReads(store func() data.IData) {
+	It("lists items in order of their IDs", func() {
+		Expect(ids(must(store().GetUsers()))).To(Equal([]int{0, 2, 3}))
+		Expect(ids(must(store().GetAlbums()))).To(Equal([]int{0, 1, 3, 4}))
+		Expect(ids(must(store().GetPhotos()))).To(Equal([]int{0, 1, 2, 5}))
+	})
+
+	It("looks up items by ID", func() {
+		dataset := Dataset()
+
+		for _, user := range dataset.Users {
+			Expect(store().GetUser(user.ID)).To(Equal(user))
+		}
+		for _, album := range dataset.Albums {
+			Expect(store().GetAlbum(album.ID)).To(Equal(album))
+		}
+		for _, photo := range dataset.Photos {
+			Expect(store().GetPhoto(photo.ID)).To(Equal(photo))
+		}
+	})
+
+	It("returns an error wrapping ErrNotFound for unknown IDs", func() {
+		_, err := store().GetUser(1)
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().GetAlbum(2)
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().GetPhoto(-1)
+		Expect(err).To(MatchError(data.ErrNotFound))
+	})
+
+	It("looks up users by email", func() {
+		user, err := store().GetUserWithEmail("alice@example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*user).To(Equal(Dataset().Users[1]))
+
+		_, err = store().GetUserWithEmail("nobody@example.com")
+		Expect(err).To(MatchError(data.ErrNotFound))
+	})
+
+	It("looks up the albums of a user and the photos of an album in order of their IDs", func() {
+		Expect(ids(must(store().GetAlbumsByUserID(0)))).To(Equal([]int{0, 3}))
+		Expect(ids(must(store().GetAlbumsByUserID(2)))).To(Equal([]int{1, 4}))
+		Expect(ids(must(store().GetPhotosByAlbumID(0)))).To(Equal([]int{0, 2}))
+		Expect(ids(must(store().GetPhotosByAlbumID(3)))).To(Equal([]int{1}))
+	})
+
+	It("returns empty lists for users without albums, albums without photos and unknown IDs", func() {
+		Expect(store().GetAlbumsByUserID(3)).To(BeEmpty())
+		Expect(store().GetAlbumsByUserID(99)).To(BeEmpty())
+		Expect(store().GetAlbumsByUserID(99)).ToNot(BeNil())
+		Expect(store().GetPhotosByAlbumID(4)).To(BeEmpty())
+		Expect(store().GetPhotosByAlbumID(99)).To(BeEmpty())
+		Expect(store().GetPhotosByAlbumID(99)).ToNot(BeNil())
+	})
+
+	It("looks up the albums of many users and the photos of many albums at once", func() {
+		albums := must(store().GetAlbumsByUserIDs([]int{0, 2, 3, 99}))
+		Expect(albums).To(HaveLen(4))
+		Expect(ids(albums[0])).To(Equal([]int{0, 3}))
+		Expect(ids(albums[2])).To(Equal([]int{1, 4}))
+		Expect(albums).To(HaveKeyWithValue(3, BeEmpty()))
+		Expect(albums).To(HaveKeyWithValue(99, BeEmpty()))
+
+		photos := must(store().GetPhotosByAlbumIDs([]int{0, 3, 4, 99}))
+		Expect(photos).To(HaveLen(4))
+		Expect(ids(photos[0])).To(Equal([]int{0, 2}))
+		Expect(ids(photos[3])).To(Equal([]int{1}))
+		Expect(photos).To(HaveKeyWithValue(4, BeEmpty()))
+		Expect(photos).To(HaveKeyWithValue(99, BeEmpty()))
+	})
+
+	It("searches the text of every type of item, most relevant first", func() {
+		results := must(store().Search("Birthday BOB", nil))
+
+		Expect(matches(results)).To(ConsistOf("USER 2", "ALBUM 1", "PHOTO 5"))
+		for i := 1; i < len(results); i++ {
+			Expect(results[i].Score).To(BeNumerically("<=", results[i-1].Score))
+		}
+	})
+
+	It("searches items of the given types", func() {
+		Expect(matches(must(store().Search("birthday bob", []data.SearchType{data.SearchAlbum, data.SearchPhoto})))).To(ConsistOf("ALBUM 1", "PHOTO 5"))
+		Expect(store().Search("nothing", nil)).To(BeEmpty())
+	})
+}
+
+func describeWrites(store func() data.IWritableData) {
+	It("gives new items IDs that have not been used", func() {
+		user := must(store().CreateUser(data.User{Email: "dave@example.com"}))
+		Expect(user.ID).To(BeNumerically(">", 3))
+		album := must(store().CreateAlbum(data.Album{UserID: 0}))
+		Expect(album.ID).To(BeNumerically(">", 4))
+		photo := must(store().CreatePhoto(data.Photo{AlbumID: 0}))
+		Expect(photo.ID).To(BeNumerically(">", 5))
+
+		must(store().DeleteUser(user.ID))
+		Expect(must(store().CreateUser(data.User{Email: "dave@example.com"})).ID).To(BeNumerically(">", user.ID))
+	})
+
+	It("returns the items it creates and updates from lookups", func() {
+		user := must(store().CreateUser(data.User{Name: "Dave", Username: "dave", Email: "dave@example.com", PasswordHash: "hash", Role: data.RoleUser}))
+		album := must(store().CreateAlbum(data.Album{UserID: user.ID, Description: "Garden"}))
+		photo := must(store().CreatePhoto(data.Photo{AlbumID: album.ID, Description: "Roses"}))
+
+		Expect(store().GetUser(user.ID)).To(Equal(user))
+		Expect(store().GetAlbum(album.ID)).To(Equal(album))
+		Expect(store().GetPhoto(photo.ID)).To(Equal(photo))
+
+		user.Name = "David"
+		album.Description = "Back garden"
+		photo.Description = "Tulips"
+
+		Expect(store().UpdateUser(user)).To(Equal(user))
+		Expect(store().UpdateAlbum(album)).To(Equal(album))
+		Expect(store().UpdatePhoto(photo)).To(Equal(photo))
+
+		Expect(store().GetUser(user.ID)).To(Equal(user))
+		Expect(store().GetAlbum(album.ID)).To(Equal(album))
+		Expect(store().GetPhoto(photo.ID)).To(Equal(photo))
+	})
+
+	It("keeps emails unique", func() {
+		_, err := store().CreateUser(data.User{Email: "bob@example.com"})
+		Expect(err).To(MatchError(data.ErrEmailTaken))
+		_, err = store().UpdateUser(data.User{ID: 0, Email: "bob@example.com"})
+		Expect(err).To(MatchError(data.ErrEmailTaken))
+
+		Expect(must(store().UpdateUser(data.User{ID: 2, Name: "Robert", Email: "bob@example.com"})).Name).To(Equal("Robert"))
+		Expect(ids(must(store().GetUsers()))).To(Equal([]int{0, 2, 3}))
+	})
+
+	It("finds users by their current email", func() {
+		must(store().UpdateUser(data.User{ID: 0, Email: "alice@example.org"}))
+
+		_, err := store().GetUserWithEmail("alice@example.com")
+		Expect(err).To(MatchError(data.ErrNotFound))
+		Expect(must(store().GetUserWithEmail("alice@example.org")).ID).To(Equal(0))
+
+		must(store().CreateUser(data.User{Email: "alice@example.com"}))
+		_, err = store().CreateUser(data.User{Email: "alice@example.org"})
+		Expect(err).To(MatchError(data.ErrEmailTaken))
+	})
+
+	It("rejects changes to unknown items", func() {
+		_, err := store().UpdateUser(data.User{ID: 99, Email: "dave@example.com"})
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().UpdateAlbum(data.Album{ID: 99, UserID: 0})
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().UpdatePhoto(data.Photo{ID: 99, AlbumID: 0})
+		Expect(err).To(MatchError(data.ErrNotFound))
+
+		_, err = store().DeleteUser(99)
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().DeleteAlbum(99)
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().DeletePhoto(99)
+		Expect(err).To(MatchError(data.ErrNotFound))
+
+		_, err = store().GetUserWithEmail("dave@example.com")
+		Expect(err).To(MatchError(data.ErrNotFound))
+	})
+
+	It("rejects items whose owner does not exist", func() {
+		_, err := store().CreateAlbum(data.Album{UserID: 99})
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().UpdateAlbum(data.Album{ID: 0, UserID: 99})
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().CreatePhoto(data.Photo{AlbumID: 99})
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().UpdatePhoto(data.Photo{ID: 0, AlbumID: 99})
+		Expect(err).To(MatchError(data.ErrNotFound))
+
+		Expect(ids(must(store().GetAlbums()))).To(Equal([]int{0, 1, 3, 4}))
+		Expect(ids(must(store().GetPhotos()))).To(Equal([]int{0, 1, 2, 5}))
+		Expect(ids(must(store().GetAlbumsByUserID(0)))).To(Equal([]int{0, 3}))
+		Expect(ids(must(store().GetPhotosByAlbumID(0)))).To(Equal([]int{0, 2}))
+	})
+
+	It("moves albums and photos to their new owner", func() {
+		must(store().UpdateAlbum(data.Album{ID: 3, UserID: 2}))
+		must(store().UpdatePhoto(data.Photo{ID: 2, AlbumID: 1}))
+
+		Expect(ids(must(store().GetAlbumsByUserID(0)))).To(Equal([]int{0}))
+		Expect(ids(must(store().GetAlbumsByUserID(2)))).To(Equal([]int{1, 3, 4}))
+		Expect(ids(must(store().GetPhotosByAlbumID(0)))).To(Equal([]int{0}))
+		Expect(ids(must(store().GetPhotosByAlbumID(1)))).To(Equal([]int{2, 5}))
+	})
+
+	It("deletes the albums and photos of deleted users", func() {
+		Expect(must(store().DeleteUser(0)).Email).To(Equal("alice@example.com"))
+
+		Expect(ids(must(store().GetUsers()))).To(Equal([]int{2, 3}))
+		Expect(ids(must(store().GetAlbums()))).To(Equal([]int{1, 4}))
+		Expect(ids(must(store().GetPhotos()))).To(Equal([]int{5}))
+		Expect(store().GetAlbumsByUserID(0)).To(BeEmpty())
+		Expect(store().GetPhotosByAlbumID(0)).To(BeEmpty())
+		Expect(store().GetPhotosByAlbumID(3)).To(BeEmpty())
+		_, err := store().GetAlbum(3)
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().GetPhoto(1)
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().GetUserWithEmail("alice@example.com")
+		Expect(err).To(MatchError(data.ErrNotFound))
+
+		must(store().CreateUser(data.User{Email: "alice@example.com"}))
+	})
+
+	It("deletes the photos of deleted albums", func() {
+		Expect(must(store().DeleteAlbum(0)).Description).To(Equal("Family"))
+
+		Expect(ids(must(store().GetAlbums()))).To(Equal([]int{1, 3, 4}))
+		Expect(ids(must(store().GetPhotos()))).To(Equal([]int{1, 5}))
+		Expect(ids(must(store().GetAlbumsByUserID(0)))).To(Equal([]int{3}))
+		Expect(store().GetPhotosByAlbumID(0)).To(BeEmpty())
+	})
+
+	It("deletes photos", func() {
+		Expect(must(store().DeletePhoto(2)).Description).To(Equal("Beach at sunset"))
+
+		Expect(ids(must(store().GetPhotos()))).To(Equal([]int{0, 1, 5}))
+		Expect(ids(must(store().GetPhotosByAlbumID(0)))).To(Equal([]int{0}))
+	})
+
+	It("searches the data as changed", func() {
+		user := must(store().CreateUser(data.User{Name: "Dave Birthday", Email: "dave@example.com"}))
+		must(store().UpdateAlbum(data.Album{ID: 1, UserID: 2, Description: "Anniversary"}))
+		must(store().DeleteUser(2))
+
+		Expect(matches(must(store().Search("birthday bob", nil)))).To(ConsistOf(fmt.Sprint("USER ", user.ID)))
+	})
+
+	It("is safe for concurrent use", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				album := must(store().CreateAlbum(data.Album{UserID: 3}))
+				must(store().CreatePhoto(data.Photo{AlbumID: album.ID}))
+			}()
+			go func() {
+				defer wg.Done()
+				store().GetAlbumsByUserID(3)
+				store().GetPhotos()
+				store().Search("anything", nil)
+			}()
+		}
+		wg.Wait()
+
+		Expect(store().GetAlbumsByUserID(3)).To(HaveLen(10))
+		Expect(store().GetPhotos()).To(HaveLen(14))
+	})
+}
+
+func describeSnapshots(store func() data.ISnapshotData) {
+	It("keeps snapshots unchanged by later writes", func() {
+		snapshot := store().Snapshot()
+		if closer, ok := snapshot.(io.Closer); ok {
+			DeferCleanup(closer.Close)
+		}
+
+		writable, ok := store().(data.IWritableData)
+		if !ok {
+			Skip(fmt.Sprintf("%T is not writable", store()))
+		}
+		must(writable.UpdateUser(data.User{ID: 2, Name: "Robert", Email: "robert@example.com"}))
+		must(writable.CreateAlbum(data.Album{UserID: 3}))
+		must(writable.DeleteAlbum(0))
+
+		Expect(must(snapshot.GetUser(2)).Name).To(Equal("Bob Smith"))
+		Expect(must(snapshot.GetUserWithEmail("bob@example.com")).ID).To(Equal(2))
+		Expect(snapshot.GetAlbumsByUserID(3)).To(BeEmpty())
+		Expect(ids(must(snapshot.GetPhotosByAlbumID(0)))).To(Equal([]int{0, 2}))
+		Expect(matches(must(snapshot.Search("bob", nil)))).To(ConsistOf("USER 2"))
+	})
+}
+
+func describeRestores(store func() data.IRestorableData) {
+	// change makes a change to each kind of item, and returns the IDs of the new items.
+	change := func() (userID, albumID, photoID int) {
+		must(store().UpdateUser(data.User{ID: 2, Name: "Robert", Email: "robert@example.com"}))
+		must(store().DeleteAlbum(0))
+		userID = must(store().CreateUser(data.User{Email: "dave@example.com"})).ID
+		albumID = must(store().CreateAlbum(data.Album{UserID: userID})).ID
+		photoID = must(store().CreatePhoto(data.Photo{AlbumID: albumID})).ID
+		return userID, albumID, photoID
+	}
+
+	It("restores the data saved in a snapshot", func() {
+		Expect(store().SaveSnapshot("before")).To(Succeed())
+		userID, _, _ := change()
+
+		Expect(store().RestoreSnapshot("before")).To(Succeed())
+
+		Expect(must(store().GetUser(2)).Name).To(Equal("Bob Smith"))
+		Expect(must(store().GetUserWithEmail("bob@example.com")).ID).To(Equal(2))
+		_, err := store().GetUserWithEmail("dave@example.com")
+		Expect(err).To(MatchError(data.ErrNotFound))
+		_, err = store().GetUser(userID)
+		Expect(err).To(MatchError(data.ErrNotFound))
+		Expect(ids(must(store().GetAlbums()))).To(Equal([]int{0, 1, 3, 4}))
+		Expect(ids(must(store().GetPhotosByAlbumID(0)))).To(Equal([]int{0, 2}))
+		Expect(matches(must(store().Search("bob", nil)))).To(ConsistOf("USER 2"))
+	})
+
+	It("gives new items the same IDs after restoring a snapshot", func() {
+		Expect(store().SaveSnapshot("before")).To(Succeed())
+		userID, albumID, photoID := change()
+
+		Expect(store().RestoreSnapshot("before")).To(Succeed())
+
+		newUserID, newAlbumID, newPhotoID := change()
+		Expect([]int{newUserID, newAlbumID, newPhotoID}).To(Equal([]int{userID, albumID, photoID}))
+	})
+
+	It("keeps snapshots unchanged by writes after restoring them", func() {
+		Expect(store().SaveSnapshot("before")).To(Succeed())
+		Expect(store().RestoreSnapshot("before")).To(Succeed())
+		change()
+
+		Expect(store().RestoreSnapshot("before")).To(Succeed())
+
+		Expect(must(store().GetUser(2)).Name).To(Equal("Bob Smith"))
+		Expect(ids(must(store().GetUsers()))).To(Equal([]int{0, 2, 3}))
+	})
+
+	It("replaces snapshots with the same name", func() {
+		Expect(store().SaveSnapshot("snapshot")).To(Succeed())
+		must(store().DeletePhoto(0))
+		Expect(store().SaveSnapshot("snapshot")).To(Succeed())
+		must(store().DeletePhoto(1))
+
+		Expect(store().RestoreSnapshot("snapshot")).To(Succeed())
+
+		Expect(ids(must(store().GetPhotos()))).To(Equal([]int{1, 2, 5}))
+	})
+
+	It("resets the data to how it was created, keeping snapshots", func() {
+		change()
+		Expect(store().SaveSnapshot("changed")).To(Succeed())
+
+		Expect(store().Reset()).To(Succeed())
+
+		Expect(ids(must(store().GetUsers()))).To(Equal([]int{0, 2, 3}))
+		Expect(must(store().GetUser(2)).Name).To(Equal("Bob Smith"))
+		Expect(ids(must(store().GetAlbums()))).To(Equal([]int{0, 1, 3, 4}))
+		Expect(store().Snapshots()).To(Equal([]string{"changed"}))
+	})
+
+	It("lists and deletes snapshots", func() {
+		Expect(store().Snapshots()).To(BeEmpty())
+		Expect(store().SaveSnapshot("b")).To(Succeed())
+		Expect(store().SaveSnapshot("a")).To(Succeed())
+		Expect(store().Snapshots()).To(Equal([]string{"a", "b"}))
+
+		Expect(store().DeleteSnapshot("b")).To(Succeed())
+
+		Expect(store().Snapshots()).To(Equal([]string{"a"}))
+		Expect(store().RestoreSnapshot("b")).To(MatchError(data.ErrNotFound))
+	})
+
+	It("returns an error wrapping ErrNotFound for unknown snapshots", func() {
+		Expect(store().RestoreSnapshot("unknown")).To(MatchError(data.ErrNotFound))
+		Expect(store().DeleteSnapshot("unknown")).To(MatchError(data.ErrNotFound))
+	})
+}
+
+func ids(items interface{}) []int {
+	switch items := items.(type) {
+	case []data.User:
+		return utils.Transform(items, func(user data.User) int { return user.ID })
+	case []data.Album:
+		return utils.Transform(items, func(album data.Album) int { return album.ID })
+	case []data.Photo:
+		return utils.Transform(items, func(photo data.Photo) int { return photo.ID })
+	}
+	panic(fmt.Sprintf("unexpected items %T", items))
+}
+
+// matches describes each search result by its type and ID.
+func matches(results []data.SearchResult) []string {
+	return utils.Transform(results, func(result data.SearchResult) string {
+		return fmt.Sprintf("%s %d", result.Type, result.ID)
+	})
+}
+
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
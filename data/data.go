@@ -1,5 +1,22 @@
 package data
 
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrEmailTaken = errors.New("email is already in use")
+)
+
+type Role string
+
+const (
+	RoleUser  Role = "USER"
+	RoleAdmin Role = "ADMIN"
+)
+
 type User struct {
 	ID           int
 	Name         string
@@ -7,11 +24,14 @@ type User struct {
 	Albums       []Album
 	Email        string
 	PasswordHash string
+	Role         Role
 }
 
 type Authentication struct {
-	Token string
-	User  User
+	Token        string
+	RefreshToken string
+	ExpiresAt    time.Time
+	User         User
 }
 
 type Album struct {
@@ -27,16 +47,72 @@ type Photo struct {
 	Description string
 }
 
+// IData reads the data. Looking up a list returns an error only if the data could not be read,
+// such as when a database fails.
 type IData interface {
-	GetUsers() []User
-	GetAlbums() []Album
-	GetPhotos() []Photo
+	GetUsers() ([]User, error)
+	GetAlbums() ([]Album, error)
+	GetPhotos() ([]Photo, error)
 
-	GetUser(id int) User
-	GetAlbum(id int) Album
-	GetPhoto(id int) Photo
+	// GetUser, GetAlbum and GetPhoto return an error wrapping ErrNotFound
+	// if there is no item with the ID.
+	GetUser(id int) (User, error)
+	GetAlbum(id int) (Album, error)
+	GetPhoto(id int) (Photo, error)
 
 	GetUserWithEmail(email string) (*User, error)
-	GetAlbumsByUserID(userID int) []Album
-	GetPhotosByAlbumID(albumID int) []Photo
+	GetAlbumsByUserID(userID int) ([]Album, error)
+	GetPhotosByAlbumID(albumID int) ([]Photo, error)
+
+	// GetAlbumsByUserIDs and GetPhotosByAlbumIDs look up the items of many users or albums at once.
+	// The result has an entry for every ID, which is empty if there are no items.
+	GetAlbumsByUserIDs(userIDs []int) (map[int][]Album, error)
+	GetPhotosByAlbumIDs(albumIDs []int) (map[int][]Photo, error)
+
+	// Search returns the items of the given types, or of any type if none are given,
+	// matching the text, most relevant first.
+	Search(text string, types []SearchType) ([]SearchResult, error)
+}
+
+type IWritableData interface {
+	IData
+
+	CreateUser(user User) (User, error)
+	UpdateUser(user User) (User, error)
+	DeleteUser(id int) (User, error)
+
+	CreateAlbum(album Album) (Album, error)
+	UpdateAlbum(album Album) (Album, error)
+	DeleteAlbum(id int) (Album, error)
+
+	CreatePhoto(photo Photo) (Photo, error)
+	UpdatePhoto(photo Photo) (Photo, error)
+	DeletePhoto(id int) (Photo, error)
+}
+
+// ISnapshotData is implemented by data models that can give a consistent view of their data,
+// such as for the duration of a request.
+type ISnapshotData interface {
+	IData
+
+	// Snapshot returns a view of the data as it is now, which later writes do not change.
+	// A snapshot that is an io.Closer holds resources, such as a transaction, until it is closed.
+	Snapshot() IData
+}
+
+// IRestorableData is implemented by data models that can save their data under a name and go back to it later,
+// such as to isolate tests that change the data from each other.
+type IRestorableData interface {
+	IWritableData
+
+	// SaveSnapshot saves the data as it is now under the name, replacing any snapshot with the same name.
+	SaveSnapshot(name string) error
+	// RestoreSnapshot and DeleteSnapshot return an error wrapping ErrNotFound if there is no snapshot with the name.
+	RestoreSnapshot(name string) error
+	DeleteSnapshot(name string) error
+	// Snapshots returns the names of the saved snapshots in order.
+	Snapshots() []string
+
+	// Reset restores the data the model was created with. Saved snapshots are kept.
+	Reset() error
 }
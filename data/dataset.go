@@ -0,0 +1,12 @@
+package data
+
+// Dataset is a complete set of users, albums and photos.
+type Dataset struct {
+	Users  []User
+	Albums []Album
+	Photos []Photo
+
+	// Passwords holds the plaintext password of each user, keyed by user ID,
+	// for users whose password has not yet been hashed.
+	Passwords map[int]string
+}
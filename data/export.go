@@ -0,0 +1,205 @@
+package data
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// fixtureUser, fixtureAlbum and fixturePhoto are items as they are written to fixture files.
+type fixtureUser struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	Role         Role   `json:"role"`
+	PasswordHash string `json:"passwordhash,omitempty"`
+	Password     string `json:"password,omitempty"`
+}
+
+type fixtureAlbum struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"userid"`
+	Description string `json:"description"`
+}
+
+type fixturePhoto struct {
+	ID          int    `json:"id"`
+	AlbumID     int    `json:"albumid"`
+	Description string `json:"description"`
+}
+
+type fixtures struct {
+	Users  []fixtureUser  `json:"users"`
+	Albums []fixtureAlbum `json:"albums"`
+	Photos []fixturePhoto `json:"photos"`
+}
+
+// DatasetOf returns every item of the data. The items are read from a snapshot if the data takes them,
+// so that they are consistent with each other.
+func DatasetOf(source IData) (Dataset, error) {
+	if snapshots, ok := source.(ISnapshotData); ok {
+		source = snapshots.Snapshot()
+		if closer, ok := source.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
+
+	users, err := source.GetUsers()
+	if err != nil {
+		return Dataset{}, err
+	}
+	albums, err := source.GetAlbums()
+	if err != nil {
+		return Dataset{}, err
+	}
+	photos, err := source.GetPhotos()
+	if err != nil {
+		return Dataset{}, err
+	}
+
+	return Dataset{Users: users, Albums: albums, Photos: photos}, nil
+}
+
+// fixturesOf returns the items of the dataset as they are written to fixture files.
+func fixturesOf(dataset Dataset) fixtures {
+	exported := fixtures{Users: []fixtureUser{}, Albums: []fixtureAlbum{}, Photos: []fixturePhoto{}}
+	for _, user := range dataset.Users {
+		exported.Users = append(exported.Users, fixtureUser{user.ID, user.Name, user.Username, user.Email, user.Role, user.PasswordHash, dataset.Passwords[user.ID]})
+	}
+	for _, album := range dataset.Albums {
+		exported.Albums = append(exported.Albums, fixtureAlbum{album.ID, album.UserID, album.Description})
+	}
+	for _, photo := range dataset.Photos {
+		exported.Photos = append(exported.Photos, fixturePhoto{photo.ID, photo.AlbumID, photo.Description})
+	}
+	return exported
+}
+
+// ExportJSON writes every item of the data as a JSON fixture file, which LoadFixtures reads back.
+// Users are written with their password hashes.
+func ExportJSON(w io.Writer, source IData) error {
+	dataset, err := DatasetOf(source)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, dataset)
+}
+
+// ExportNDJSON writes every item of the data as an NDJSON fixture file, with a line for each item.
+func ExportNDJSON(w io.Writer, source IData) error {
+	dataset, err := DatasetOf(source)
+	if err != nil {
+		return err
+	}
+	return WriteNDJSON(w, dataset)
+}
+
+// ExportCSV writes the users, albums and photos of the data as the CSV fixture files users.csv, albums.csv
+// and photos.csv in the directory, which is created if it does not exist.
+func ExportCSV(dir string, source IData) error {
+	dataset, err := DatasetOf(source)
+	if err != nil {
+		return err
+	}
+	return WriteCSV(dir, dataset)
+}
+
+// WriteJSON writes the dataset as a JSON fixture file. Users are written with their password hashes,
+// and with their passwords in plain text if the dataset has them, such as when it was generated.
+func WriteJSON(w io.Writer, dataset Dataset) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(fixturesOf(dataset))
+}
+
+// WriteNDJSON writes the dataset as an NDJSON fixture file, with a line for each item.
+func WriteNDJSON(w io.Writer, dataset Dataset) error {
+	exported := fixturesOf(dataset)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for _, user := range exported.Users {
+		if err := encoder.Encode(struct {
+			Type string `json:"type"`
+			fixtureUser
+		}{"user", user}); err != nil {
+			return err
+		}
+	}
+	for _, album := range exported.Albums {
+		if err := encoder.Encode(struct {
+			Type string `json:"type"`
+			fixtureAlbum
+		}{"album", album}); err != nil {
+			return err
+		}
+	}
+	for _, photo := range exported.Photos {
+		if err := encoder.Encode(struct {
+			Type string `json:"type"`
+			fixturePhoto
+		}{"photo", photo}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes the users, albums and photos of the dataset as the CSV fixture files users.csv, albums.csv
+// and photos.csv in the directory, which is created if it does not exist. The users have a password column
+// if the dataset has passwords.
+func WriteCSV(dir string, dataset Dataset) error {
+	exported := fixturesOf(dataset)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	users := [][]string{{"id", "name", "username", "email", "role", "passwordhash"}}
+	if len(dataset.Passwords) > 0 {
+		users[0] = append(users[0], "password")
+	}
+	for _, user := range exported.Users {
+		record := []string{strconv.Itoa(user.ID), user.Name, user.Username, user.Email, string(user.Role), user.PasswordHash}
+		if len(dataset.Passwords) > 0 {
+			record = append(record, user.Password)
+		}
+		users = append(users, record)
+	}
+	if err := writeCSV(filepath.Join(dir, "users.csv"), users); err != nil {
+		return err
+	}
+
+	albums := [][]string{{"id", "userid", "description"}}
+	for _, album := range exported.Albums {
+		albums = append(albums, []string{strconv.Itoa(album.ID), strconv.Itoa(album.UserID), album.Description})
+	}
+	if err := writeCSV(filepath.Join(dir, "albums.csv"), albums); err != nil {
+		return err
+	}
+
+	photos := [][]string{{"id", "albumid", "description"}}
+	for _, photo := range exported.Photos {
+		photos = append(photos, []string{strconv.Itoa(photo.ID), strconv.Itoa(photo.AlbumID), photo.Description})
+	}
+	return writeCSV(filepath.Join(dir, "photos.csv"), photos)
+}
+
+func writeCSV(path string, records [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := csv.NewWriter(file).WriteAll(records); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,157 @@
+package data_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/api"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = Describe("Export", func() {
+	var dir string
+	var dataset data.Dataset
+	var source data.IData
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+
+		hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Text that needs quoting or escaping in some format, or that could be mistaken for another type.
+		dataset = data.Dataset{
+			Users: []data.User{
+				{ID: 0, Name: `Zoë "Z" O'Brien`, Username: "null", Email: "zoe@example.com", PasswordHash: string(hash), Role: data.RoleAdmin},
+				{ID: 4, Name: "123", Username: "true", Email: "<b>@example.com", Role: data.RoleUser},
+			},
+			Albums: []data.Album{
+				{ID: 1, UserID: 4, Description: "Line one\nline two, with a comma"},
+				{ID: 2, UserID: 0, Description: ""},
+			},
+			Photos: []data.Photo{
+				{ID: 7, AlbumID: 1, Description: "  spaces  "},
+				{ID: 8, AlbumID: 2, Description: "- [not, a, list]: {}"},
+			},
+			Passwords: map[int]string{},
+		}
+
+		source, err = api.NewTestDataWithDataset(dataset, bcrypt.MinCost)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	write := func(name string, export func(w *bytes.Buffer) error) string {
+		var buffer bytes.Buffer
+		Expect(export(&buffer)).To(Succeed())
+
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, buffer.Bytes(), 0o644)).To(Succeed())
+		return path
+	}
+
+	It("round trips through JSON", func() {
+		path := write("export.json", func(w *bytes.Buffer) error { return data.ExportJSON(w, source) })
+
+		Expect(data.LoadFixtures(path)).To(Equal(dataset))
+	})
+
+	It("round trips through NDJSON", func() {
+		path := write("export.ndjson", func(w *bytes.Buffer) error { return data.ExportNDJSON(w, source) })
+
+		Expect(data.LoadFixtures(path)).To(Equal(dataset))
+	})
+
+	It("round trips through CSV", func() {
+		Expect(data.ExportCSV(dir, source)).To(Succeed())
+
+		Expect(data.LoadFixtures(dir)).To(Equal(dataset))
+	})
+
+	It("writes a JSON fixture file", func() {
+		source, err := api.NewTestDataWithDataset(data.Dataset{
+			Users:  []data.User{{ID: 0, Name: "Alice", Username: "alice", Email: "alice@example.com", Role: data.RoleUser}},
+			Albums: []data.Album{{ID: 0, UserID: 0, Description: "Holiday"}},
+		}, bcrypt.MinCost)
+		Expect(err).ToNot(HaveOccurred())
+
+		var buffer bytes.Buffer
+		Expect(data.ExportJSON(&buffer, source)).To(Succeed())
+		Expect(buffer.String()).To(Equal(`{
+  "users": [
+    {
+      "id": 0,
+      "name": "Alice",
+      "username": "alice",
+      "email": "alice@example.com",
+      "role": "USER"
+    }
+  ],
+  "albums": [
+    {
+      "id": 0,
+      "userid": 0,
+      "description": "Holiday"
+    }
+  ],
+  "photos": []
+}
+`))
+	})
+
+	It("writes an NDJSON line for each item", func() {
+		var buffer bytes.Buffer
+		Expect(data.ExportNDJSON(&buffer, source)).To(Succeed())
+
+		lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+		Expect(lines).To(HaveLen(6))
+		Expect(string(lines[2])).To(Equal(`{"type":"album","id":1,"userid":4,"description":"Line one\nline two, with a comma"}`))
+	})
+
+	It("writes a CSV file for each type of item", func() {
+		Expect(data.ExportCSV(filepath.Join(dir, "export"), source)).To(Succeed())
+
+		photos, err := os.ReadFile(filepath.Join(dir, "export", "photos.csv"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(photos)).To(Equal("id,albumid,description\n7,1,\"  spaces  \"\n8,2,\"- [not, a, list]: {}\"\n"))
+	})
+
+	Context("of a dataset with passwords", func() {
+		var generated data.Dataset
+
+		BeforeEach(func() {
+			config := data.DefaultGeneratorConfig()
+			config.Users = 3
+			config.AlbumsPerUser = data.Range{Min: 1, Max: 1}
+			config.PhotosPerAlbum = data.Range{Min: 1, Max: 1}
+
+			var err error
+			generated, err = data.Generate(config)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("round trips through JSON", func() {
+			path := write("generated.json", func(w *bytes.Buffer) error { return data.WriteJSON(w, generated) })
+
+			Expect(data.LoadFixtures(path)).To(Equal(generated))
+		})
+
+		It("round trips through NDJSON", func() {
+			path := write("generated.ndjson", func(w *bytes.Buffer) error { return data.WriteNDJSON(w, generated) })
+
+			Expect(data.LoadFixtures(path)).To(Equal(generated))
+		})
+
+		It("round trips through CSV", func() {
+			Expect(data.WriteCSV(dir, generated)).To(Succeed())
+
+			users, err := os.ReadFile(filepath.Join(dir, "users.csv"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(users)).To(HavePrefix("id,name,username,email,role,passwordhash,password\n"))
+			Expect(data.LoadFixtures(dir)).To(Equal(generated))
+		})
+	})
+})
@@ -0,0 +1,614 @@
+package data
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureError is a problem with a fixture file, at a line and column of the file if they are known.
+type FixtureError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (err FixtureError) Error() string {
+	if err.Line == 0 {
+		return fmt.Sprintf("%s: %s", err.File, err.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", err.File, err.Line, err.Column, err.Message)
+}
+
+// FixtureErrors are all the problems found in the fixture files, in order of their position.
+type FixtureErrors []FixtureError
+
+func (errs FixtureErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// fixtureParsers parse fixture files by their extension.
+var fixtureParsers = map[string]func(loader *fixtureLoader, file string, content []byte){
+	".json":   (*fixtureLoader).parseJSON,
+	".yaml":   (*fixtureLoader).parseYAML,
+	".yml":    (*fixtureLoader).parseYAML,
+	".ndjson": (*fixtureLoader).parseNDJSON,
+	".csv":    (*fixtureLoader).parseCSV,
+}
+
+// csvIntColumns are the columns of CSV fixture files that hold integers.
+var csvIntColumns = map[string]bool{
+	"id":      true,
+	"userid":  true,
+	"albumid": true,
+}
+
+// LoadFixtures reads a dataset from a fixture file, or from every fixture file in a directory.
+//
+// A JSON or YAML fixture file is a mapping with lists of users, albums and photos:
+//
+//	users:
+//	  - id: 0
+//	    name: Alice Jones
+//	    username: alice
+//	    email: alice@example.com
+//	    role: ADMIN
+//	    password: secret
+//	albums:
+//	  - id: 0
+//	    userid: 0
+//	    description: Holiday
+//	photos:
+//	  - id: 0
+//	    albumid: 0
+//	    description: Beach
+//
+// An NDJSON fixture file has an object on each line, with the fields of a user, album or photo
+// and a type of user, album or photo. A CSV fixture file is named users.csv, albums.csv or photos.csv,
+// and has a header row of field names; empty cells are treated as missing fields.
+//
+// A user has either a plaintext password, which is hashed by the store, or the bcrypt hash
+// of one as passwordhash. The role is USER if it is not given.
+// Albums and photos may refer to users and albums of any of the files.
+//
+// If the fixtures are invalid, the error is a FixtureErrors with every problem found.
+func LoadFixtures(path string) (Dataset, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Dataset{}, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return Dataset{}, err
+		}
+
+		files = nil
+		for _, entry := range entries {
+			if _, ok := fixtureParsers[strings.ToLower(filepath.Ext(entry.Name()))]; ok && !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return Dataset{}, fmt.Errorf("no fixture files in %s", path)
+		}
+	}
+
+	loader := newFixtureLoader()
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return Dataset{}, err
+		}
+		loader.parse(file, content)
+	}
+	loader.checkReferences()
+
+	if len(loader.errs) > 0 {
+		sort.SliceStable(loader.errs, func(i, j int) bool {
+			a, b := loader.errs[i], loader.errs[j]
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Column < b.Column
+		})
+		return Dataset{}, loader.errs
+	}
+	return loader.dataset, nil
+}
+
+// fixturePosition is where an item or field is defined.
+type fixturePosition struct {
+	file   string
+	line   int
+	column int
+}
+
+func (position fixturePosition) String() string {
+	return fmt.Sprintf("%s:%d:%d", position.file, position.line, position.column)
+}
+
+// fixtureReference is a reference from an item to the ID of its owner.
+type fixtureReference struct {
+	id       int
+	position fixturePosition
+}
+
+type fixtureLoader struct {
+	dataset Dataset
+	errs    FixtureErrors
+
+	users  map[int]fixturePosition
+	albums map[int]fixturePosition
+	photos map[int]fixturePosition
+	emails map[string]fixturePosition
+
+	userReferences  []fixtureReference
+	albumReferences []fixtureReference
+}
+
+func newFixtureLoader() *fixtureLoader {
+	return &fixtureLoader{
+		dataset: Dataset{
+			Users:     []User{},
+			Albums:    []Album{},
+			Photos:    []Photo{},
+			Passwords: make(map[int]string),
+		},
+		users:  make(map[int]fixturePosition),
+		albums: make(map[int]fixturePosition),
+		photos: make(map[int]fixturePosition),
+		emails: make(map[string]fixturePosition),
+	}
+}
+
+// parse adds the items of the file to the dataset.
+func (loader *fixtureLoader) parse(file string, content []byte) {
+	parse, ok := fixtureParsers[strings.ToLower(filepath.Ext(file))]
+	if !ok {
+		loader.errs = append(loader.errs, FixtureError{File: file, Message: "unsupported file type, expected .json, .yaml, .yml, .ndjson or .csv"})
+		return
+	}
+	parse(loader, file, content)
+}
+
+// parseJSON parses the file as YAML, of which JSON is a subset, for the positions of the nodes.
+// It is checked to be JSON first so that YAML is not accepted in .json files.
+func (loader *fixtureLoader) parseJSON(file string, content []byte) {
+	var value interface{}
+	if err := json.Unmarshal(content, &value); err != nil {
+		loader.errs = append(loader.errs, jsonError(file, content, 0, err))
+		return
+	}
+	loader.parseYAML(file, content)
+}
+
+func (loader *fixtureLoader) parseYAML(file string, content []byte) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		loader.errs = append(loader.errs, FixtureError{File: file, Message: strings.TrimPrefix(err.Error(), "yaml: ")})
+		return
+	}
+	if len(document.Content) == 0 {
+		return
+	}
+
+	root := resolveAlias(document.Content[0])
+	if root.Kind != yaml.MappingNode {
+		loader.errorf(file, root, "expected a mapping of users, albums and photos")
+		return
+	}
+
+	loader.fields(file, root, map[string]func(value *yaml.Node) bool{
+		"users":  func(value *yaml.Node) bool { return loader.items(file, value, loader.user) },
+		"albums": func(value *yaml.Node) bool { return loader.items(file, value, loader.album) },
+		"photos": func(value *yaml.Node) bool { return loader.items(file, value, loader.photo) },
+	})
+}
+
+// parseNDJSON parses each line of the file as an item, whose type is given by its type field.
+func (loader *fixtureLoader) parseNDJSON(file string, content []byte) {
+	parsers := map[string]func(file string, item *yaml.Node){
+		"user":  loader.user,
+		"album": loader.album,
+		"photo": loader.photo,
+	}
+
+	offset := 0
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		lineOffset := offset
+		offset += len(line) + 1
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var value interface{}
+		if err := json.Unmarshal(line, &value); err != nil {
+			loader.errs = append(loader.errs, jsonError(file, content, lineOffset, err))
+			continue
+		}
+
+		var document yaml.Node
+		if err := yaml.Unmarshal(line, &document); err != nil {
+			loader.errs = append(loader.errs, FixtureError{File: file, Line: i + 1, Column: 1, Message: strings.TrimPrefix(err.Error(), "yaml: ")})
+			continue
+		}
+		item := document.Content[0]
+		moveLines(item, i)
+
+		if item.Kind != yaml.MappingNode {
+			loader.errorf(file, item, "expected an object")
+			continue
+		}
+
+		typeIndex := -1
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			if item.Content[j].Value == "type" {
+				typeIndex = j
+				break
+			}
+		}
+		if typeIndex < 0 {
+			loader.errorf(file, item, "item has no type")
+			continue
+		}
+		parse, ok := parsers[item.Content[typeIndex+1].Value]
+		if !ok {
+			loader.errorf(file, item.Content[typeIndex+1], "type must be user, album or photo")
+			continue
+		}
+
+		item.Content = append(item.Content[:typeIndex:typeIndex], item.Content[typeIndex+2:]...)
+		parse(file, item)
+	}
+}
+
+// parseCSV parses each row of the file as an item, whose type is given by the name of the file.
+func (loader *fixtureLoader) parseCSV(file string, content []byte) {
+	parsers := map[string]func(file string, item *yaml.Node){
+		"users":  loader.user,
+		"albums": loader.album,
+		"photos": loader.photo,
+	}
+
+	name := filepath.Base(file)
+	parse, ok := parsers[strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))]
+	if !ok {
+		loader.errs = append(loader.errs, FixtureError{File: file, Message: "CSV fixture files must be named users.csv, albums.csv or photos.csv"})
+		return
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	header, err := reader.Read()
+	if err == io.EOF {
+		return
+	}
+	if err != nil {
+		loader.errs = append(loader.errs, csvError(file, err))
+		return
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			loader.errs = append(loader.errs, csvError(file, err))
+			return
+		}
+
+		line, column := reader.FieldPos(0)
+		item := &yaml.Node{Kind: yaml.MappingNode, Line: line, Column: column}
+		for i, cell := range row {
+			if cell == "" {
+				continue
+			}
+
+			line, column := reader.FieldPos(i)
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: header[i], Line: line, Column: column}
+			// Integer columns are left untagged, so that their tag is resolved from their value.
+			value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: cell, Line: line, Column: column}
+			if csvIntColumns[header[i]] {
+				value.Tag = ""
+			}
+			item.Content = append(item.Content, key, value)
+		}
+		parse(file, item)
+	}
+}
+
+// items parses each item of the list, and returns false if it is not a list.
+func (loader *fixtureLoader) items(file string, list *yaml.Node, parse func(file string, item *yaml.Node)) bool {
+	if list.Kind == yaml.ScalarNode && list.ShortTag() == "!!null" {
+		return true
+	}
+	if list.Kind != yaml.SequenceNode {
+		loader.errorf(file, list, "expected a list")
+		return false
+	}
+
+	for _, item := range list.Content {
+		item = resolveAlias(item)
+		if item.Kind != yaml.MappingNode {
+			loader.errorf(file, item, "expected a mapping")
+			continue
+		}
+		parse(file, item)
+	}
+	return true
+}
+
+func (loader *fixtureLoader) user(file string, item *yaml.Node) {
+	user := User{Role: RoleUser}
+
+	fields := loader.fields(file, item, map[string]func(value *yaml.Node) bool{
+		"id":       func(value *yaml.Node) bool { return loader.int(file, "id", value, &user.ID) },
+		"name":     func(value *yaml.Node) bool { return loader.string(file, "name", value, &user.Name) },
+		"username": func(value *yaml.Node) bool { return loader.string(file, "username", value, &user.Username) },
+		"email":    func(value *yaml.Node) bool { return loader.string(file, "email", value, &user.Email) },
+		"role": func(value *yaml.Node) bool {
+			var role string
+			if !loader.string(file, "role", value, &role) {
+				return false
+			}
+			user.Role = Role(role)
+			if user.Role != RoleUser && user.Role != RoleAdmin {
+				loader.errorf(file, value, "role must be %s or %s", RoleUser, RoleAdmin)
+				return false
+			}
+			return true
+		},
+		"password": func(value *yaml.Node) bool {
+			var password string
+			return loader.string(file, "password", value, &password)
+		},
+		"passwordhash": func(value *yaml.Node) bool {
+			if !loader.string(file, "passwordhash", value, &user.PasswordHash) {
+				return false
+			}
+			if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+				loader.errorf(file, value, "passwordhash is not a bcrypt hash")
+				return false
+			}
+			return true
+		},
+	})
+
+	if !loader.define(file, item, fields, "user", user.ID, loader.users) {
+		return
+	}
+
+	email, ok := fields["email"]
+	switch {
+	case !ok || email != nil && user.Email == "":
+		loader.errorf(file, item, "user %d has no email", user.ID)
+	case email == nil:
+	default:
+		if first, ok := loader.emails[user.Email]; ok {
+			loader.errorf(file, email, "email %s is already used by the user at %s", user.Email, first)
+		} else {
+			loader.emails[user.Email] = loader.position(file, email)
+		}
+	}
+
+	password, hasPassword := fields["password"]
+	if _, hasPasswordHash := fields["passwordhash"]; hasPassword && hasPasswordHash {
+		loader.errorf(file, item, "user %d has both a password and a passwordhash", user.ID)
+	} else if password != nil {
+		loader.dataset.Passwords[user.ID] = password.Value
+	}
+
+	loader.dataset.Users = append(loader.dataset.Users, user)
+}
+
+func (loader *fixtureLoader) album(file string, item *yaml.Node) {
+	var album Album
+
+	fields := loader.fields(file, item, map[string]func(value *yaml.Node) bool{
+		"id":          func(value *yaml.Node) bool { return loader.int(file, "id", value, &album.ID) },
+		"userid":      func(value *yaml.Node) bool { return loader.int(file, "userid", value, &album.UserID) },
+		"description": func(value *yaml.Node) bool { return loader.string(file, "description", value, &album.Description) },
+	})
+
+	if !loader.define(file, item, fields, "album", album.ID, loader.albums) {
+		return
+	}
+
+	if userID, ok := fields["userid"]; !ok {
+		loader.errorf(file, item, "album %d has no userid", album.ID)
+	} else if userID != nil {
+		loader.userReferences = append(loader.userReferences, fixtureReference{album.UserID, loader.position(file, userID)})
+	}
+
+	loader.dataset.Albums = append(loader.dataset.Albums, album)
+}
+
+func (loader *fixtureLoader) photo(file string, item *yaml.Node) {
+	var photo Photo
+
+	fields := loader.fields(file, item, map[string]func(value *yaml.Node) bool{
+		"id":          func(value *yaml.Node) bool { return loader.int(file, "id", value, &photo.ID) },
+		"albumid":     func(value *yaml.Node) bool { return loader.int(file, "albumid", value, &photo.AlbumID) },
+		"description": func(value *yaml.Node) bool { return loader.string(file, "description", value, &photo.Description) },
+	})
+
+	if !loader.define(file, item, fields, "photo", photo.ID, loader.photos) {
+		return
+	}
+
+	if albumID, ok := fields["albumid"]; !ok {
+		loader.errorf(file, item, "photo %d has no albumid", photo.ID)
+	} else if albumID != nil {
+		loader.albumReferences = append(loader.albumReferences, fixtureReference{photo.AlbumID, loader.position(file, albumID)})
+	}
+
+	loader.dataset.Photos = append(loader.dataset.Photos, photo)
+}
+
+// define records the position of the item with the ID, and returns false if the item has no ID,
+// or its ID is invalid or already used by another item of the same type.
+func (loader *fixtureLoader) define(file string, item *yaml.Node, fields map[string]*yaml.Node, name string, id int, positions map[int]fixturePosition) bool {
+	idNode, ok := fields["id"]
+	if !ok {
+		loader.errorf(file, item, "%s has no id", name)
+		return false
+	}
+	if idNode == nil {
+		return false
+	}
+	if id < 0 {
+		loader.errorf(file, idNode, "%s id must not be negative", name)
+		return false
+	}
+	if first, ok := positions[id]; ok {
+		loader.errorf(file, idNode, "%s %d is already defined at %s", name, id, first)
+		return false
+	}
+
+	positions[id] = loader.position(file, item)
+	return true
+}
+
+// checkReferences reports the albums and photos whose owner is not defined in any file.
+func (loader *fixtureLoader) checkReferences() {
+	for _, reference := range loader.userReferences {
+		if _, ok := loader.users[reference.id]; !ok {
+			loader.errorAt(reference.position, fmt.Sprintf("user %d does not exist", reference.id))
+		}
+	}
+	for _, reference := range loader.albumReferences {
+		if _, ok := loader.albums[reference.id]; !ok {
+			loader.errorAt(reference.position, fmt.Sprintf("album %d does not exist", reference.id))
+		}
+	}
+}
+
+// fields parses the value of each field of the mapping, and returns the values by field.
+// The value of a field that could not be parsed is nil. Unknown and repeated fields are reported.
+func (loader *fixtureLoader) fields(file string, mapping *yaml.Node, parsers map[string]func(value *yaml.Node) bool) map[string]*yaml.Node {
+	values := make(map[string]*yaml.Node, len(parsers))
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], resolveAlias(mapping.Content[i+1])
+
+		parse, ok := parsers[key.Value]
+		if !ok {
+			loader.errorf(file, key, "unknown field %q", key.Value)
+			continue
+		}
+		if _, ok := values[key.Value]; ok {
+			loader.errorf(file, key, "field %q is repeated", key.Value)
+			continue
+		}
+
+		values[key.Value] = nil
+		if parse(value) {
+			values[key.Value] = value
+		}
+	}
+
+	return values
+}
+
+// int sets the target to the value, and returns false if it is not an integer.
+func (loader *fixtureLoader) int(file string, name string, value *yaml.Node, target *int) bool {
+	if value.Kind != yaml.ScalarNode || value.ShortTag() != "!!int" || value.Decode(target) != nil {
+		loader.errorf(file, value, "%s must be an integer", name)
+		return false
+	}
+	return true
+}
+
+// string sets the target to the value, and returns false if it is not a scalar.
+func (loader *fixtureLoader) string(file string, name string, value *yaml.Node, target *string) bool {
+	if value.Kind != yaml.ScalarNode || value.ShortTag() == "!!null" {
+		loader.errorf(file, value, "%s must be a string", name)
+		return false
+	}
+	*target = value.Value
+	return true
+}
+
+func (loader *fixtureLoader) position(file string, node *yaml.Node) fixturePosition {
+	return fixturePosition{file: file, line: node.Line, column: node.Column}
+}
+
+func (loader *fixtureLoader) errorf(file string, node *yaml.Node, format string, args ...interface{}) {
+	loader.errorAt(loader.position(file, node), fmt.Sprintf(format, args...))
+}
+
+func (loader *fixtureLoader) errorAt(position fixturePosition, message string) {
+	loader.errs = append(loader.errs, FixtureError{File: position.file, Line: position.line, Column: position.column, Message: message})
+}
+
+// resolveAlias returns the node an alias refers to, or the node itself if it is not an alias.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// moveLines moves the node and its children down by the number of lines.
+func moveLines(node *yaml.Node, lines int) {
+	node.Line += lines
+	for _, child := range node.Content {
+		moveLines(child, lines)
+	}
+}
+
+// jsonError returns the error of invalid JSON, which starts at the offset in the content, at its position in the content.
+func jsonError(file string, content []byte, offset int, err error) FixtureError {
+	var syntaxError *json.SyntaxError
+	if !errors.As(err, &syntaxError) {
+		return FixtureError{File: file, Message: err.Error()}
+	}
+
+	// The offset of the error is just after the byte that was not expected.
+	end := offset + int(syntaxError.Offset) - 1
+	if end < offset {
+		end = offset
+	}
+
+	line, column := 1, 1
+	for _, b := range content[:end] {
+		if b == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return FixtureError{File: file, Line: line, Column: column, Message: err.Error()}
+}
+
+// csvError returns the error of invalid CSV at its position in the file.
+func csvError(file string, err error) FixtureError {
+	var parseError *csv.ParseError
+	if !errors.As(err, &parseError) {
+		return FixtureError{File: file, Message: err.Error()}
+	}
+	return FixtureError{File: file, Line: parseError.Line, Column: parseError.Column, Message: parseError.Err.Error()}
+}
@@ -0,0 +1,208 @@
+package data_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = Describe("Fixtures", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+		return path
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+
+	expected := data.Dataset{
+		Users: []data.User{
+			{ID: 1, Name: "Alice Jones", Username: "alice", Email: "alice@example.com", Role: data.RoleAdmin},
+			{ID: 0, Name: "Bob Smith", Username: "bob", Email: "bob@example.com", PasswordHash: string(hash), Role: data.RoleUser},
+		},
+		Albums: []data.Album{
+			{ID: 0, UserID: 1, Description: "Holiday"},
+		},
+		Photos: []data.Photo{
+			{ID: 3, AlbumID: 0, Description: "Beach"},
+		},
+		Passwords: map[int]string{1: "secret"},
+	}
+
+	It("loads YAML", func() {
+		path := write("fixtures.yaml", `
+users:
+  - id: 1
+    name: Alice Jones
+    username: alice
+    email: alice@example.com
+    role: ADMIN
+    password: secret
+  - id: 0
+    name: Bob Smith
+    username: bob
+    email: bob@example.com
+    passwordhash: `+string(hash)+`
+albums:
+  - {id: 0, userid: 1, description: Holiday}
+photos:
+  - id: 3
+    albumid: 0
+    description: Beach
+`)
+
+		Expect(data.LoadFixtures(path)).To(Equal(expected))
+	})
+
+	It("loads JSON", func() {
+		path := write("fixtures.json", `{
+	"users": [
+		{"id": 1, "name": "Alice Jones", "username": "alice", "email": "alice@example.com", "role": "ADMIN", "password": "secret"},
+		{"id": 0, "name": "Bob Smith", "username": "bob", "email": "bob@example.com", "passwordhash": "`+string(hash)+`"}
+	],
+	"albums": [{"id": 0, "userid": 1, "description": "Holiday"}],
+	"photos": [{"id": 3, "albumid": 0, "description": "Beach"}]
+}`)
+
+		Expect(data.LoadFixtures(path)).To(Equal(expected))
+	})
+
+	It("loads every fixture file of a directory", func() {
+		write("1-users.yml", "users:\n  - {id: 0, email: a@example.com}\n")
+		write("2-albums.json", `{"albums": [{"id": 0, "userid": 0}]}`)
+		write("3-photos.yaml", "photos:\n  - {id: 0, albumid: 0}\n")
+		write("README.md", "not a fixture")
+
+		dataset, err := data.LoadFixtures(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dataset.Users).To(Equal([]data.User{{ID: 0, Email: "a@example.com", Role: data.RoleUser}}))
+		Expect(dataset.Albums).To(Equal([]data.Album{{ID: 0, UserID: 0}}))
+		Expect(dataset.Photos).To(Equal([]data.Photo{{ID: 0, AlbumID: 0}}))
+	})
+
+	It("loads empty files", func() {
+		path := write("empty.yaml", "")
+
+		dataset, err := data.LoadFixtures(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dataset.Users).To(BeEmpty())
+	})
+
+	It("reports every problem with its position", func() {
+		write("users.yaml", `users:
+  - id: 0
+    email: a@example.com
+    role: OWNER
+  - id: 0
+    email: b@example.com
+  - id: 1
+    email: a@example.com
+    password: secret
+    passwordhash: secret
+  - name: No ID
+    email: c@example.com
+  - id: two
+    email: d@example.com
+  - id: 3
+    nickname: Dee
+`)
+		write("albums.json", `{
+	"albums": [
+		{"id": 0, "userid": 9},
+		{"id": 1}
+	],
+	"photos": [{"id": 0, "albumid": 5}]
+}`)
+
+		_, err := data.LoadFixtures(dir)
+
+		var errs data.FixtureErrors
+		Expect(err).To(BeAssignableToTypeOf(errs))
+		errs = err.(data.FixtureErrors)
+
+		albums, users := filepath.Join(dir, "albums.json"), filepath.Join(dir, "users.yaml")
+		Expect(errs).To(Equal(data.FixtureErrors{
+			{File: albums, Line: 3, Column: 23, Message: "user 9 does not exist"},
+			{File: albums, Line: 4, Column: 3, Message: "album 1 has no userid"},
+			{File: albums, Line: 6, Column: 34, Message: "album 5 does not exist"},
+			{File: users, Line: 4, Column: 11, Message: "role must be USER or ADMIN"},
+			{File: users, Line: 5, Column: 9, Message: "user 0 is already defined at " + users + ":2:5"},
+			{File: users, Line: 7, Column: 5, Message: "user 1 has both a password and a passwordhash"},
+			{File: users, Line: 8, Column: 12, Message: "email a@example.com is already used by the user at " + users + ":3:12"},
+			{File: users, Line: 10, Column: 19, Message: "passwordhash is not a bcrypt hash"},
+			{File: users, Line: 11, Column: 5, Message: "user has no id"},
+			{File: users, Line: 13, Column: 9, Message: "id must be an integer"},
+			{File: users, Line: 15, Column: 5, Message: "user 3 has no email"},
+			{File: users, Line: 16, Column: 5, Message: `unknown field "nickname"`},
+		}))
+		Expect(err.Error()).To(HavePrefix(albums + ":3:23: user 9 does not exist\n"))
+	})
+
+	It("reports invalid JSON with its position", func() {
+		path := write("fixtures.json", "{\n  \"users\": [,]\n}")
+
+		_, err := data.LoadFixtures(path)
+		Expect(err).To(MatchError(ContainSubstring(path + ":2:13: invalid character ','")))
+	})
+
+	It("does not accept YAML in JSON files", func() {
+		path := write("fixtures.json", "users: []\n")
+
+		_, err := data.LoadFixtures(path)
+		Expect(err).To(MatchError(ContainSubstring(path + ":1:1: invalid character")))
+	})
+
+	It("reports the positions of problems in NDJSON", func() {
+		path := write("fixtures.ndjson", `{"type": "user", "id": 0, "email": "a@example.com"}
+
+{"id": 1, "email": "b@example.com"}
+{"type": "comment", "id": 0}
+{"type": "album", "id": 0, "userid": 5}
+{"type": "photo", "id": 0,}
+`)
+
+		_, err := data.LoadFixtures(path)
+		Expect(err).To(Equal(data.FixtureErrors{
+			{File: path, Line: 3, Column: 1, Message: "item has no type"},
+			{File: path, Line: 4, Column: 10, Message: "type must be user, album or photo"},
+			{File: path, Line: 5, Column: 38, Message: "user 5 does not exist"},
+			{File: path, Line: 6, Column: 27, Message: "invalid character '}' looking for beginning of object key string"},
+		}))
+	})
+
+	It("reports the positions of problems in CSV", func() {
+		write("users.csv", "id,email,role\n0,a@example.com,\nx,b@example.com,OWNER\n")
+		write("albums.csv", "id,userid\n0,0\n1\n")
+		path := write("other.csv", "id\n0\n")
+
+		_, err := data.LoadFixtures(dir)
+		Expect(err).To(Equal(data.FixtureErrors{
+			{File: filepath.Join(dir, "albums.csv"), Line: 3, Column: 1, Message: "wrong number of fields"},
+			{File: path, Message: "CSV fixture files must be named users.csv, albums.csv or photos.csv"},
+			{File: filepath.Join(dir, "users.csv"), Line: 3, Column: 1, Message: "id must be an integer"},
+			{File: filepath.Join(dir, "users.csv"), Line: 3, Column: 17, Message: "role must be USER or ADMIN"},
+		}))
+	})
+
+	It("reports files of other types", func() {
+		path := write("fixtures.txt", "users: []\n")
+
+		_, err := data.LoadFixtures(path)
+		Expect(err).To(MatchError(path + ": unsupported file type, expected .json, .yaml, .yml, .ndjson or .csv"))
+	})
+
+	It("reports directories without fixtures", func() {
+		_, err := data.LoadFixtures(dir)
+		Expect(err).To(MatchError("no fixture files in " + dir))
+	})
+})
@@ -0,0 +1,272 @@
+package data
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+//go:embed words/*.txt
+var wordFiles embed.FS
+
+var (
+	firstNames   = readWords("first_names.txt")
+	lastNames    = readWords("last_names.txt")
+	adjectives   = readWords("adjectives.txt")
+	nouns        = readWords("nouns.txt")
+	prepositions = readWords("prepositions.txt")
+	domains      = readWords("domains.txt")
+)
+
+func readWords(name string) []string {
+	bytes, err := wordFiles.ReadFile("words/" + name)
+	if err != nil {
+		panic(err)
+	}
+	return strings.Fields(string(bytes))
+}
+
+// Range is an inclusive range of counts.
+type Range struct {
+	Min int
+	Max int
+}
+
+// GeneratorConfig controls the size and shape of a generated dataset.
+// The first Admins users are administrators.
+type GeneratorConfig struct {
+	Seed           int64
+	Users          int
+	Admins         int
+	AlbumsPerUser  Range
+	PhotosPerAlbum Range
+}
+
+// DefaultGeneratorConfig returns 10 users, each with 10 albums of 10 photos.
+// The first user is an administrator.
+func DefaultGeneratorConfig() GeneratorConfig {
+	return GeneratorConfig{
+		Seed:           1,
+		Users:          10,
+		Admins:         1,
+		AlbumsPerUser:  Range{Min: 10, Max: 10},
+		PhotosPerAlbum: Range{Min: 10, Max: 10},
+	}
+}
+
+func (config GeneratorConfig) Validate() error {
+	if config.Users < 0 {
+		return errors.New("number of users must not be negative")
+	}
+	if config.Admins < 0 {
+		return errors.New("number of admins must not be negative")
+	}
+	if err := config.AlbumsPerUser.validate(); err != nil {
+		return fmt.Errorf("albums per user: %w", err)
+	}
+	if err := config.PhotosPerAlbum.validate(); err != nil {
+		return fmt.Errorf("photos per album: %w", err)
+	}
+	return nil
+}
+
+// ParseRange parses a range written as "min-max", or a single number for a fixed count.
+func ParseRange(s string) (Range, error) {
+	var r Range
+	return r, r.Set(s)
+}
+
+func (r Range) String() string {
+	if r.Min == r.Max {
+		return strconv.Itoa(r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// Set implements flag.Value.
+func (r *Range) Set(s string) error {
+	minString, maxString, isRange := strings.Cut(s, "-")
+	if !isRange {
+		maxString = minString
+	}
+
+	min, err := strconv.Atoi(strings.TrimSpace(minString))
+	if err != nil {
+		return fmt.Errorf("invalid range %q", s)
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(maxString))
+	if err != nil {
+		return fmt.Errorf("invalid range %q", s)
+	}
+
+	r.Min, r.Max = min, max
+	return r.validate()
+}
+
+// MarshalText implements encoding.TextMarshaler, writing the range as String does.
+func (r Range) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, reading the range as Set does.
+func (r *Range) UnmarshalText(text []byte) error {
+	return r.Set(string(text))
+}
+
+func (r Range) validate() error {
+	if r.Min < 0 {
+		return errors.New("min must not be negative")
+	}
+	if r.Max < r.Min {
+		return errors.New("max must not be less than min")
+	}
+	return nil
+}
+
+// Generator produces realistic fake values from embedded word lists.
+// Two generators created with the same seed produce the same values.
+type Generator struct {
+	rand      *rand.Rand
+	usernames map[string]bool
+}
+
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		rand:      rand.New(rand.NewSource(seed)),
+		usernames: make(map[string]bool),
+	}
+}
+
+// Generate builds a dataset as described by the config.
+// User passwords are "Password" followed by the user ID.
+func Generate(config GeneratorConfig) (Dataset, error) {
+	if err := config.Validate(); err != nil {
+		return Dataset{}, err
+	}
+
+	generator := NewGenerator(config.Seed)
+	dataset := Dataset{
+		Users:     make([]User, 0, config.Users),
+		Passwords: make(map[int]string, config.Users),
+	}
+
+	for userID := 0; userID < config.Users; userID++ {
+		first, last := generator.FirstName(), generator.LastName()
+		username := generator.Username(first, last)
+		role := RoleUser
+		if userID < config.Admins {
+			role = RoleAdmin
+		}
+		dataset.Users = append(dataset.Users, User{
+			ID:       userID,
+			Name:     first + " " + last,
+			Username: username,
+			Email:    generator.Email(username),
+			Role:     role,
+		})
+		dataset.Passwords[userID] = fmt.Sprint("Password", userID)
+
+		for i := generator.Count(config.AlbumsPerUser); i > 0; i-- {
+			albumID := len(dataset.Albums)
+			dataset.Albums = append(dataset.Albums, Album{
+				ID:          albumID,
+				UserID:      userID,
+				Description: generator.Title(),
+			})
+
+			for j := generator.Count(config.PhotosPerAlbum); j > 0; j-- {
+				dataset.Photos = append(dataset.Photos, Photo{
+					ID:          len(dataset.Photos),
+					AlbumID:     albumID,
+					Description: generator.Sentence(),
+				})
+			}
+		}
+	}
+
+	return dataset, nil
+}
+
+// Count returns a number within the range.
+func (g *Generator) Count(r Range) int {
+	return r.Min + g.rand.Intn(r.Max-r.Min+1)
+}
+
+func (g *Generator) FirstName() string {
+	return g.pick(firstNames)
+}
+
+func (g *Generator) LastName() string {
+	return g.pick(lastNames)
+}
+
+// Username returns a username derived from the name that has not been
+// returned by this generator before.
+func (g *Generator) Username(first, last string) string {
+	first, last = strings.ToLower(first), strings.ToLower(last)
+
+	var base string
+	switch g.rand.Intn(4) {
+	case 0:
+		base = first + "." + last
+	case 1:
+		base = first + "_" + last
+	case 2:
+		base = first[:1] + last
+	default:
+		base = first + last
+	}
+
+	username := base
+	for suffix := g.rand.Intn(100); g.usernames[username]; suffix++ {
+		username = fmt.Sprint(base, suffix)
+	}
+	g.usernames[username] = true
+
+	return username
+}
+
+func (g *Generator) Email(username string) string {
+	return username + "@" + g.pick(domains)
+}
+
+// Title returns a short title, such as "Misty Harbour".
+func (g *Generator) Title() string {
+	switch g.rand.Intn(3) {
+	case 0:
+		return capitalise(g.pick(adjectives)) + " " + capitalise(g.pick(nouns))
+	case 1:
+		return "The " + capitalise(g.pick(adjectives)) + " " + capitalise(g.pick(nouns))
+	default:
+		return capitalise(g.pick(nouns)) + " " + g.pick(prepositions) + " the " + capitalise(g.pick(nouns))
+	}
+}
+
+// Sentence returns a short sentence, such as "A quiet harbour beside the old lighthouse."
+func (g *Generator) Sentence() string {
+	adjective := g.pick(adjectives)
+	return fmt.Sprintf("%s %s %s %s the %s %s.",
+		g.article(adjective), adjective, g.pick(nouns), g.pick(prepositions), g.pick(adjectives), g.pick(nouns))
+}
+
+func (g *Generator) article(next string) string {
+	switch {
+	case g.rand.Intn(2) == 0:
+		return "The"
+	case strings.ContainsAny(next[:1], "aeiou"):
+		return "An"
+	default:
+		return "A"
+	}
+}
+
+func (g *Generator) pick(words []string) string {
+	return words[g.rand.Intn(len(words))]
+}
+
+func capitalise(word string) string {
+	return strings.ToUpper(word[:1]) + word[1:]
+}
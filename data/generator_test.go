@@ -0,0 +1,174 @@
+package data
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generate", func() {
+	var config GeneratorConfig
+
+	BeforeEach(func() {
+		config = DefaultGeneratorConfig()
+	})
+
+	It("generates the configured number of users", func() {
+		config.Users = 25
+
+		dataset, err := Generate(config)
+
+		Expect(err).To(BeNil())
+		Expect(dataset.Users).To(HaveLen(25))
+		Expect(dataset.Passwords).To(HaveLen(25))
+	})
+
+	It("generates the same data for the same seed", func() {
+		first, _ := Generate(config)
+		second, _ := Generate(config)
+
+		firstBytes, _ := json.Marshal(first)
+		secondBytes, _ := json.Marshal(second)
+
+		Expect(firstBytes).To(Equal(secondBytes))
+	})
+
+	It("generates different data for different seeds", func() {
+		first, _ := Generate(config)
+		config.Seed++
+		second, _ := Generate(config)
+
+		Expect(first.Users).ToNot(Equal(second.Users))
+	})
+
+	It("makes the first users administrators", func() {
+		config.Admins = 2
+
+		dataset, _ := Generate(config)
+
+		Expect(dataset.Users[0].Role).To(Equal(RoleAdmin))
+		Expect(dataset.Users[1].Role).To(Equal(RoleAdmin))
+		for _, user := range dataset.Users[2:] {
+			Expect(user.Role).To(Equal(RoleUser))
+		}
+	})
+
+	It("generates unique usernames and emails", func() {
+		config.Users = 1000
+		config.AlbumsPerUser = Range{}
+
+		dataset, _ := Generate(config)
+
+		usernames := make(map[string]bool)
+		emails := make(map[string]bool)
+		for _, user := range dataset.Users {
+			Expect(usernames).ToNot(HaveKey(user.Username))
+			Expect(emails).ToNot(HaveKey(user.Email))
+			usernames[user.Username] = true
+			emails[user.Email] = true
+		}
+	})
+
+	It("generates realistic values", func() {
+		dataset, _ := Generate(config)
+
+		for _, user := range dataset.Users {
+			Expect(user.Name).To(MatchRegexp(`^[A-Z][a-z]+ [A-Z][a-z]+$`))
+			Expect(user.Email).To(MatchRegexp(`^[a-z._0-9]+@[a-z.]+$`))
+		}
+		for _, album := range dataset.Albums {
+			Expect(album.Description).To(MatchRegexp(`^[A-Z].+[a-z]$`))
+		}
+		for _, photo := range dataset.Photos {
+			Expect(photo.Description).To(MatchRegexp(`^(The|A|An) .+\.$`))
+		}
+	})
+
+	It("keeps counts within the configured ranges", func() {
+		config.AlbumsPerUser = Range{Min: 2, Max: 5}
+		config.PhotosPerAlbum = Range{Min: 0, Max: 3}
+
+		dataset, _ := Generate(config)
+
+		albumsPerUser := make(map[int]int)
+		for _, album := range dataset.Albums {
+			albumsPerUser[album.UserID]++
+		}
+		for _, user := range dataset.Users {
+			Expect(albumsPerUser[user.ID]).To(BeNumerically(">=", 2))
+			Expect(albumsPerUser[user.ID]).To(BeNumerically("<=", 5))
+		}
+
+		photosPerAlbum := make(map[int]int)
+		for _, photo := range dataset.Photos {
+			photosPerAlbum[photo.AlbumID]++
+		}
+		for _, album := range dataset.Albums {
+			Expect(photosPerAlbum[album.ID]).To(BeNumerically("<=", 3))
+		}
+	})
+
+	It("references existing users and albums", func() {
+		config.AlbumsPerUser = Range{Min: 0, Max: 4}
+		config.PhotosPerAlbum = Range{Min: 0, Max: 4}
+
+		dataset, _ := Generate(config)
+
+		for i, album := range dataset.Albums {
+			Expect(album.ID).To(Equal(i))
+			Expect(album.UserID).To(BeNumerically("<", len(dataset.Users)))
+		}
+		for i, photo := range dataset.Photos {
+			Expect(photo.ID).To(Equal(i))
+			Expect(photo.AlbumID).To(BeNumerically("<", len(dataset.Albums)))
+		}
+	})
+
+	DescribeTable("rejects invalid configs", func(modify func(config *GeneratorConfig)) {
+		modify(&config)
+
+		_, err := Generate(config)
+
+		Expect(err).ToNot(BeNil())
+	},
+		Entry("negative users", func(config *GeneratorConfig) { config.Users = -1 }),
+		Entry("negative admins", func(config *GeneratorConfig) { config.Admins = -1 }),
+		Entry("negative albums", func(config *GeneratorConfig) { config.AlbumsPerUser = Range{Min: -1, Max: 1} }),
+		Entry("inverted photos", func(config *GeneratorConfig) { config.PhotosPerAlbum = Range{Min: 2, Max: 1} }),
+	)
+})
+
+var _ = Describe("Range", func() {
+	DescribeTable("parses", func(s string, expected Range) {
+		r, err := ParseRange(s)
+
+		Expect(err).To(BeNil())
+		Expect(r).To(Equal(expected))
+		Expect(r.String()).To(Equal(s))
+	},
+		Entry("single number", "5", Range{Min: 5, Max: 5}),
+		Entry("range", "1-10", Range{Min: 1, Max: 10}),
+	)
+
+	DescribeTable("rejects", func(s string) {
+		_, err := ParseRange(s)
+
+		Expect(err).ToNot(BeNil())
+	},
+		Entry("text", "many"),
+		Entry("missing max", "1-"),
+		Entry("inverted", "10-1"),
+	)
+
+	It("is read and written as text", func() {
+		text, err := Range{Min: 1, Max: 3}.MarshalText()
+		Expect(err).To(BeNil())
+		Expect(string(text)).To(Equal("1-3"))
+
+		var r Range
+		Expect(r.UnmarshalText([]byte("2"))).To(Succeed())
+		Expect(r).To(Equal(Range{Min: 2, Max: 2}))
+		Expect(r.UnmarshalText([]byte("3-1"))).ToNot(Succeed())
+	})
+})
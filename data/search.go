@@ -0,0 +1,238 @@
+package data
+
+import (
+	"hash/maphash"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+)
+
+// SearchType is a kind of item that can be searched for.
+type SearchType string
+
+const (
+	SearchUser  SearchType = "USER"
+	SearchAlbum SearchType = "ALBUM"
+	SearchPhoto SearchType = "PHOTO"
+)
+
+// searchTypeOrder breaks ties between equally relevant items of different types.
+var searchTypeOrder = map[SearchType]int{
+	SearchUser:  0,
+	SearchAlbum: 1,
+	SearchPhoto: 2,
+}
+
+// SearchResult is an item matching a search, with its relevance to the search.
+type SearchResult struct {
+	Type  SearchType
+	ID    int
+	Score float64
+}
+
+type searchDocument struct {
+	Type SearchType
+	ID   int
+}
+
+// SearchIndex is an inverted index of the words in the names and usernames of users,
+// and the descriptions of albums and photos.
+// It is not safe for concurrent use while it is being changed.
+type SearchIndex struct {
+	// postings holds the number of times each word appears in each document.
+	postings *utils.CopyOnWriteMap[string, *utils.CopyOnWriteMap[searchDocument, int]]
+	// owned holds the words whose postings are not shared with a copy of the index,
+	// so that they can be changed in place.
+	owned map[string]bool
+	// documents holds the words of each document.
+	documents *utils.CopyOnWriteMap[searchDocument, indexedDocument]
+}
+
+type indexedDocument struct {
+	// length is the number of words in the document.
+	length int
+	// words are the distinct words of the document, so that it can be removed.
+	words []string
+}
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		postings:  utils.NewCopyOnWriteMap[string, *utils.CopyOnWriteMap[searchDocument, int]](shardOfWord),
+		owned:     make(map[string]bool),
+		documents: utils.NewCopyOnWriteMap[searchDocument, indexedDocument](shardOfDocument),
+	}
+}
+
+// Clone returns a copy of the index. Changes to either do not change the other.
+// The copy shares its postings with the original until either changes them, so it is cheap to make.
+func (index *SearchIndex) Clone() *SearchIndex {
+	index.owned = make(map[string]bool)
+
+	return &SearchIndex{
+		postings:  index.postings.Clone(),
+		owned:     make(map[string]bool),
+		documents: index.documents.Clone(),
+	}
+}
+
+// IndexUser adds the user to the index, replacing any previous version of them.
+func (index *SearchIndex) IndexUser(user User) {
+	index.add(searchDocument{SearchUser, user.ID}, user.Name, user.Username)
+}
+
+func (index *SearchIndex) IndexAlbum(album Album) {
+	index.add(searchDocument{SearchAlbum, album.ID}, album.Description)
+}
+
+func (index *SearchIndex) IndexPhoto(photo Photo) {
+	index.add(searchDocument{SearchPhoto, photo.ID}, photo.Description)
+}
+
+func (index *SearchIndex) RemoveUser(id int) {
+	index.remove(searchDocument{SearchUser, id})
+}
+
+func (index *SearchIndex) RemoveAlbum(id int) {
+	index.remove(searchDocument{SearchAlbum, id})
+}
+
+func (index *SearchIndex) RemovePhoto(id int) {
+	index.remove(searchDocument{SearchPhoto, id})
+}
+
+// Search returns the items of the given types, or of any type if none are given,
+// containing any of the words in the text, most relevant first.
+// Words are matched case insensitively. Items are more relevant the more often they
+// contain the words, the rarer the words are, and the shorter the items are.
+func (index *SearchIndex) Search(text string, types []SearchType) []SearchResult {
+	scores := make(map[searchDocument]float64)
+
+	for _, word := range distinct(tokenize(text)) {
+		documents, ok := index.postings.Get(word)
+		if !ok {
+			continue
+		}
+		idf := math.Log(1 + float64(index.documents.Len())/float64(documents.Len()))
+
+		documents.Range(func(document searchDocument, count int) {
+			if len(types) > 0 && !containsType(types, document.Type) {
+				return
+			}
+			indexed, _ := index.documents.Get(document)
+			scores[document] += idf * float64(count) / math.Sqrt(float64(indexed.length))
+		})
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for document, score := range scores {
+		results = append(results, SearchResult{Type: document.Type, ID: document.ID, Score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Type != b.Type {
+			return searchTypeOrder[a.Type] < searchTypeOrder[b.Type]
+		}
+		return a.ID < b.ID
+	})
+
+	return results
+}
+
+func (index *SearchIndex) add(document searchDocument, texts ...string) {
+	index.remove(document)
+
+	var words []string
+	for _, text := range texts {
+		words = append(words, tokenize(text)...)
+	}
+	if len(words) == 0 {
+		return
+	}
+
+	for _, word := range words {
+		documents := index.writablePostings(word)
+		count, _ := documents.Get(document)
+		documents.Set(document, count+1)
+	}
+	index.documents.Set(document, indexedDocument{length: len(words), words: distinct(words)})
+}
+
+func (index *SearchIndex) remove(document searchDocument) {
+	indexed, ok := index.documents.Get(document)
+	if !ok {
+		return
+	}
+
+	for _, word := range indexed.words {
+		documents := index.writablePostings(word)
+		documents.Delete(document)
+		if documents.Len() == 0 {
+			index.postings.Delete(word)
+			delete(index.owned, word)
+		}
+	}
+	index.documents.Delete(document)
+}
+
+// writablePostings returns the postings of the word, copying them first if they are shared.
+func (index *SearchIndex) writablePostings(word string) *utils.CopyOnWriteMap[searchDocument, int] {
+	documents, ok := index.postings.Get(word)
+	switch {
+	case !ok:
+		documents = utils.NewCopyOnWriteMap[searchDocument, int](shardOfDocument)
+	case !index.owned[word]:
+		documents = documents.Clone()
+	default:
+		return documents
+	}
+
+	index.postings.Set(word, documents)
+	index.owned[word] = true
+	return documents
+}
+
+func shardOfDocument(document searchDocument) uint {
+	return uint(document.ID)*uint(len(searchTypeOrder)) + uint(searchTypeOrder[document.Type])
+}
+
+// wordSeed spreads words between the shards of the postings.
+var wordSeed = maphash.MakeSeed()
+
+func shardOfWord(word string) uint {
+	return uint(maphash.String(wordSeed, word))
+}
+
+// tokenize splits the text into lower case words of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func distinct(words []string) []string {
+	seen := make(map[string]bool, len(words))
+	result := make([]string, 0, len(words))
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+func containsType(types []SearchType, t SearchType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
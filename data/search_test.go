@@ -0,0 +1,147 @@
+package data
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SearchIndex", func() {
+	var index *SearchIndex
+
+	documents := func(results []SearchResult) []searchDocument {
+		documents := make([]searchDocument, len(results))
+		for i, result := range results {
+			documents[i] = searchDocument{result.Type, result.ID}
+		}
+		return documents
+	}
+
+	BeforeEach(func() {
+		index = NewSearchIndex()
+		index.IndexUser(User{ID: 0, Name: "Ada Harbour", Username: "ada.harbour"})
+		index.IndexUser(User{ID: 1, Name: "Grace Hopper", Username: "ghopper"})
+		index.IndexAlbum(Album{ID: 0, UserID: 0, Description: "Misty Harbour"})
+		index.IndexAlbum(Album{ID: 1, UserID: 1, Description: "The Quiet Lighthouse"})
+		index.IndexPhoto(Photo{ID: 0, AlbumID: 0, Description: "A quiet harbour beside the old lighthouse."})
+		index.IndexPhoto(Photo{ID: 1, AlbumID: 1, Description: "The old lighthouse above the misty cliffs."})
+	})
+
+	It("finds every type of item", func() {
+		Expect(documents(index.Search("harbour", nil))).To(ConsistOf(
+			searchDocument{SearchUser, 0},
+			searchDocument{SearchAlbum, 0},
+			searchDocument{SearchPhoto, 0},
+		))
+	})
+
+	It("matches words case insensitively", func() {
+		Expect(documents(index.Search("HOPPER", nil))).To(Equal([]searchDocument{{SearchUser, 1}}))
+	})
+
+	It("matches the words of usernames", func() {
+		Expect(documents(index.Search("ghopper", nil))).To(Equal([]searchDocument{{SearchUser, 1}}))
+	})
+
+	It("does not match parts of words", func() {
+		Expect(index.Search("light", nil)).To(BeEmpty())
+	})
+
+	It("finds nothing without words", func() {
+		Expect(index.Search(" .,! ", nil)).To(BeEmpty())
+	})
+
+	It("only finds the given types", func() {
+		Expect(documents(index.Search("harbour lighthouse", []SearchType{SearchAlbum, SearchPhoto}))).To(ConsistOf(
+			searchDocument{SearchAlbum, 0},
+			searchDocument{SearchAlbum, 1},
+			searchDocument{SearchPhoto, 0},
+			searchDocument{SearchPhoto, 1},
+		))
+	})
+
+	It("ranks items matching more of the words first", func() {
+		results := index.Search("quiet lighthouse", nil)
+
+		Expect(documents(results)[:2]).To(ConsistOf(
+			searchDocument{SearchAlbum, 1},
+			searchDocument{SearchPhoto, 0},
+		))
+		Expect(documents(results)[2]).To(Equal(searchDocument{SearchPhoto, 1}))
+	})
+
+	It("ranks shorter items first", func() {
+		Expect(documents(index.Search("misty", nil))).To(Equal([]searchDocument{
+			{SearchAlbum, 0},
+			{SearchPhoto, 1},
+		}))
+	})
+
+	It("ranks items with rarer words first", func() {
+		index.IndexAlbum(Album{ID: 2, Description: "Old Harbour"})
+		index.IndexAlbum(Album{ID: 3, Description: "Old Pier"})
+
+		Expect(documents(index.Search("pier harbour", nil))[0]).To(Equal(searchDocument{SearchAlbum, 3}))
+	})
+
+	It("orders equally relevant items by type, then id", func() {
+		index.IndexPhoto(Photo{ID: 2, Description: "Echo"})
+		index.IndexAlbum(Album{ID: 3, Description: "Echo"})
+		index.IndexAlbum(Album{ID: 2, Description: "Echo"})
+
+		Expect(documents(index.Search("echo", nil))).To(Equal([]searchDocument{
+			{SearchAlbum, 2},
+			{SearchAlbum, 3},
+			{SearchPhoto, 2},
+		}))
+	})
+
+	It("scores more relevant items higher", func() {
+		results := index.Search("misty", nil)
+
+		Expect(results[0].Score).To(BeNumerically(">", results[1].Score))
+	})
+
+	It("replaces items that are indexed again", func() {
+		index.IndexUser(User{ID: 0, Name: "Ada Lovelace", Username: "ada"})
+
+		Expect(documents(index.Search("harbour", nil))).NotTo(ContainElement(searchDocument{SearchUser, 0}))
+		Expect(documents(index.Search("lovelace", nil))).To(Equal([]searchDocument{{SearchUser, 0}}))
+	})
+
+	It("removes items", func() {
+		index.RemoveUser(0)
+		index.RemoveAlbum(0)
+		index.RemovePhoto(0)
+
+		Expect(index.Search("harbour", nil)).To(BeEmpty())
+		_, ok := index.postings.Get("harbour")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("ignores removing items that are not indexed", func() {
+		index.RemovePhoto(42)
+
+		Expect(index.Search("lighthouse", nil)).To(HaveLen(3))
+	})
+
+	It("clones the index", func() {
+		clone := index.Clone()
+		clone.RemoveUser(0)
+		clone.IndexPhoto(Photo{ID: 0, Description: "Ada Lovelace"})
+
+		Expect(documents(index.Search("harbour", nil))).To(ContainElement(searchDocument{SearchUser, 0}))
+		Expect(index.Search("lovelace", nil)).To(BeEmpty())
+		Expect(documents(clone.Search("harbour", nil))).NotTo(ContainElement(searchDocument{SearchUser, 0}))
+		Expect(documents(clone.Search("lovelace", nil))).To(Equal([]searchDocument{{SearchPhoto, 0}}))
+	})
+
+	It("keeps the words of a clone that the original removes", func() {
+		clone := index.Clone()
+		index.RemoveUser(0)
+		index.RemoveAlbum(0)
+		index.RemovePhoto(0)
+
+		Expect(index.Search("harbour", nil)).To(BeEmpty())
+		Expect(clone.Search("harbour", nil)).To(HaveLen(3))
+	})
+})
@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations change the schema of the database, in order. The user_version of the database
+// is the number of migrations that have been applied to it.
+// Released migrations must never be changed; add another one instead.
+var migrations = []string{
+	`CREATE TABLE users (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		name          TEXT NOT NULL,
+		username      TEXT NOT NULL,
+		email         TEXT NOT NULL,
+		password_hash TEXT NOT NULL DEFAULT '',
+		role          TEXT NOT NULL DEFAULT 'USER'
+	);
+	CREATE UNIQUE INDEX users_email ON users (email);
+
+	CREATE TABLE albums (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		description TEXT NOT NULL
+	);
+	CREATE INDEX albums_user_id ON albums (user_id);
+
+	CREATE TABLE photos (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		album_id    INTEGER NOT NULL REFERENCES albums (id) ON DELETE CASCADE,
+		description TEXT NOT NULL
+	);
+	CREATE INDEX photos_album_id ON photos (album_id);`,
+}
+
+// migrate applies the migrations that have not been applied to the database yet.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than the latest known version %d", version, len(migrations))
+	}
+
+	for ; version < len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+	}
+
+	return nil
+}
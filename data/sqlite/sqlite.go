@@ -0,0 +1,719 @@
+// Package sqlite stores the data in a SQLite database, so that it survives restarts
+// and can be inspected with standard tools.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/utils"
+	"golang.org/x/crypto/bcrypt"
+	_ "modernc.org/sqlite"
+)
+
+// batchSize is the most IDs that are looked up by a single query, well below SQLite's limit on parameters.
+const batchSize = 500
+
+const (
+	userColumns  = `id, name, username, email, password_hash, role`
+	albumColumns = `id, user_id, description`
+	photoColumns = `id, album_id, description`
+)
+
+// Store is a data.IWritableData backed by a SQLite database, which is safe for concurrent use.
+// It is a data.ISnapshotData, whose snapshots hold a read transaction until they are closed.
+//
+// Searches are answered by a data.SearchIndex, which is built when the store is opened
+// and kept up to date by writes.
+type Store struct {
+	reader
+	db *sql.DB
+
+	// mu serialises writes, so that the index is changed in the same order as the database,
+	// and guards the index.
+	mu    sync.RWMutex
+	index *data.SearchIndex
+}
+
+// reader reads the data through the database, or through a transaction.
+type reader struct {
+	q queryer
+}
+
+// Open opens the database at the path, creating it if it does not exist,
+// and migrates it to the latest schema.
+func Open(path string) (*Store, error) {
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Set("_txlock", "immediate")
+
+	// The path is opaque, so that it is not parsed as a URL, but it is escaped, so that a ? or # in it is not
+	// taken for the start of the query or fragment.
+	dsn := url.URL{Scheme: "file", Opaque: escapePath(path), RawQuery: query.Encode()}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+
+	store := &Store{reader: reader{db}, db: db, index: data.NewSearchIndex()}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := store.buildIndex(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Close closes the database.
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+// Seed fills the empty database with the dataset, hashing the passwords of its users at the bcrypt cost.
+func (store *Store) Seed(dataset data.Dataset, passwordCost int) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		var seeded bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users)`).Scan(&seeded); err != nil {
+			return err
+		}
+		if seeded {
+			return errors.New("the database has already been seeded")
+		}
+
+		insertUser, err := tx.Prepare(`INSERT INTO users (` + userColumns + `) VALUES (?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer insertUser.Close()
+
+		for _, user := range dataset.Users {
+			if password, ok := dataset.Passwords[user.ID]; ok {
+				hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+				if err != nil {
+					return err
+				}
+				user.PasswordHash = string(hashedPass)
+			}
+			if _, err := insertUser.Exec(user.ID, user.Name, user.Username, user.Email, user.PasswordHash, user.Role); err != nil {
+				return fmt.Errorf("user %d: %w", user.ID, err)
+			}
+		}
+
+		insertAlbum, err := tx.Prepare(`INSERT INTO albums (` + albumColumns + `) VALUES (?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer insertAlbum.Close()
+
+		for _, album := range dataset.Albums {
+			if _, err := insertAlbum.Exec(album.ID, album.UserID, album.Description); err != nil {
+				return fmt.Errorf("album %d: %w", album.ID, err)
+			}
+		}
+
+		insertPhoto, err := tx.Prepare(`INSERT INTO photos (` + photoColumns + `) VALUES (?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer insertPhoto.Close()
+
+		for _, photo := range dataset.Photos {
+			if _, err := insertPhoto.Exec(photo.ID, photo.AlbumID, photo.Description); err != nil {
+				return fmt.Errorf("photo %d: %w", photo.ID, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, user := range dataset.Users {
+		store.index.IndexUser(user)
+	}
+	for _, album := range dataset.Albums {
+		store.index.IndexAlbum(album)
+	}
+	for _, photo := range dataset.Photos {
+		store.index.IndexPhoto(photo)
+	}
+
+	return nil
+}
+
+func (r reader) GetUsers() ([]data.User, error) {
+	return list(r.q, scanUser, `SELECT `+userColumns+` FROM users ORDER BY id`)
+}
+
+func (r reader) GetAlbums() ([]data.Album, error) {
+	return list(r.q, scanAlbum, `SELECT `+albumColumns+` FROM albums ORDER BY id`)
+}
+
+func (r reader) GetPhotos() ([]data.Photo, error) {
+	return list(r.q, scanPhoto, `SELECT `+photoColumns+` FROM photos ORDER BY id`)
+}
+
+func (r reader) GetUser(id int) (data.User, error) {
+	return getUser(r.q, id)
+}
+
+func (r reader) GetAlbum(id int) (data.Album, error) {
+	return getAlbum(r.q, id)
+}
+
+func (r reader) GetPhoto(id int) (data.Photo, error) {
+	return getPhoto(r.q, id)
+}
+
+func (r reader) GetUserWithEmail(email string) (*data.User, error) {
+	user, err := scanUser(r.q.QueryRow(`SELECT `+userColumns+` FROM users WHERE email = ?`, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user with email %s %w", email, data.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r reader) GetAlbumsByUserID(userID int) ([]data.Album, error) {
+	return list(r.q, scanAlbum, `SELECT `+albumColumns+` FROM albums WHERE user_id = ? ORDER BY id`, userID)
+}
+
+func (r reader) GetPhotosByAlbumID(albumID int) ([]data.Photo, error) {
+	return list(r.q, scanPhoto, `SELECT `+photoColumns+` FROM photos WHERE album_id = ? ORDER BY id`, albumID)
+}
+
+func (r reader) GetAlbumsByUserIDs(userIDs []int) (map[int][]data.Album, error) {
+	albums, err := listIn(r.q, scanAlbum, `SELECT `+albumColumns+` FROM albums WHERE user_id IN (%s) ORDER BY id`, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	return utils.GroupBy(albums, userIDs, func(album data.Album) int {
+		return album.UserID
+	}), nil
+}
+
+func (r reader) GetPhotosByAlbumIDs(albumIDs []int) (map[int][]data.Photo, error) {
+	photos, err := listIn(r.q, scanPhoto, `SELECT `+photoColumns+` FROM photos WHERE album_id IN (%s) ORDER BY id`, albumIDs)
+	if err != nil {
+		return nil, err
+	}
+	return utils.GroupBy(photos, albumIDs, func(photo data.Photo) int {
+		return photo.AlbumID
+	}), nil
+}
+
+func (store *Store) Search(text string, types []data.SearchType) ([]data.SearchResult, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return store.index.Search(text, types), nil
+}
+
+// Snapshot returns a view of the data as it is now, which reads it in a read-only transaction.
+// The snapshot is an io.Closer, which must be closed to end the transaction.
+func (store *Store) Snapshot() data.IData {
+	// Writes are held off until the transaction has read from the database, which fixes the data
+	// that it sees, so that the index is cloned as of the same write.
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	tx, err := store.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return failedSnapshot{err}
+	}
+	var seeded bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users)`).Scan(&seeded); err != nil {
+		tx.Rollback()
+		return failedSnapshot{err}
+	}
+
+	return &snapshot{reader: reader{tx}, tx: tx, index: store.index.Clone()}
+}
+
+// snapshot is a view of the store as of the start of its transaction.
+type snapshot struct {
+	reader
+	tx    *sql.Tx
+	index *data.SearchIndex
+}
+
+func (snapshot *snapshot) Search(text string, types []data.SearchType) ([]data.SearchResult, error) {
+	return snapshot.index.Search(text, types), nil
+}
+
+// Close ends the transaction of the snapshot.
+func (snapshot *snapshot) Close() error {
+	return snapshot.tx.Rollback()
+}
+
+// failedSnapshot is a snapshot whose transaction could not be begun, every read of which returns the error.
+type failedSnapshot struct {
+	err error
+}
+
+func (snapshot failedSnapshot) GetUsers() ([]data.User, error) {
+	return nil, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetAlbums() ([]data.Album, error) {
+	return nil, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetPhotos() ([]data.Photo, error) {
+	return nil, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetUser(id int) (data.User, error) {
+	return data.User{}, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetAlbum(id int) (data.Album, error) {
+	return data.Album{}, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetPhoto(id int) (data.Photo, error) {
+	return data.Photo{}, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetUserWithEmail(email string) (*data.User, error) {
+	return nil, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetAlbumsByUserID(userID int) ([]data.Album, error) {
+	return nil, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetPhotosByAlbumID(albumID int) ([]data.Photo, error) {
+	return nil, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetAlbumsByUserIDs(userIDs []int) (map[int][]data.Album, error) {
+	return nil, snapshot.err
+}
+
+func (snapshot failedSnapshot) GetPhotosByAlbumIDs(albumIDs []int) (map[int][]data.Photo, error) {
+	return nil, snapshot.err
+}
+
+func (snapshot failedSnapshot) Search(text string, types []data.SearchType) ([]data.SearchResult, error) {
+	return nil, snapshot.err
+}
+
+func (store *Store) CreateUser(user data.User) (data.User, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		if err := checkEmailFree(tx, user.Email, -1); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`INSERT INTO users (name, username, email, password_hash, role) VALUES (?, ?, ?, ?, ?)`,
+			user.Name, user.Username, user.Email, user.PasswordHash, user.Role)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		user.ID = int(id)
+		return err
+	})
+	if err != nil {
+		return data.User{}, err
+	}
+
+	store.index.IndexUser(user)
+	return user, nil
+}
+
+func (store *Store) UpdateUser(user data.User) (data.User, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		if _, err := getUser(tx, user.ID); err != nil {
+			return err
+		}
+		if err := checkEmailFree(tx, user.Email, user.ID); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`UPDATE users SET name = ?, username = ?, email = ?, password_hash = ?, role = ? WHERE id = ?`,
+			user.Name, user.Username, user.Email, user.PasswordHash, user.Role, user.ID)
+		return err
+	})
+	if err != nil {
+		return data.User{}, err
+	}
+
+	store.index.IndexUser(user)
+	return user, nil
+}
+
+// DeleteUser deletes the user along with their albums and photos.
+func (store *Store) DeleteUser(id int) (data.User, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var user data.User
+	var albumIDs, photoIDs []int
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		var err error
+		if user, err = getUser(tx, id); err != nil {
+			return err
+		}
+		if albumIDs, err = list(tx, scanID, `SELECT id FROM albums WHERE user_id = ?`, id); err != nil {
+			return err
+		}
+		if photoIDs, err = list(tx, scanID, `SELECT photos.id FROM photos JOIN albums ON photos.album_id = albums.id WHERE albums.user_id = ?`, id); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM users WHERE id = ?`, id)
+		return err
+	})
+	if err != nil {
+		return data.User{}, err
+	}
+
+	for _, photoID := range photoIDs {
+		store.index.RemovePhoto(photoID)
+	}
+	for _, albumID := range albumIDs {
+		store.index.RemoveAlbum(albumID)
+	}
+	store.index.RemoveUser(id)
+
+	return user, nil
+}
+
+func (store *Store) CreateAlbum(album data.Album) (data.Album, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		if _, err := getUser(tx, album.UserID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`INSERT INTO albums (user_id, description) VALUES (?, ?)`, album.UserID, album.Description)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		album.ID = int(id)
+		return err
+	})
+	if err != nil {
+		return data.Album{}, err
+	}
+
+	store.index.IndexAlbum(album)
+	return album, nil
+}
+
+func (store *Store) UpdateAlbum(album data.Album) (data.Album, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		if _, err := getAlbum(tx, album.ID); err != nil {
+			return err
+		}
+		if _, err := getUser(tx, album.UserID); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`UPDATE albums SET user_id = ?, description = ? WHERE id = ?`, album.UserID, album.Description, album.ID)
+		return err
+	})
+	if err != nil {
+		return data.Album{}, err
+	}
+
+	store.index.IndexAlbum(album)
+	return album, nil
+}
+
+// DeleteAlbum deletes the album along with its photos.
+func (store *Store) DeleteAlbum(id int) (data.Album, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var album data.Album
+	var photoIDs []int
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		var err error
+		if album, err = getAlbum(tx, id); err != nil {
+			return err
+		}
+		if photoIDs, err = list(tx, scanID, `SELECT id FROM photos WHERE album_id = ?`, id); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM albums WHERE id = ?`, id)
+		return err
+	})
+	if err != nil {
+		return data.Album{}, err
+	}
+
+	for _, photoID := range photoIDs {
+		store.index.RemovePhoto(photoID)
+	}
+	store.index.RemoveAlbum(id)
+
+	return album, nil
+}
+
+func (store *Store) CreatePhoto(photo data.Photo) (data.Photo, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		if _, err := getAlbum(tx, photo.AlbumID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`INSERT INTO photos (album_id, description) VALUES (?, ?)`, photo.AlbumID, photo.Description)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		photo.ID = int(id)
+		return err
+	})
+	if err != nil {
+		return data.Photo{}, err
+	}
+
+	store.index.IndexPhoto(photo)
+	return photo, nil
+}
+
+func (store *Store) UpdatePhoto(photo data.Photo) (data.Photo, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		if _, err := getPhoto(tx, photo.ID); err != nil {
+			return err
+		}
+		if _, err := getAlbum(tx, photo.AlbumID); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`UPDATE photos SET album_id = ?, description = ? WHERE id = ?`, photo.AlbumID, photo.Description, photo.ID)
+		return err
+	})
+	if err != nil {
+		return data.Photo{}, err
+	}
+
+	store.index.IndexPhoto(photo)
+	return photo, nil
+}
+
+func (store *Store) DeletePhoto(id int) (data.Photo, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var photo data.Photo
+
+	err := store.transaction(func(tx *sql.Tx) error {
+		var err error
+		if photo, err = getPhoto(tx, id); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM photos WHERE id = ?`, id)
+		return err
+	})
+	if err != nil {
+		return data.Photo{}, err
+	}
+
+	store.index.RemovePhoto(id)
+	return photo, nil
+}
+
+// buildIndex indexes every item in the database.
+func (store *Store) buildIndex() error {
+	users, err := list(store.db, scanUser, `SELECT `+userColumns+` FROM users`)
+	if err != nil {
+		return err
+	}
+	albums, err := list(store.db, scanAlbum, `SELECT `+albumColumns+` FROM albums`)
+	if err != nil {
+		return err
+	}
+	photos, err := list(store.db, scanPhoto, `SELECT `+photoColumns+` FROM photos`)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		store.index.IndexUser(user)
+	}
+	for _, album := range albums {
+		store.index.IndexAlbum(album)
+	}
+	for _, photo := range photos {
+		store.index.IndexPhoto(photo)
+	}
+
+	return nil
+}
+
+// transaction runs f in a transaction, which is committed if f succeeds and rolled back otherwise.
+func (store *Store) transaction(f func(tx *sql.Tx) error) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row scanner) (data.User, error) {
+	var user data.User
+	err := row.Scan(&user.ID, &user.Name, &user.Username, &user.Email, &user.PasswordHash, &user.Role)
+	return user, err
+}
+
+func scanAlbum(row scanner) (data.Album, error) {
+	var album data.Album
+	err := row.Scan(&album.ID, &album.UserID, &album.Description)
+	return album, err
+}
+
+func scanPhoto(row scanner) (data.Photo, error) {
+	var photo data.Photo
+	err := row.Scan(&photo.ID, &photo.AlbumID, &photo.Description)
+	return photo, err
+}
+
+func scanID(row scanner) (int, error) {
+	var id int
+	err := row.Scan(&id)
+	return id, err
+}
+
+func getUser(q queryer, id int) (data.User, error) {
+	user, err := scanUser(q.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return data.User{}, fmt.Errorf("user %d %w", id, data.ErrNotFound)
+	}
+	return user, err
+}
+
+func getAlbum(q queryer, id int) (data.Album, error) {
+	album, err := scanAlbum(q.QueryRow(`SELECT `+albumColumns+` FROM albums WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return data.Album{}, fmt.Errorf("album %d %w", id, data.ErrNotFound)
+	}
+	return album, err
+}
+
+func getPhoto(q queryer, id int) (data.Photo, error) {
+	photo, err := scanPhoto(q.QueryRow(`SELECT `+photoColumns+` FROM photos WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return data.Photo{}, fmt.Errorf("photo %d %w", id, data.ErrNotFound)
+	}
+	return photo, err
+}
+
+// checkEmailFree returns data.ErrEmailTaken if a user other than the one with the ID has the email.
+func checkEmailFree(q queryer, email string, id int) error {
+	var taken bool
+	if err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE email = ? AND id != ?)`, email, id).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return data.ErrEmailTaken
+	}
+	return nil
+}
+
+// list returns every row of the query.
+func list[T any](q queryer, scan func(scanner) (T, error), query string, args ...interface{}) ([]T, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]T, 0)
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// listIn returns every row of the query, whose %s is replaced by the placeholders of a batch of the IDs,
+// running the query once for each batch.
+func listIn[T any](q queryer, scan func(scanner) (T, error), query string, ids []int) ([]T, error) {
+	items := make([]T, 0)
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:]
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+
+		batchItems, err := list(q, scan, fmt.Sprintf(query, placeholders), args...)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batchItems...)
+	}
+	return items, nil
+}
+
+// escapePath escapes the characters of the path that would end it in a URL, leaving the rest as they are.
+func escapePath(path string) string {
+	return strings.NewReplacer("%", "%25", "?", "%3F", "#", "%23").Replace(path)
+}
@@ -0,0 +1,289 @@
+package sqlite_test
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/api"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data/conformance"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data/sqlite"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
+	_ "modernc.org/sqlite"
+)
+
+var _ = conformance.DescribeData("Store", func(dataset data.Dataset) (data.IData, error) {
+	store, err := sqlite.Open(filepath.Join(GinkgoT().TempDir(), "data.db"))
+	if err != nil {
+		return nil, err
+	}
+	DeferCleanup(store.Close)
+	return store, store.Seed(dataset, bcrypt.MinCost)
+})
+
+var _ = Describe("Store", func() {
+	var path string
+	var store *sqlite.Store
+	var reference data.IWritableData
+
+	config := data.DefaultGeneratorConfig()
+	config.Users = 4
+	config.AlbumsPerUser = data.Range{Min: 1, Max: 3}
+	config.PhotosPerAlbum = data.Range{Min: 0, Max: 3}
+
+	open := func() *sqlite.Store {
+		store, err := sqlite.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		return store
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "data.db")
+		store = open()
+		DeferCleanup(func() { store.Close() })
+
+		dataset, err := data.Generate(config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.Seed(dataset, bcrypt.MinCost)).To(Succeed())
+
+		reference, err = api.NewTestDataWithConfig(config)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// withoutPasswords clears the password hashes, which differ between stores as they are salted.
+	withoutPasswords := func(users []data.User) []data.User {
+		for i := range users {
+			users[i].PasswordHash = ""
+		}
+		return users
+	}
+
+	expectSameData := func(store data.IData) {
+		Expect(withoutPasswords(must(store.GetUsers()))).To(Equal(withoutPasswords(must(reference.GetUsers()))))
+		Expect(store.GetAlbums()).To(Equal(must(reference.GetAlbums())))
+		Expect(store.GetPhotos()).To(Equal(must(reference.GetPhotos())))
+
+		userIDs := []int{-1}
+		for _, user := range must(reference.GetUsers()) {
+			userIDs = append(userIDs, user.ID)
+			Expect(store.GetAlbumsByUserID(user.ID)).To(Equal(must(reference.GetAlbumsByUserID(user.ID))))
+		}
+		albumIDs := []int{-1}
+		for _, album := range must(reference.GetAlbums()) {
+			albumIDs = append(albumIDs, album.ID)
+			Expect(store.GetPhotosByAlbumID(album.ID)).To(Equal(must(reference.GetPhotosByAlbumID(album.ID))))
+		}
+		Expect(store.GetAlbumsByUserIDs(userIDs)).To(Equal(must(reference.GetAlbumsByUserIDs(userIDs))))
+		Expect(store.GetPhotosByAlbumIDs(albumIDs)).To(Equal(must(reference.GetPhotosByAlbumIDs(albumIDs))))
+
+		for _, text := range []string{"user", "album", "photo", must(reference.GetUsers())[0].Name} {
+			Expect(store.Search(text, nil)).To(Equal(must(reference.Search(text, nil))))
+		}
+	}
+
+	It("stores the generated data", func() {
+		expectSameData(store)
+	})
+
+	It("hashes the passwords of seeded users at the cost", func() {
+		dataset, err := data.Generate(config)
+		Expect(err).ToNot(HaveOccurred())
+
+		for id, password := range dataset.Passwords {
+			user := must(store.GetUser(id))
+			Expect(bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))).To(Succeed())
+			Expect(bcrypt.Cost([]byte(user.PasswordHash))).To(Equal(bcrypt.MinCost))
+		}
+	})
+
+	It("makes the same changes as the in-memory data", func() {
+		for _, dataModel := range []data.IWritableData{store, reference} {
+			user := must(dataModel.CreateUser(data.User{Name: "New", Username: "new", Email: "new@example.com", Role: data.RoleUser}))
+			album := must(dataModel.CreateAlbum(data.Album{UserID: user.ID, Description: "New album"}))
+			must(dataModel.CreatePhoto(data.Photo{AlbumID: album.ID, Description: "New photo"}))
+
+			user.Name = "Renamed"
+			must(dataModel.UpdateUser(user))
+			must(dataModel.UpdateAlbum(data.Album{ID: 0, UserID: user.ID, Description: "Moved album"}))
+			must(dataModel.UpdatePhoto(data.Photo{ID: 0, AlbumID: album.ID, Description: "Moved photo"}))
+
+			must(dataModel.DeleteUser(1))
+			must(dataModel.DeleteAlbum(must(dataModel.GetAlbumsByUserID(2))[0].ID))
+			must(dataModel.DeletePhoto(0))
+		}
+
+		expectSameData(store)
+	})
+
+	It("keeps the data when reopened", func() {
+		must(store.DeleteUser(0))
+		Expect(store.Close()).To(Succeed())
+		must(reference.DeleteUser(0))
+
+		store = open()
+
+		expectSameData(store)
+	})
+
+	It("does not reuse the IDs of deleted items when reopened", func() {
+		users := must(reference.GetUsers())
+		last := users[len(users)-1]
+		must(store.DeleteUser(last.ID))
+		Expect(store.Close()).To(Succeed())
+
+		store = open()
+
+		Expect(must(store.CreateUser(data.User{Email: "new@example.com"})).ID).To(Equal(last.ID + 1))
+	})
+
+	It("opens the file at a path that would be a URL query or fragment", func() {
+		Expect(store.Close()).To(Succeed())
+		dir := GinkgoT().TempDir()
+		path = filepath.Join(dir, "data?mode=ro#1.db")
+
+		store = open()
+		must(store.CreateUser(data.User{Email: "new@example.com"}))
+
+		Expect(path).To(BeAnExistingFile())
+		Expect(filepath.Join(dir, "data")).ToNot(BeAnExistingFile())
+	})
+
+	It("returns the error of a query that fails", func() {
+		Expect(store.Close()).To(Succeed())
+
+		_, err := store.GetUsers()
+		Expect(err).To(MatchError(ContainSubstring("database is closed")))
+		_, err = store.GetAlbumsByUserID(0)
+		Expect(err).To(HaveOccurred())
+		_, err = store.GetPhotosByAlbumIDs([]int{0, 1})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("ends the transaction of a snapshot when it is closed", func() {
+		snapshot := store.Snapshot()
+		must(store.DeleteUser(0))
+		Expect(snapshot.GetUser(0)).ToNot(BeZero())
+
+		Expect(snapshot.(io.Closer).Close()).To(Succeed())
+
+		_, err := snapshot.GetUser(0)
+		Expect(err).To(MatchError(sql.ErrTxDone))
+	})
+
+	It("returns the error of a snapshot that cannot be taken", func() {
+		Expect(store.Close()).To(Succeed())
+
+		snapshot := store.Snapshot()
+
+		_, err := snapshot.GetUsers()
+		Expect(err).To(MatchError(ContainSubstring("database is closed")))
+		_, err = snapshot.Search("a", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("refuses to seed a database that has data", func() {
+		Expect(store.Seed(data.Dataset{Users: []data.User{{ID: 100, Email: "new@example.com"}}}, bcrypt.MinCost)).ToNot(Succeed())
+		_, err := store.GetUser(100)
+		Expect(err).To(MatchError(data.ErrNotFound))
+	})
+
+	Describe("schema", func() {
+		var db *sql.DB
+
+		BeforeEach(func() {
+			var err error
+			db, err = sql.Open("sqlite", path+"?_pragma=foreign_keys(1)")
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(db.Close)
+		})
+
+		userVersion := func() int {
+			var version int
+			Expect(db.QueryRow(`PRAGMA user_version`).Scan(&version)).To(Succeed())
+			return version
+		}
+
+		It("is migrated once", func() {
+			version := userVersion()
+			Expect(version).To(BeNumerically(">", 0))
+
+			Expect(store.Close()).To(Succeed())
+			store = open()
+
+			Expect(userVersion()).To(Equal(version))
+			expectSameData(store)
+		})
+
+		It("is not opened if it is newer than the store", func() {
+			_, err := db.Exec(`PRAGMA user_version = 1000`)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = sqlite.Open(path)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("enforces foreign keys", func() {
+			_, err := db.Exec(`INSERT INTO albums (user_id, description) VALUES (-1, '')`)
+			Expect(err).To(HaveOccurred())
+			_, err = db.Exec(`INSERT INTO photos (album_id, description) VALUES (-1, '')`)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("enforces unique emails", func() {
+			_, err := db.Exec(`INSERT INTO users (name, username, email) VALUES ('', '', ?)`, must(reference.GetUser(0)).Email)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("indexes the foreign keys and emails", func() {
+			indexes := map[string]string{}
+			rows, err := db.Query(`SELECT name, tbl_name FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL`)
+			Expect(err).ToNot(HaveOccurred())
+			defer rows.Close()
+			for rows.Next() {
+				var name, table string
+				Expect(rows.Scan(&name, &table)).To(Succeed())
+				indexes[name] = table
+			}
+
+			Expect(indexes).To(Equal(map[string]string{
+				"users_email":     "users",
+				"albums_user_id":  "albums",
+				"photos_album_id": "photos",
+			}))
+		})
+	})
+})
+
+var _ = Describe("Batched lookups", func() {
+	It("look up more IDs than fit in one query", func() {
+		store, err := sqlite.Open(filepath.Join(GinkgoT().TempDir(), "data.db"))
+		Expect(err).ToNot(HaveOccurred())
+		defer store.Close()
+
+		dataset := data.Dataset{}
+		userIDs := make([]int, 1200)
+		for id := range userIDs {
+			userIDs[id] = id
+			dataset.Users = append(dataset.Users, data.User{ID: id, Email: fmt.Sprintf("user%d@example.com", id)})
+			dataset.Albums = append(dataset.Albums, data.Album{ID: id, UserID: id})
+		}
+		Expect(store.Seed(dataset, bcrypt.MinCost)).To(Succeed())
+
+		albums := must(store.GetAlbumsByUserIDs(userIDs))
+		Expect(albums).To(HaveLen(len(userIDs)))
+		for _, id := range userIDs {
+			Expect(albums[id]).To(Equal([]data.Album{{ID: id, UserID: id}}))
+		}
+	})
+})
+
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
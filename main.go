@@ -1,25 +1,338 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"net/http"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/api"
-	"github.com/graphql-go/handler"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/config"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data/sqlite"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/server"
+	"golang.org/x/crypto/bcrypt"
 )
 
-func main() {
-	data := api.NewTestData()
-	auth := api.NewAuthenticationProvider()
-	api := api.NewAPI(data, auth)
-
-	h := handler.New(&handler.Config{
-		Schema:   &api.Schema,
-		Pretty:   true,
-		GraphiQL: true,
+type command struct {
+	run     func(args []string) error
+	summary string
+}
+
+// errDone is returned by loadConfig if the command has nothing more to do, as it was asked for its help
+// or to print its configuration.
+var errDone = errors.New("done")
+
+// errUsage is returned by loadConfig if the flags or configuration are invalid, which it has already reported.
+var errUsage = errors.New("invalid usage")
+
+// commands are chosen by the first argument. The server is started if there is no command,
+// so that it can be given flags without one.
+var commands = map[string]command{
+	"serve":    {serve, "serve the GraphQL API"},
+	"generate": {generate, "write generated data as JSON, NDJSON or CSV"},
+	"export":   {export, "write the data of a database or fixtures as JSON, NDJSON or CSV"},
+	"schema":   {schema, "print the schema of the API in the GraphQL schema definition language"},
+	"seed":     {seed, "fill a new SQLite database with generated data, or with fixtures"},
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nThe command is serve if none is given. Run a command with -h for its flags.\n")
+}
+
+// newFlags returns the flags of a command, which defines the flags of the configuration groups it uses.
+func newFlags(name string, groups config.Group) (*flag.FlagSet, *config.Flags) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\nSettings are read from the config file, then from %sNAME environment variables, then from the flags.\n\n", os.Args[0], name, config.EnvPrefix)
+		flags.PrintDefaults()
+	}
+	return flags, config.NewFlags(flags, groups)
+}
+
+// loadConfig loads the configuration of a command, along with the errors of the checks of its own flags.
+// It returns errUsage if there are any errors, and errDone if the configuration was only to be printed.
+func loadConfig(flags *config.Flags, args []string, checks ...func() error) (config.Config, error) {
+	cfg, err := flags.Load(args, os.LookupEnv)
+
+	var errs config.Errors
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return config.Config{}, errDone
+	case errors.As(err, &errs):
+	case err != nil:
+		// The flag set has reported it.
+		return config.Config{}, errUsage
+	}
+
+	for _, check := range checks {
+		if err := check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		fmt.Fprintln(os.Stderr, "invalid configuration:")
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, "  "+err.Error())
+		}
+		return config.Config{}, errUsage
+	}
+
+	if flags.PrintConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			return config.Config{}, err
+		}
+		return config.Config{}, errDone
+	}
+	return cfg, nil
+}
+
+// loadDataset loads the fixtures, or otherwise generates the dataset.
+func loadDataset(cfg config.DataConfig) (data.Dataset, error) {
+	if cfg.Fixtures != "" {
+		return data.LoadFixtures(cfg.Fixtures)
+	}
+	return data.Generate(cfg.GeneratorConfig())
+}
+
+// passwordCost returns the bcrypt cost to hash the passwords of the dataset at. Generated passwords are
+// no secret, as the generate command writes them, so they are hashed at the minimum cost to start quickly.
+func passwordCost(cfg config.Config) int {
+	if cfg.Data.Fixtures == "" {
+		return bcrypt.MinCost
+	}
+	return cfg.Auth.PasswordCost
+}
+
+// openData opens the SQLite database, or the fixtures, or otherwise generated data,
+// along with a function that closes it.
+func openData(cfg config.Config) (data.IWritableData, func() error, error) {
+	if cfg.Data.DB != "" {
+		store, err := sqlite.Open(cfg.Data.DB)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, store.Close, nil
+	}
+
+	dataset, err := loadDataset(cfg.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	testData, err := api.NewTestDataWithDataset(dataset, passwordCost(cfg))
+	if err != nil {
+		return nil, nil, err
+	}
+	return testData, func() error { return nil }, nil
+}
+
+// serve serves the GraphQL API until it is stopped by SIGINT or SIGTERM, when it finishes the requests
+// in flight and closes the data. It returns an error if the server fails to start or to shut down.
+func serve(args []string) error {
+	_, configFlags := newFlags("serve", config.Server|config.Database|config.Fixtures|config.Generator|config.Auth|config.Passwords|config.Features)
+	cfg, err := loadConfig(configFlags, args)
+	if err != nil {
+		return err
+	}
+
+	dataModel, closeData, err := openData(cfg)
+	if err != nil {
+		return err
+	}
+
+	srv, err := server.New(cfg, dataModel)
+	if err == nil {
+		err = srv.Start()
+	}
+	if err != nil {
+		closeData()
+		return err
+	}
+
+	fmt.Printf("Starting server at %s\n", srv.URL())
+	if err := srv.Wait(context.Background()); err != nil {
+		return err
+	}
+	fmt.Println("Server stopped")
+	return nil
+}
+
+// outputFlags defines the flags choosing the format and destination of a dataset. The returned
+// function checks them once they have been parsed.
+func outputFlags(flags *flag.FlagSet) (format, output *string, check func() error) {
+	format = flags.String("format", "json", "format to write, one of json, ndjson or csv")
+	output = flags.String("o", "-", "file to write JSON or NDJSON to, - for standard output, or directory to write CSV files to")
+
+	return format, output, func() error {
+		switch *format {
+		case "json", "ndjson":
+			return nil
+		case "csv":
+			if *output == "-" {
+				return errors.New("the directory to write CSV files to must be given with -o")
+			}
+			return nil
+		}
+		return fmt.Errorf("unknown format %q, expected json, ndjson or csv", *format)
+	}
+}
+
+// writeDataset writes the dataset as JSON or NDJSON to a file or standard output, or as CSV files to a directory.
+func writeDataset(format, output string, dataset data.Dataset) error {
+	if format == "csv" {
+		return data.WriteCSV(output, dataset)
+	}
+
+	write := data.WriteJSON
+	if format == "ndjson" {
+		write = data.WriteNDJSON
+	}
+	return writeOutput(output, func(w io.Writer) error { return write(w, dataset) })
+}
+
+// writeOutput writes to a file, or to standard output if the file is "-".
+func writeOutput(output string, write func(w io.Writer) error) error {
+	if output == "-" {
+		return write(os.Stdout)
+	}
+
+	w, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := write(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// generate writes generated data, including the passwords of the users so that they can sign in.
+func generate(args []string) error {
+	flags, configFlags := newFlags("generate", config.Generator)
+	format, output, checkOutput := outputFlags(flags)
+	cfg, err := loadConfig(configFlags, args, checkOutput)
+	if err != nil {
+		return err
+	}
+
+	dataset, err := data.Generate(cfg.Data.GeneratorConfig())
+	if err != nil {
+		return err
+	}
+	return writeDataset(*format, *output, dataset)
+}
+
+// export writes the data of a database, fixtures or otherwise generated data.
+func export(args []string) error {
+	flags, configFlags := newFlags("export", config.Database|config.Fixtures|config.Generator|config.Passwords)
+	format, output, checkOutput := outputFlags(flags)
+	cfg, err := loadConfig(configFlags, args, checkOutput)
+	if err != nil {
+		return err
+	}
+
+	dataModel, closeData, err := openData(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeData()
+
+	dataset, err := data.DatasetOf(dataModel)
+	if err != nil {
+		return err
+	}
+	return writeDataset(*format, *output, dataset)
+}
+
+// schema prints the schema of the API, with the features that change it.
+func schema(args []string) error {
+	flags, configFlags := newFlags("schema", config.Features)
+	output := flags.String("o", "-", "file to write the schema to, - for standard output")
+	cfg, err := loadConfig(configFlags, args)
+	if err != nil {
+		return err
+	}
+
+	testData, err := api.NewTestDataWithDataset(data.Dataset{}, bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+	fakeDataAPI, err := api.NewAPIWithConfig(testData, api.NewAuthenticationProvider(), cfg.APIConfig())
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*output, func(w io.Writer) error {
+		_, err := io.WriteString(w, api.PrintSchema(fakeDataAPI.Schema))
+		return err
 	})
+}
+
+// seed fills a new SQLite database with generated data, or with fixtures.
+func seed(args []string) error {
+	flags, configFlags := newFlags("seed", config.Fixtures|config.Generator|config.Passwords)
+	path := flags.String("db", "data.db", "path of the SQLite database to seed")
+	cfg, err := loadConfig(configFlags, args)
+	if err != nil {
+		return err
+	}
+
+	dataset, err := loadDataset(cfg.Data)
+	if err != nil {
+		return err
+	}
+	store, err := sqlite.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.Seed(dataset, passwordCost(cfg)); err != nil {
+		return err
+	}
+	fmt.Printf("Seeded %s with %d users, %d albums and %d photos\n", *path, len(dataset.Users), len(dataset.Albums), len(dataset.Photos))
+	return nil
+}
+
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage()
+		return
+	}
+
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
 
-	fmt.Println("Starting server at localhost:8080/graphql")
-	http.Handle("/graphql", h)
-	http.ListenAndServe(":8080", nil)
+	switch err := command.run(args); {
+	case errors.Is(err, errDone):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	case err != nil:
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,199 @@
+// Package server serves the API over HTTP, and shuts it down gracefully when it is stopped.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/api"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/config"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	"github.com/graphql-go/handler"
+)
+
+// Server serves the GraphQL API of the data, along with the discovery and admin endpoints that are turned on.
+//
+// The server owns the data: it is closed, if it is an io.Closer, once the server has shut down,
+// so that a persistent store is flushed after the last request that changes it.
+type Server struct {
+	config    config.ServerConfig
+	dataModel data.IWritableData
+	http      *http.Server
+
+	listener net.Listener
+	// stopped receives the error that stopped the server serving, or nil if it was shut down.
+	stopped chan error
+	signals chan os.Signal
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// New returns a server of the data, configured by the config. The server does not listen until it is started.
+func New(cfg config.Config, dataModel data.IWritableData) (*Server, error) {
+	auth, err := api.NewAuthenticationProviderWithConfig(cfg.Auth.AuthenticationConfig())
+	if err != nil {
+		return nil, err
+	}
+	fakeDataAPI, err := api.NewAPIWithConfig(dataModel, auth, cfg.APIConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	h := handler.New(&handler.Config{
+		Schema:   &fakeDataAPI.Schema,
+		Pretty:   cfg.Features.Pretty,
+		GraphiQL: cfg.Features.GraphiQL,
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Server.Path, api.AuthenticationMiddleware(auth, h))
+
+	if cfg.Features.JWKS && len(auth.KeySet().Keys) > 0 {
+		mux.Handle(api.JWKSPath, api.JWKSHandler(auth))
+		mux.Handle(api.OpenIDConfigurationPath, api.OpenIDConfigurationHandler(auth))
+	}
+
+	if restorableData, ok := dataModel.(data.IRestorableData); ok && cfg.Features.Snapshots {
+		mux.Handle(api.AdminPath, api.AuthenticationMiddleware(auth, api.AdminHandler(restorableData)))
+	}
+
+	server := &Server{
+		config:    cfg.Server,
+		dataModel: dataModel,
+		http: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: cfg.Server.ReadTimeout,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+		},
+	}
+
+	// The certificate is loaded now, rather than when serving, so that problems with it stop the server starting.
+	if cfg.Server.TLS.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.Server.TLS.Cert, cfg.Server.TLS.Key)
+		if err != nil {
+			return nil, err
+		}
+		server.http.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	}
+
+	return server, nil
+}
+
+// Start listens on the address of the config and serves requests in the background.
+// From then on, SIGINT and SIGTERM are handled by Wait rather than stopping the process.
+func (server *Server) Start() error {
+	listener, err := net.Listen("tcp", server.config.Listen)
+	if err != nil {
+		return err
+	}
+	server.listener = listener
+	server.stopped = make(chan error, 1)
+
+	server.signals = make(chan os.Signal, 1)
+	signal.Notify(server.signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		var err error
+		if server.usesTLS() {
+			err = server.http.ServeTLS(listener, "", "")
+		} else {
+			err = server.http.Serve(listener)
+		}
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		server.stopped <- err
+	}()
+
+	return nil
+}
+
+// Addr returns the address the server is listening on, which has the port that was chosen if it was 0.
+func (server *Server) Addr() net.Addr {
+	return server.listener.Addr()
+}
+
+// URL returns the URL of the GraphQL endpoint.
+func (server *Server) URL() string {
+	scheme := "http"
+	if server.usesTLS() {
+		scheme = "https"
+	}
+
+	addr := server.listener.Addr().(*net.TCPAddr)
+	host := addr.IP.String()
+	if addr.IP.IsUnspecified() {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, fmt.Sprint(addr.Port)), server.config.Path)
+}
+
+// usesTLS returns whether the server serves HTTPS. The TLS config of the http.Server is not checked,
+// as serving HTTP/2 adds one.
+func (server *Server) usesTLS() bool {
+	return server.config.TLS.Cert != ""
+}
+
+// Wait serves until the context is done, the process is sent SIGINT or SIGTERM, or the server fails.
+// Then it shuts the server down, giving requests in flight the shutdown timeout of the config to finish.
+//
+// It returns the error that the server failed with, or the error shutting it down.
+func (server *Server) Wait(ctx context.Context) error {
+	var err error
+	select {
+	case <-ctx.Done():
+	case <-server.signals:
+	case err = <-server.stopped:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), server.config.ShutdownTimeout)
+	defer cancel()
+
+	if shutdownErr := server.Shutdown(shutdownCtx); err == nil {
+		err = shutdownErr
+	}
+	return err
+}
+
+// Shutdown stops the server listening and waits for requests in flight to finish, until the context is done,
+// when their connections are closed. Then it closes the data.
+//
+// It returns an error if the requests did not finish in time, or if the data could not be closed.
+func (server *Server) Shutdown(ctx context.Context) error {
+	if server.signals != nil {
+		signal.Stop(server.signals)
+	}
+
+	err := server.http.Shutdown(ctx)
+	if err != nil {
+		server.http.Close()
+		err = fmt.Errorf("requests did not finish before shutting down: %w", err)
+	}
+
+	if closeErr := server.closeData(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// closeData closes the data once, however many times the server is shut down.
+func (server *Server) closeData() error {
+	server.closeOnce.Do(func() {
+		if closer, ok := server.dataModel.(io.Closer); ok {
+			server.closeErr = closer.Close()
+		}
+	})
+	return server.closeErr
+}
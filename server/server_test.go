@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/api"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/config"
+	"github.com/Dylan-Kentish/GraphQLFakeDataAPI/data"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// blockingData blocks requests for a user until they are released, and records when it is closed.
+type blockingData struct {
+	data.IWritableData
+	blocked  chan struct{}
+	release  chan struct{}
+	closed   chan struct{}
+	closeErr error
+}
+
+func (d *blockingData) GetUser(id int) (data.User, error) {
+	d.blocked <- struct{}{}
+	<-d.release
+	return d.IWritableData.GetUser(id)
+}
+
+func (d *blockingData) Close() error {
+	close(d.closed)
+	return d.closeErr
+}
+
+var _ = Describe("Server", func() {
+	var cfg config.Config
+	var dataModel *blockingData
+	var srv *Server
+
+	BeforeEach(func() {
+		cfg = config.Default()
+		cfg.Server.Listen = "127.0.0.1:0"
+		cfg.Auth.PasswordCost = 4
+
+		generatorConfig := cfg.Data.GeneratorConfig()
+		generatorConfig.Users = 2
+		testData, err := api.NewTestDataWithConfig(generatorConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		dataModel = &blockingData{
+			IWritableData: testData,
+			blocked:       make(chan struct{}),
+			release:       make(chan struct{}),
+			closed:        make(chan struct{}),
+		}
+
+		srv, err = New(cfg, dataModel)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(srv.Start()).To(Succeed())
+		DeferCleanup(func() {
+			close(dataModel.release)
+			srv.Shutdown(context.Background())
+		})
+	})
+
+	post := func(query string) (int, string, error) {
+		response, err := http.Post(srv.URL(), "application/json", strings.NewReader(`{"query": "`+query+`"}`))
+		if err != nil {
+			return 0, "", err
+		}
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		return response.StatusCode, string(body), err
+	}
+
+	// postInBackground sends the query once the data is asked for it, and returns the status of its response,
+	// which is 0 if the request failed.
+	postInBackground := func(query string) <-chan int {
+		status := make(chan int, 1)
+		go func() {
+			code, _, _ := post(query)
+			status <- code
+		}()
+		Eventually(dataModel.blocked).Should(Receive())
+		return status
+	}
+
+	It("listens on a free port", func() {
+		Expect(srv.Addr().String()).To(HavePrefix("127.0.0.1:"))
+		Expect(srv.Addr().String()).ToNot(Equal("127.0.0.1:0"))
+		Expect(srv.URL()).To(Equal("http://" + srv.Addr().String() + "/graphql"))
+
+		status, body, err := post("{ users { id } }")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`{"data": {"users": [{"id": 0}, {"id": 1}]}}`))
+	})
+
+	It("fails to start if the address is in use", func() {
+		cfg.Server.Listen = srv.Addr().String()
+		other, err := New(cfg, dataModel)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(other.Start()).To(MatchError(ContainSubstring("address already in use")))
+	})
+
+	It("serves HTTPS with the certificate", func() {
+		srv.Shutdown(context.Background())
+		cfg.Server.TLS = writeCertificate(GinkgoT().TempDir())
+		dataModel.closed = make(chan struct{})
+
+		var err error
+		srv, err = New(cfg, dataModel)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(srv.Start()).To(Succeed())
+		Expect(srv.URL()).To(HavePrefix("https://127.0.0.1:"))
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+		response, err := client.Post(srv.URL(), "application/json", strings.NewReader(`{"query": "{ users { id } }"}`))
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("fails to be created with an invalid certificate", func() {
+		cfg.Server.TLS = config.TLSConfig{Cert: "missing.pem", Key: "missing.pem"}
+
+		_, err := New(cfg, dataModel)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("finishes requests in flight before closing the data", func() {
+		status := postInBackground("{ user(id: 0) { id } }")
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- srv.Shutdown(context.Background()) }()
+
+		Eventually(func() error {
+			_, _, err := post("{ users { id } }")
+			return err
+		}).Should(HaveOccurred())
+		Consistently(shutdown).ShouldNot(Receive())
+		Expect(dataModel.closed).ToNot(BeClosed())
+
+		dataModel.release <- struct{}{}
+		Eventually(status).Should(Receive(Equal(http.StatusOK)))
+		Eventually(shutdown).Should(Receive(BeNil()))
+		Expect(dataModel.closed).To(BeClosed())
+	})
+
+	It("gives up on requests that do not finish in time", func() {
+		postInBackground("{ user(id: 0) { id } }")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		err := srv.Shutdown(ctx)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(dataModel.closed).To(BeClosed())
+	})
+
+	It("reports errors closing the data", func() {
+		dataModel.closeErr = errors.New("disk full")
+
+		Expect(srv.Shutdown(context.Background())).To(MatchError("disk full"))
+	})
+
+	Context("waiting", func() {
+		var waited chan error
+
+		wait := func(ctx context.Context) {
+			waited = make(chan error, 1)
+			go func() { waited <- srv.Wait(ctx) }()
+		}
+
+		It("shuts down when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			wait(ctx)
+			Consistently(waited).ShouldNot(Receive())
+
+			cancel()
+			Eventually(waited).Should(Receive(BeNil()))
+			Expect(dataModel.closed).To(BeClosed())
+		})
+
+		It("shuts down when the process is terminated", func() {
+			wait(context.Background())
+
+			srv.signals <- syscall.SIGTERM
+
+			Eventually(waited).Should(Receive(BeNil()))
+			Expect(dataModel.closed).To(BeClosed())
+		})
+
+		It("waits up to the shutdown timeout for requests in flight", func() {
+			srv.Shutdown(context.Background())
+			cfg.Server.ShutdownTimeout = 100 * time.Millisecond
+			dataModel.closed = make(chan struct{})
+
+			var err error
+			srv, err = New(cfg, dataModel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(srv.Start()).To(Succeed())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			wait(ctx)
+			postInBackground("{ user(id: 0) { id } }")
+			cancel()
+
+			Eventually(waited).Should(Receive(MatchError(context.DeadlineExceeded)))
+		})
+	})
+})
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1 to the directory.
+func writeCertificate(dir string) config.TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+	privateKey, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	tlsConfig := config.TLSConfig{Cert: filepath.Join(dir, "cert.pem"), Key: filepath.Join(dir, "key.pem")}
+	Expect(os.WriteFile(tlsConfig.Cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0o644)).To(Succeed())
+	Expect(os.WriteFile(tlsConfig.Key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKey}), 0o600)).To(Succeed())
+	return tlsConfig
+}
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Connection is a page of items, as described by the Relay cursor connections specification.
+type Connection[T any] struct {
+	Edges      []Edge[T]
+	PageInfo   PageInfo
+	TotalCount int
+}
+
+type Edge[T any] struct {
+	Node   T
+	Cursor string
+}
+
+type PageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     string
+	EndCursor       string
+}
+
+// Cursors identify items by their IDs, so that a cursor keeps its place once its item has been deleted.
+type Cursors[T any] struct {
+	// ID returns the ID of an item.
+	ID func(item T) int
+	// Encode returns the cursor of the item with the ID.
+	Encode func(id int) string
+	// Decode returns the ID of the cursor, or false if it is not a cursor of these items.
+	Decode func(cursor string) (int, bool)
+}
+
+// CheckConnectionArgs returns an error if the first, after, last or before arguments are invalid,
+// so that they can be checked before the items are loaded.
+func CheckConnectionArgs[T any](args map[string]interface{}, cursors Cursors[T]) error {
+	for _, name := range []string{"after", "before"} {
+		if cursor, exists := args[name].(string); exists {
+			if _, ok := cursors.Decode(cursor); !ok {
+				return fmt.Errorf("%w %q", ErrInvalidCursor, cursor)
+			}
+		}
+	}
+
+	for _, name := range []string{"first", "last"} {
+		if n, exists := args[name].(int); exists && n < 0 {
+			return fmt.Errorf("%s %w", name, ErrNegative)
+		}
+	}
+
+	return nil
+}
+
+// NewConnection pages through s, which must be sorted by ID, using the first, after, last and before
+// arguments, if present. A cursor is placed by its ID, so it need not be the cursor of an item in s.
+func NewConnection[S []T, T any](s S, args map[string]interface{}, cursors Cursors[T]) (Connection[T], error) {
+	return newConnection(s, args, cursors, func(id int) (int, bool) {
+		i := sort.Search(len(s), func(i int) bool { return cursors.ID(s[i]) >= id })
+		return i, i < len(s) && cursors.ID(s[i]) == id
+	})
+}
+
+// NewOrderedConnection pages through s, which is sorted by something other than ID, like NewConnection.
+// As the place of an item that is not in s is unknown, each cursor must be the cursor of an item in s.
+func NewOrderedConnection[S []T, T any](s S, args map[string]interface{}, cursors Cursors[T]) (Connection[T], error) {
+	return newConnection(s, args, cursors, func(id int) (int, bool) {
+		for i, item := range s {
+			if cursors.ID(item) == id {
+				return i, true
+			}
+		}
+		return -1, false
+	})
+}
+
+// newConnection pages through s, placing cursors with find, which returns the index of the item with the ID,
+// or if there is none, the index it would be at, or -1 if that is unknown.
+func newConnection[S []T, T any](s S, args map[string]interface{}, cursors Cursors[T], find func(id int) (int, bool)) (Connection[T], error) {
+	if err := CheckConnectionArgs(args, cursors); err != nil {
+		return Connection[T]{}, err
+	}
+
+	indexOf := func(cursor string) (int, bool, error) {
+		id, _ := cursors.Decode(cursor)
+		index, found := find(id)
+		if index < 0 {
+			return 0, false, fmt.Errorf("cursor %q %w", cursor, ErrCursorNotInList)
+		}
+		return index, found, nil
+	}
+
+	start, end := 0, len(s)
+
+	if after, exists := args["after"].(string); exists {
+		index, found, err := indexOf(after)
+		if err != nil {
+			return Connection[T]{}, err
+		}
+		start = index
+		if found {
+			start++
+		}
+	}
+
+	if before, exists := args["before"].(string); exists {
+		index, _, err := indexOf(before)
+		if err != nil {
+			return Connection[T]{}, err
+		}
+		end = index
+	}
+
+	if end < start {
+		end = start
+	}
+
+	if first, exists := args["first"].(int); exists && end-start > first {
+		end = start + first
+	}
+
+	if last, exists := args["last"].(int); exists && end-start > last {
+		start = end - last
+	}
+
+	connection := Connection[T]{
+		Edges: Transform(s[start:end], func(item T) Edge[T] {
+			return Edge[T]{Node: item, Cursor: cursors.Encode(cursors.ID(item))}
+		}),
+		PageInfo: PageInfo{
+			HasNextPage:     end < len(s),
+			HasPreviousPage: start > 0,
+		},
+		TotalCount: len(s),
+	}
+
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	return connection, nil
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewConnection", func() {
+	slice := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	cursors := Cursors[int]{
+		ID:     func(i int) int { return i },
+		Encode: func(id int) string { return fmt.Sprint("cursor", id) },
+		Decode: func(cursor string) (int, bool) {
+			var id int
+			_, err := fmt.Sscanf(cursor, "cursor%d", &id)
+			return id, err == nil
+		},
+	}
+
+	nodes := func(connection Connection[int]) []int {
+		return Transform(connection.Edges, func(edge Edge[int]) int { return edge.Node })
+	}
+
+	DescribeTable("pages through the items",
+		func(args map[string]interface{}, expected []int, hasPrevious bool, hasNext bool) {
+			connection, err := NewConnection(slice, args, cursors)
+
+			Expect(err).To(BeNil())
+			Expect(nodes(connection)).To(Equal(expected))
+			Expect(connection.TotalCount).To(Equal(len(slice)))
+			Expect(connection.PageInfo.HasPreviousPage).To(Equal(hasPrevious))
+			Expect(connection.PageInfo.HasNextPage).To(Equal(hasNext))
+		},
+		Entry("no arguments", map[string]interface{}{}, slice, false, false),
+		Entry("first", map[string]interface{}{"first": 3}, []int{0, 1, 2}, false, true),
+		Entry("first greater than len", map[string]interface{}{"first": 20}, slice, false, false),
+		Entry("first zero", map[string]interface{}{"first": 0}, []int{}, false, true),
+		Entry("after", map[string]interface{}{"after": "cursor6"}, []int{7, 8, 9}, true, false),
+		Entry("first after", map[string]interface{}{"first": 2, "after": "cursor2"}, []int{3, 4}, true, true),
+		Entry("after last item", map[string]interface{}{"after": "cursor9"}, []int{}, true, false),
+		Entry("last", map[string]interface{}{"last": 3}, []int{7, 8, 9}, true, false),
+		Entry("last greater than len", map[string]interface{}{"last": 20}, slice, false, false),
+		Entry("before", map[string]interface{}{"before": "cursor3"}, []int{0, 1, 2}, false, true),
+		Entry("last before", map[string]interface{}{"last": 2, "before": "cursor5"}, []int{3, 4}, true, true),
+		Entry("after and before", map[string]interface{}{"after": "cursor2", "before": "cursor6"}, []int{3, 4, 5}, true, true),
+		Entry("before after", map[string]interface{}{"after": "cursor6", "before": "cursor2"}, []int{}, true, true),
+	)
+
+	DescribeTable("places the cursors of items that are not in the list by their IDs",
+		func(args map[string]interface{}, expected []int) {
+			connection, err := NewConnection([]int{0, 2, 4, 6, 8}, args, cursors)
+
+			Expect(err).To(BeNil())
+			Expect(nodes(connection)).To(Equal(expected))
+		},
+		Entry("after", map[string]interface{}{"after": "cursor3"}, []int{4, 6, 8}),
+		Entry("before", map[string]interface{}{"before": "cursor3"}, []int{0, 2}),
+		Entry("after every item", map[string]interface{}{"after": "cursor20"}, []int{}),
+		Entry("before every item", map[string]interface{}{"before": "cursor-1"}, []int{}),
+		Entry("first after", map[string]interface{}{"first": 1, "after": "cursor5"}, []int{6}),
+	)
+
+	Context("ordered by something other than ID", func() {
+		ordered := []int{5, 3, 9, 1}
+
+		It("places the cursors at their items", func() {
+			connection, err := NewOrderedConnection(ordered, map[string]interface{}{"after": "cursor3", "before": "cursor1"}, cursors)
+
+			Expect(err).To(BeNil())
+			Expect(nodes(connection)).To(Equal([]int{9}))
+			Expect(connection.PageInfo.HasPreviousPage).To(BeTrue())
+			Expect(connection.PageInfo.HasNextPage).To(BeTrue())
+		})
+
+		It("rejects the cursor of an item that is not in the list", func() {
+			_, err := NewOrderedConnection(ordered, map[string]interface{}{"after": "cursor4"}, cursors)
+
+			Expect(err).To(MatchError(`cursor "cursor4" is not in the list`))
+			Expect(err).To(MatchError(ErrCursorNotInList))
+		})
+	})
+
+	It("sets the start and end cursors", func() {
+		connection, _ := NewConnection(slice, map[string]interface{}{"first": 3}, cursors)
+
+		Expect(connection.Edges[1].Cursor).To(Equal("cursor1"))
+		Expect(connection.PageInfo.StartCursor).To(Equal("cursor0"))
+		Expect(connection.PageInfo.EndCursor).To(Equal("cursor2"))
+	})
+
+	It("leaves the cursors empty when there are no edges", func() {
+		connection, _ := NewConnection(slice, map[string]interface{}{"first": 0}, cursors)
+
+		Expect(connection.PageInfo.StartCursor).To(BeEmpty())
+		Expect(connection.PageInfo.EndCursor).To(BeEmpty())
+	})
+
+	DescribeTable("rejects invalid arguments", func(args map[string]interface{}, expected string, sentinel error) {
+		_, err := NewConnection(slice, args, cursors)
+
+		Expect(err).To(MatchError(expected))
+		Expect(err).To(MatchError(sentinel))
+	},
+		Entry("negative first", map[string]interface{}{"first": -1}, "first must not be negative", ErrNegative),
+		Entry("negative last", map[string]interface{}{"last": -1}, "last must not be negative", ErrNegative),
+		Entry("unknown after", map[string]interface{}{"after": "nope"}, `invalid cursor "nope"`, ErrInvalidCursor),
+		Entry("unknown before", map[string]interface{}{"before": "nope"}, `invalid cursor "nope"`, ErrInvalidCursor),
+	)
+})
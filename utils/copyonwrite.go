@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"sync/atomic"
+
+	"golang.org/x/exp/maps"
+)
+
+const copyOnWriteShards = 256
+
+// versions gives each CopyOnWriteMap, and each copy of one, a version of its own,
+// which marks the shards that it may change in place.
+var versions atomic.Uint64
+
+// CopyOnWriteMap is a map that is cheap to copy.
+//
+// The items are split between shards by key. A copy shares every shard with the original,
+// and whichever of them changes a shared shard first copies just that shard. So copying the map
+// and then changing an item costs about as much as copying one shard, however large the map.
+//
+// It is not safe for concurrent use while it is being changed.
+type CopyOnWriteMap[K comparable, V any] struct {
+	shardOf func(key K) uint
+	// shards are nil until they have items.
+	shards  []*copyOnWriteShard[K, V]
+	version uint64
+	length  int
+}
+
+type copyOnWriteShard[K comparable, V any] struct {
+	// version is the version of the map that may change the shard in place.
+	version uint64
+	items   map[K]V
+}
+
+// NewCopyOnWriteMap returns an empty map, which puts each key in the shard chosen by shardOf.
+func NewCopyOnWriteMap[K comparable, V any](shardOf func(key K) uint) *CopyOnWriteMap[K, V] {
+	return &CopyOnWriteMap[K, V]{
+		shardOf: shardOf,
+		shards:  make([]*copyOnWriteShard[K, V], copyOnWriteShards),
+		version: versions.Add(1),
+	}
+}
+
+// Clone returns a copy of the map. Changes to either do not change the other.
+func (m *CopyOnWriteMap[K, V]) Clone() *CopyOnWriteMap[K, V] {
+	// Neither map may change the shared shards in place any more.
+	m.version = versions.Add(1)
+
+	clone := *m
+	clone.shards = make([]*copyOnWriteShard[K, V], len(m.shards))
+	copy(clone.shards, m.shards)
+	clone.version = versions.Add(1)
+
+	return &clone
+}
+
+// Len returns the number of items.
+func (m *CopyOnWriteMap[K, V]) Len() int {
+	return m.length
+}
+
+// Get returns the value of the key, and whether there is one.
+func (m *CopyOnWriteMap[K, V]) Get(key K) (V, bool) {
+	shard := m.shards[m.shardOf(key)%copyOnWriteShards]
+	if shard == nil {
+		var zero V
+		return zero, false
+	}
+	value, ok := shard.items[key]
+	return value, ok
+}
+
+// Set sets the value of the key.
+func (m *CopyOnWriteMap[K, V]) Set(key K, value V) {
+	shard := m.writableShard(key)
+	if _, ok := shard.items[key]; !ok {
+		m.length++
+	}
+	shard.items[key] = value
+}
+
+// Delete removes the key, if it has a value.
+func (m *CopyOnWriteMap[K, V]) Delete(key K) {
+	if _, ok := m.Get(key); !ok {
+		return
+	}
+	delete(m.writableShard(key).items, key)
+	m.length--
+}
+
+// Range calls f for every item, in no particular order.
+func (m *CopyOnWriteMap[K, V]) Range(f func(key K, value V)) {
+	for _, shard := range m.shards {
+		if shard == nil {
+			continue
+		}
+		for key, value := range shard.items {
+			f(key, value)
+		}
+	}
+}
+
+// writableShard returns the shard of the key, copying it first if it is shared.
+func (m *CopyOnWriteMap[K, V]) writableShard(key K) *copyOnWriteShard[K, V] {
+	i := m.shardOf(key) % copyOnWriteShards
+	switch shard := m.shards[i]; {
+	case shard == nil:
+		m.shards[i] = &copyOnWriteShard[K, V]{version: m.version, items: make(map[K]V)}
+	case shard.version != m.version:
+		m.shards[i] = &copyOnWriteShard[K, V]{version: m.version, items: maps.Clone(shard.items)}
+	}
+	return m.shards[i]
+}
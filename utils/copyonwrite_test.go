@@ -0,0 +1,63 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CopyOnWriteMap", func() {
+	var m *CopyOnWriteMap[int, string]
+
+	items := func(m *CopyOnWriteMap[int, string]) map[int]string {
+		items := make(map[int]string)
+		m.Range(func(key int, value string) {
+			items[key] = value
+		})
+		return items
+	}
+
+	BeforeEach(func() {
+		m = NewCopyOnWriteMap[int, string](func(key int) uint { return uint(key) })
+		m.Set(1, "a")
+		m.Set(2, "b")
+		m.Set(1000, "c")
+	})
+
+	It("Gets, sets and deletes items", func() {
+		m.Set(2, "B")
+		m.Delete(1000)
+		m.Delete(3)
+
+		value, ok := m.Get(2)
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("B"))
+		_, ok = m.Get(1000)
+		Expect(ok).To(BeFalse())
+		Expect(m.Len()).To(Equal(2))
+		Expect(items(m)).To(Equal(map[int]string{1: "a", 2: "B"}))
+	})
+
+	It("Does not change copies", func() {
+		clone := m.Clone()
+		clone.Set(1, "A")
+		clone.Delete(2)
+		clone.Set(3, "d")
+
+		Expect(items(m)).To(Equal(map[int]string{1: "a", 2: "b", 1000: "c"}))
+		Expect(m.Len()).To(Equal(3))
+		Expect(items(clone)).To(Equal(map[int]string{1: "A", 3: "d", 1000: "c"}))
+		Expect(clone.Len()).To(Equal(3))
+	})
+
+	It("Is not changed by changes to copies of copies", func() {
+		clone := m.Clone()
+		m.Set(1, "A")
+		cloneOfClone := clone.Clone()
+		cloneOfClone.Set(2, "B")
+		clone.Set(1000, "C")
+
+		Expect(items(m)).To(Equal(map[int]string{1: "A", 2: "b", 1000: "c"}))
+		Expect(items(clone)).To(Equal(map[int]string{1: "a", 2: "b", 1000: "C"}))
+		Expect(items(cloneOfClone)).To(Equal(map[int]string{1: "a", 2: "B", 1000: "c"}))
+	})
+})
@@ -38,6 +38,57 @@ func ValuesWhere[M map[T]U, T constraints.Ordered, U any](m M, condition func(it
 	return Where(OrderedValues(m), condition)
 }
 
+// GroupBy returns the items with each of the keys, keeping their order.
+// Every key has an entry, which is empty if no items have that key.
+func GroupBy[S []T, T any, K comparable](s S, keys []K, keyOf func(item T) K) map[K]S {
+	groups := make(map[K]S, len(keys))
+	for _, key := range keys {
+		groups[key] = make(S, 0)
+	}
+
+	for _, item := range s {
+		key := keyOf(item)
+		if group, ok := groups[key]; ok {
+			groups[key] = append(group, item)
+		}
+	}
+
+	return groups
+}
+
+// All returns a condition that matches items matching every condition.
+// It matches every item if there are no conditions.
+func All[T any](conditions ...func(item T) bool) func(item T) bool {
+	return func(item T) bool {
+		for _, condition := range conditions {
+			if !condition(item) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any returns a condition that matches items matching any of the conditions.
+// It matches no items if there are no conditions.
+func Any[T any](conditions ...func(item T) bool) func(item T) bool {
+	return func(item T) bool {
+		for _, condition := range conditions {
+			if condition(item) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Negate returns a condition that matches items not matching the condition.
+func Negate[T any](condition func(item T) bool) func(item T) bool {
+	return func(item T) bool {
+		return !condition(item)
+	}
+}
+
 // OrderedValues returns the values sorted by Key
 func OrderedValues[M ~map[T]U, T constraints.Ordered, U any](m M) []U {
 	keys := maps.Keys(m)
@@ -55,6 +106,50 @@ func OrderedValues[M ~map[T]U, T constraints.Ordered, U any](m M) []U {
 	return result
 }
 
+// Comparison compares two items. It is negative if a sorts before b, positive if a sorts after b and zero otherwise.
+type Comparison[T any] func(a, b T) int
+
+// Ascending compares items by the value, from lowest to highest.
+func Ascending[T any, V constraints.Ordered](value func(item T) V) Comparison[T] {
+	return func(a, b T) int {
+		x, y := value(a), value(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	}
+}
+
+// Descending compares items by the value, from highest to lowest.
+func Descending[T any, V constraints.Ordered](value func(item T) V) Comparison[T] {
+	ascending := Ascending(value)
+	return func(a, b T) int {
+		return ascending(b, a)
+	}
+}
+
+// OrderBy returns a sorted copy of the slice. Items are compared by each comparison in turn,
+// and items that are equal by every comparison keep their original order.
+func OrderBy[S []T, T any](s S, comparisons ...Comparison[T]) S {
+	r := make(S, len(s))
+	copy(r, s)
+
+	sort.SliceStable(r, func(i, j int) bool {
+		for _, compare := range comparisons {
+			if c := compare(r[i], r[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	return r
+}
+
 func Single[S []T, T any](s S, condition func(item T) bool) (*T, error) {
 	matches := Where(s, condition)
 
@@ -73,6 +73,31 @@ var _ = Describe("ValuesWhere", func() {
 	})
 })
 
+var _ = Describe("GroupBy", func() {
+	words := []string{"apple", "bee", "cat", "ant", "bear", "ace"}
+	firstLetter := func(word string) byte { return word[0] }
+
+	It("groups the items by key, keeping their order", func() {
+		groups := GroupBy(words, []byte{'a', 'b'}, firstLetter)
+
+		Expect(groups).To(Equal(map[byte][]string{
+			'a': {"apple", "ant", "ace"},
+			'b': {"bee", "bear"},
+		}))
+	})
+
+	It("has an empty group for keys without items", func() {
+		groups := GroupBy(words, []byte{'z'}, firstLetter)
+
+		Expect(groups).To(HaveKeyWithValue(byte('z'), BeEmpty()))
+		Expect(groups['z']).NotTo(BeNil())
+	})
+
+	It("has no groups without keys", func() {
+		Expect(GroupBy(words, nil, firstLetter)).To(BeEmpty())
+	})
+})
+
 var _ = Describe("OrderedValues", func() {
 	It("returns the values in order", func() {
 		count := 10
@@ -138,3 +163,69 @@ var _ = Describe("Single", func() {
 		})
 	})
 })
+
+var _ = Describe("Predicates", func() {
+	isEven := func(i int) bool { return i%2 == 0 }
+	isSmall := func(i int) bool { return i < 5 }
+	slice := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	DescribeTable("combine conditions", func(condition func(int) bool, expected []int) {
+		Expect(Where(slice, condition)).To(Equal(expected))
+	},
+		Entry("All", All(isEven, isSmall), []int{0, 2, 4}),
+		Entry("All without conditions", All[int](), slice),
+		Entry("Any", Any(isEven, isSmall), []int{0, 1, 2, 3, 4, 6, 8}),
+		Entry("Any without conditions", Any[int](), []int{}),
+		Entry("Negate", Negate(isEven), []int{1, 3, 5, 7, 9}),
+		Entry("nested", Negate(Any(isEven, All(isSmall, Negate(isEven)))), []int{5, 7, 9}),
+	)
+})
+
+var _ = Describe("OrderBy", func() {
+	type person struct {
+		name string
+		age  int
+	}
+
+	people := []person{{"c", 30}, {"a", 40}, {"b", 30}, {"a", 20}, {"c", 20}}
+	name := func(p person) string { return p.name }
+	age := func(p person) int { return p.age }
+
+	DescribeTable("sorts the slice", func(comparisons []Comparison[person], expected []person) {
+		Expect(OrderBy(people, comparisons...)).To(Equal(expected))
+	},
+		Entry("without comparisons", []Comparison[person]{}, people),
+		Entry("ascending", []Comparison[person]{Ascending(name)},
+			[]person{{"a", 40}, {"a", 20}, {"b", 30}, {"c", 30}, {"c", 20}}),
+		Entry("descending", []Comparison[person]{Descending(age)},
+			[]person{{"a", 40}, {"c", 30}, {"b", 30}, {"a", 20}, {"c", 20}}),
+		Entry("by multiple keys", []Comparison[person]{Ascending(name), Ascending(age)},
+			[]person{{"a", 20}, {"a", 40}, {"b", 30}, {"c", 20}, {"c", 30}}),
+		Entry("by mixed directions", []Comparison[person]{Descending(age), Descending(name)},
+			[]person{{"a", 40}, {"c", 30}, {"b", 30}, {"c", 20}, {"a", 20}}),
+	)
+
+	It("does not modify the slice", func() {
+		original := append([]person{}, people...)
+
+		OrderBy(people, Ascending(name))
+
+		Expect(people).To(Equal(original))
+	})
+
+	It("is stable", func() {
+		count := 1000
+		slice := make([]int, count)
+		for i := range slice {
+			slice[i] = i
+		}
+
+		result := OrderBy(slice, Ascending(func(i int) int { return i % 3 }))
+
+		for i := 1; i < count; i++ {
+			if result[i-1]%3 == result[i]%3 {
+				Expect(result[i-1]).To(BeNumerically("<", result[i]))
+			}
+		}
+	})
+})
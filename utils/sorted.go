@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/slices"
+)
+
+// InsertSorted inserts the value into the sorted slice s, keeping it sorted, and returns the result.
+// The slice is returned unchanged if it already contains the value.
+func InsertSorted[S ~[]T, T constraints.Ordered](s S, value T) S {
+	i, found := slices.BinarySearch(s, value)
+	if found {
+		return s
+	}
+	// Unlike slices.Insert, append leaves room to grow, so that inserting one value at a time stays cheap.
+	s = append(s, value)
+	copy(s[i+1:], s[i:])
+	s[i] = value
+	return s
+}
+
+// RemoveSorted removes the value from the sorted slice s and returns the result.
+// The slice is returned unchanged if it does not contain the value.
+func RemoveSorted[S ~[]T, T constraints.Ordered](s S, value T) S {
+	i, found := slices.BinarySearch(s, value)
+	if !found {
+		return s
+	}
+	return slices.Delete(s, i, i+1)
+}
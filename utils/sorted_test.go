@@ -0,0 +1,35 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InsertSorted", func() {
+	It("Inserts the value in order", func() {
+		Expect(InsertSorted([]int{1, 3, 5}, 0)).To(Equal([]int{0, 1, 3, 5}))
+		Expect(InsertSorted([]int{1, 3, 5}, 4)).To(Equal([]int{1, 3, 4, 5}))
+		Expect(InsertSorted([]int{1, 3, 5}, 6)).To(Equal([]int{1, 3, 5, 6}))
+	})
+
+	It("Inserts into an empty slice", func() {
+		Expect(InsertSorted([]int(nil), 1)).To(Equal([]int{1}))
+	})
+
+	It("Does not insert a value twice", func() {
+		Expect(InsertSorted([]int{1, 3, 5}, 3)).To(Equal([]int{1, 3, 5}))
+	})
+})
+
+var _ = Describe("RemoveSorted", func() {
+	It("Removes the value", func() {
+		Expect(RemoveSorted([]int{1, 3, 5}, 1)).To(Equal([]int{3, 5}))
+		Expect(RemoveSorted([]int{1, 3, 5}, 3)).To(Equal([]int{1, 5}))
+		Expect(RemoveSorted([]int{1, 3, 5}, 5)).To(Equal([]int{1, 3}))
+	})
+
+	It("Ignores missing values", func() {
+		Expect(RemoveSorted([]int{1, 3, 5}, 4)).To(Equal([]int{1, 3, 5}))
+		Expect(RemoveSorted([]int(nil), 4)).To(BeEmpty())
+	})
+})
@@ -1,10 +1,38 @@
 package utils
 
-func TryLimitIfPresent[S []T, T any](s S, Args map[string]interface{}) []T {
-	if limit, exists := Args["limit"].(int); exists &&
-		limit < len(s) {
-		return s[:limit]
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors of invalid paging arguments, which are wrapped with the name of the argument or the cursor.
+var (
+	ErrNegative        = errors.New("must not be negative")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrCursorNotInList = errors.New("is not in the list")
+)
+
+// TryPageIfPresent skips the first offset items and then limits the result to limit items,
+// if either argument is present.
+func TryPageIfPresent[S []T, T any](s S, args map[string]interface{}) (S, error) {
+	if offset, exists := args["offset"].(int); exists {
+		if offset < 0 {
+			return nil, fmt.Errorf("offset %w", ErrNegative)
+		}
+		if offset > len(s) {
+			offset = len(s)
+		}
+		s = s[offset:]
+	}
+
+	if limit, exists := args["limit"].(int); exists {
+		if limit < 0 {
+			return nil, fmt.Errorf("limit %w", ErrNegative)
+		}
+		if limit < len(s) {
+			s = s[:limit]
+		}
 	}
 
-	return s
+	return s, nil
 }
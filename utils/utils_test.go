@@ -5,7 +5,7 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("TryLimitIfPresent", func() {
+var _ = Describe("TryPageIfPresent", func() {
 	var size int
 	var hasLimit bool
 	var limit int
@@ -37,7 +37,7 @@ var _ = Describe("TryLimitIfPresent", func() {
 			})
 
 			It("Returns limited items", func() {
-				Expect(TryLimitIfPresent(slice, args)).To(Equal(slice[:limit]))
+				Expect(TryPageIfPresent(slice, args)).To(Equal(slice[:limit]))
 			})
 		})
 
@@ -48,7 +48,7 @@ var _ = Describe("TryLimitIfPresent", func() {
 			})
 
 			It("Returns all items", func() {
-				Expect(TryLimitIfPresent(slice, args)).To(Equal(slice))
+				Expect(TryPageIfPresent(slice, args)).To(Equal(slice))
 			})
 		})
 	})
@@ -59,7 +59,31 @@ var _ = Describe("TryLimitIfPresent", func() {
 		})
 
 		It("Returns all items", func() {
-			Expect(TryLimitIfPresent(slice, args)).To(Equal(slice))
+			Expect(TryPageIfPresent(slice, args)).To(Equal(slice))
 		})
 	})
+
+	DescribeTable("offset and limit", func(args map[string]interface{}, expected []int) {
+		slice := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+		Expect(TryPageIfPresent(slice, args)).To(Equal(expected))
+	},
+		Entry("offset zero", map[string]interface{}{"offset": 0}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}),
+		Entry("offset", map[string]interface{}{"offset": 7}, []int{7, 8, 9}),
+		Entry("offset equal to len", map[string]interface{}{"offset": 10}, []int{}),
+		Entry("offset greater than len", map[string]interface{}{"offset": 11}, []int{}),
+		Entry("limit zero", map[string]interface{}{"limit": 0}, []int{}),
+		Entry("offset and limit", map[string]interface{}{"offset": 2, "limit": 3}, []int{2, 3, 4}),
+		Entry("offset and limit past the end", map[string]interface{}{"offset": 8, "limit": 3}, []int{8, 9}),
+	)
+
+	DescribeTable("rejects negative values", func(args map[string]interface{}, expected string) {
+		_, err := TryPageIfPresent([]int{0, 1, 2}, args)
+
+		Expect(err).To(MatchError(expected))
+		Expect(err).To(MatchError(ErrNegative))
+	},
+		Entry("negative offset", map[string]interface{}{"offset": -1}, "offset must not be negative"),
+		Entry("negative limit", map[string]interface{}{"limit": -1}, "limit must not be negative"),
+	)
 })